@@ -0,0 +1,4 @@
+-- migrations/schema/000003_rollups.down.sql
+DROP FUNCTION IF EXISTS analytics_unique_ips(BIT VARYING);
+DROP TABLE IF EXISTS analytics_referer_rollups;
+DROP TABLE IF EXISTS analytics_rollups;
@@ -0,0 +1,37 @@
+-- Migration: 000003_rollups
+-- Hourly and daily click rollups maintained by the analytics worker.
+--
+-- granularity is 'hour' or 'day'; bucket is the UTC start of the period.
+-- ip_sketch is a linear-counting bitmap: each IP sets one bit chosen by hash,
+-- so sketches merge with bitwise OR and the number of zero bits estimates the
+-- distinct IP count (see analytics_unique_ips).
+CREATE TABLE IF NOT EXISTS analytics_rollups (
+    short_code  VARCHAR(16) NOT NULL,
+    granularity TEXT NOT NULL CHECK (granularity IN ('hour', 'day')),
+    bucket      TIMESTAMP WITH TIME ZONE NOT NULL,
+    clicks      BIGINT NOT NULL DEFAULT 0,
+    ip_sketch   BIT(2048) NOT NULL,
+    PRIMARY KEY (short_code, granularity, bucket)
+);
+
+-- Clicks per referer host; an empty host counts clicks without a Referer.
+CREATE TABLE IF NOT EXISTS analytics_referer_rollups (
+    short_code   VARCHAR(16) NOT NULL,
+    granularity  TEXT NOT NULL CHECK (granularity IN ('hour', 'day')),
+    bucket       TIMESTAMP WITH TIME ZONE NOT NULL,
+    referer_host TEXT NOT NULL,
+    clicks       BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (short_code, granularity, bucket, referer_host)
+);
+
+-- analytics_unique_ips turns an ip_sketch into a distinct-IP estimate:
+-- m * ln(m / zeros). A saturated sketch reports its upper bound m * ln(m).
+CREATE OR REPLACE FUNCTION analytics_unique_ips(sketch BIT VARYING)
+RETURNS BIGINT
+LANGUAGE SQL IMMUTABLE STRICT AS $$
+    SELECT CASE
+        WHEN bit_count(sketch) = 0 THEN 0
+        ELSE round(length(sketch) * ln(length(sketch)::numeric
+             / GREATEST(length(sketch) - bit_count(sketch), 1)))::BIGINT
+    END
+$$;
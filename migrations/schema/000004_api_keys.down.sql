@@ -0,0 +1,3 @@
+-- migrations/schema/000004_api_keys.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS owner;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,21 @@
+-- Migration: 000004_api_keys
+-- API keys and link ownership.
+--
+-- Only the SHA-256 of each key is stored; keys are long random strings, so a
+-- fast hash is enough and allows an indexed lookup. key_prefix keeps the first
+-- characters of the plaintext so operators can tell keys apart.
+-- Several keys may share an owner, which lets a key be rotated without losing
+-- access to the links created with it.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    key_hash BYTEA UNIQUE NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Links created without a key have no owner and can only be managed by admins.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner TEXT;
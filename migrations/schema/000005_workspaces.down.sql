@@ -0,0 +1,25 @@
+-- migrations/schema/000005_workspaces.down.sql
+-- Restoring global uniqueness fails if two workspaces share a short code;
+-- resolve those rows before rolling back.
+ALTER TABLE analytics_referer_rollups DROP CONSTRAINT IF EXISTS analytics_referer_rollups_pkey;
+DELETE FROM analytics_referer_rollups WHERE workspace_id <> '00000000-0000-0000-0000-000000000000';
+ALTER TABLE analytics_referer_rollups DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE analytics_referer_rollups ADD PRIMARY KEY (short_code, granularity, bucket, referer_host);
+
+ALTER TABLE analytics_rollups DROP CONSTRAINT IF EXISTS analytics_rollups_pkey;
+DELETE FROM analytics_rollups WHERE workspace_id <> '00000000-0000-0000-0000-000000000000';
+ALTER TABLE analytics_rollups DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE analytics_rollups ADD PRIMARY KEY (short_code, granularity, bucket);
+
+DROP INDEX IF EXISTS idx_analytics_workspace_short_code;
+ALTER TABLE analytics DROP COLUMN IF EXISTS workspace_id;
+CREATE INDEX IF NOT EXISTS idx_analytics_short_code ON analytics (short_code);
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS workspace_id;
+
+DROP INDEX IF EXISTS idx_urls_workspace_short_code;
+ALTER TABLE urls DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code);
+
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,51 @@
+-- Migration: 000005_workspaces
+-- Workspaces give each tenant its own short-code namespace.
+--
+-- The nil UUID is the default workspace: every existing row, and every
+-- request that does not resolve to a tenant, belongs to it. Settings left
+-- NULL or zero inherit the server configuration.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(32) UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    host TEXT UNIQUE,                     -- request Host that selects this workspace
+    base_url TEXT,                        -- prefix of returned short URLs
+    alias_min_length INT NOT NULL DEFAULT 0,
+    alias_max_length INT NOT NULL DEFAULT 0,
+    alias_pattern TEXT,
+    reserved_aliases TEXT[] NOT NULL DEFAULT '{}', -- added to the server's reserved list
+    max_links BIGINT NOT NULL DEFAULT 0,  -- 0 = unlimited
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO workspaces (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000000', 'default', 'Default')
+ON CONFLICT (id) DO NOTHING;
+
+-- Short codes are unique per workspace rather than globally.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS workspace_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES workspaces (id);
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+DROP INDEX IF EXISTS idx_urls_short_code;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_workspace_short_code ON urls (workspace_id, short_code);
+
+-- A key acts on the links of its own workspace only.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS workspace_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES workspaces (id);
+
+-- Clicks are attributed to (workspace_id, short_code). No foreign key: events
+-- outlive the links they refer to.
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS workspace_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000000';
+DROP INDEX IF EXISTS idx_analytics_short_code;
+CREATE INDEX IF NOT EXISTS idx_analytics_workspace_short_code ON analytics (workspace_id, short_code);
+
+ALTER TABLE analytics_rollups ADD COLUMN IF NOT EXISTS workspace_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE analytics_rollups DROP CONSTRAINT IF EXISTS analytics_rollups_pkey;
+ALTER TABLE analytics_rollups ADD PRIMARY KEY (workspace_id, short_code, granularity, bucket);
+
+ALTER TABLE analytics_referer_rollups ADD COLUMN IF NOT EXISTS workspace_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE analytics_referer_rollups DROP CONSTRAINT IF EXISTS analytics_referer_rollups_pkey;
+ALTER TABLE analytics_referer_rollups ADD PRIMARY KEY (workspace_id, short_code, granularity, bucket, referer_host);
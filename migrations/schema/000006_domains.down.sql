@@ -0,0 +1,15 @@
+-- migrations/schema/000006_domains.down.sql
+-- A workspace keeps only its oldest domain as its host.
+ALTER TABLE urls DROP COLUMN IF EXISTS domain;
+
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS host TEXT UNIQUE;
+UPDATE workspaces w
+SET host = d.host
+FROM (
+    SELECT DISTINCT ON (workspace_id) workspace_id, host
+    FROM domains
+    ORDER BY workspace_id, created_at, host
+) d
+WHERE d.workspace_id = w.id;
+
+DROP TABLE IF EXISTS domains;
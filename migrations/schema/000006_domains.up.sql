@@ -0,0 +1,23 @@
+-- Migration: 000006_domains
+-- Branded domains: any number of hosts per workspace, replacing the single
+-- workspaces.host column. A link records the domain it was created on and
+-- is only redirected on that host; links without one are served on every
+-- host of their workspace. Short codes stay unique per workspace, so one
+-- alias cannot be reused across a workspace's domains.
+CREATE TABLE IF NOT EXISTS domains (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    host TEXT UNIQUE NOT NULL,            -- lower-case, no port
+    workspace_id UUID NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_workspace_id ON domains (workspace_id);
+
+INSERT INTO domains (host, workspace_id)
+SELECT host, id FROM workspaces WHERE host IS NOT NULL
+ON CONFLICT (host) DO NOTHING;
+
+ALTER TABLE workspaces DROP COLUMN IF EXISTS host;
+
+-- A domain cannot be removed while links still point at it.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS domain TEXT REFERENCES domains (host);
@@ -0,0 +1,3 @@
+-- migrations/schema/000007_redirect_options.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS forward_query;
+ALTER TABLE urls DROP COLUMN IF EXISTS redirect_type;
@@ -0,0 +1,7 @@
+-- Migration: 000007_redirect_options
+-- Per-link redirect behaviour. A NULL redirect_type follows the server's
+-- REDIRECT_STATUS; forward_query merges the visitor's query string into the
+-- destination.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type SMALLINT
+    CHECK (redirect_type IN (301, 302, 307, 308));
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS forward_query BOOLEAN NOT NULL DEFAULT FALSE;
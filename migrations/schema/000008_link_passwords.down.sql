@@ -0,0 +1,2 @@
+-- migrations/schema/000008_link_passwords.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS password_hash;
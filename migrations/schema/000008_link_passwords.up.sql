@@ -0,0 +1,4 @@
+-- Migration: 000008_link_passwords
+-- Optional bcrypt hash of a password visitors must enter before a link
+-- redirects. NULL means the link is public.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT;
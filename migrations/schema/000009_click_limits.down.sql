@@ -0,0 +1,3 @@
+-- migrations/schema/000009_click_limits.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS uses;
+ALTER TABLE urls DROP COLUMN IF EXISTS max_clicks;
@@ -0,0 +1,7 @@
+-- Migration: 000009_click_limits
+-- Optional cap on how many times a link may redirect. uses counts redirects
+-- granted against the cap; unlike click_count, which the analytics worker
+-- updates in batches, it is incremented as each visit is allowed so it can
+-- enforce the limit. NULL max_clicks means unlimited.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT CHECK (max_clicks > 0);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS uses BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- migrations/schema/000010_link_schedule.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS fallback_url;
+ALTER TABLE urls DROP COLUMN IF EXISTS not_before;
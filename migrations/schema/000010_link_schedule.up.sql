@@ -0,0 +1,5 @@
+-- Migration: 000010_link_schedule
+-- Optional activation time: before not_before a link resolves to its
+-- fallback_url when it has one, and to 404 otherwise.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS not_before TIMESTAMPTZ;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS fallback_url TEXT;
@@ -0,0 +1,2 @@
+-- migrations/schema/000011_workspace_fallback.down.sql
+ALTER TABLE workspaces DROP COLUMN IF EXISTS fallback_url;
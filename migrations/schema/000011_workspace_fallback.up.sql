@@ -0,0 +1,4 @@
+-- Migration: 000011_workspace_fallback
+-- Where visitors of a workspace's expired, exhausted or not yet active links
+-- are sent when the link has no fallback_url of its own.
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS fallback_url TEXT;
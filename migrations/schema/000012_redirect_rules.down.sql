@@ -0,0 +1,2 @@
+-- migrations/schema/000012_redirect_rules.down.sql
+ALTER TABLE urls DROP COLUMN IF EXISTS rules;
@@ -0,0 +1,5 @@
+-- Migration: 000012_redirect_rules
+-- Ordered per-visitor destinations: a JSON array of
+-- {"browser": [...], "os": [...], "language": [...], "url": "..."} objects,
+-- the first matching rule overriding original_url. NULL = no rules.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS rules JSONB;
@@ -0,0 +1,2 @@
+-- migrations/schema/000013_click_country.down.sql
+ALTER TABLE analytics DROP COLUMN IF EXISTS country;
@@ -0,0 +1,4 @@
+-- Migration: 000013_click_country
+-- Visitor country resolved by the gateway's GeoIP lookup (ISO 3166-1
+-- alpha-2); NULL when GeoIP is disabled or the address is unknown.
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS country CHAR(2);
@@ -0,0 +1,4 @@
+-- migrations/schema/000014_link_variants.down.sql
+ALTER TABLE analytics DROP COLUMN IF EXISTS variant;
+ALTER TABLE urls DROP COLUMN IF EXISTS sticky_variants;
+ALTER TABLE urls DROP COLUMN IF EXISTS variants;
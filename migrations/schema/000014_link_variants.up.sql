@@ -0,0 +1,9 @@
+-- Migration: 000014_link_variants
+-- A/B split destinations: a JSON array of {"name", "url", "weight"} objects
+-- between which visitors no rule matched are divided by weight. NULL = no
+-- variants. sticky_variants keeps a visitor on the variant first picked.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Name of the variant a click was sent to; NULL for links without variants.
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS variant TEXT;
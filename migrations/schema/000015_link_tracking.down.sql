@@ -0,0 +1,5 @@
+-- migrations/schema/000015_link_tracking.down.sql
+ALTER TABLE analytics DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE analytics DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE analytics DROP COLUMN IF EXISTS utm_source;
+ALTER TABLE urls DROP COLUMN IF EXISTS tracking;
@@ -0,0 +1,10 @@
+-- Migration: 000015_link_tracking
+-- Campaign tags added to the destination on every redirect: a JSON object
+-- {"utm_source", "utm_medium", "utm_campaign", "extra": {...}}. NULL = none.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tracking JSONB;
+
+-- UTM tags the link carried when clicked, so stats keep crediting clicks to
+-- the campaign they were made under after the link is retagged.
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS utm_source TEXT;
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS utm_medium TEXT;
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS utm_campaign TEXT;
@@ -0,0 +1,3 @@
+-- migrations/schema/000016_canonical_hash.down.sql
+DROP INDEX IF EXISTS idx_urls_canonical_hash;
+ALTER TABLE urls DROP COLUMN IF EXISTS canonical_hash;
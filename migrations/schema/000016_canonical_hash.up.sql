@@ -0,0 +1,9 @@
+-- Migration: 000016_canonical_hash
+-- SHA-256 of the canonical destination (see service.Canonicalize), so a
+-- create request with reuse_existing can find a link it may return instead
+-- of minting a new code. Only links with a generated code carry one; custom
+-- aliases, and links created before this migration, are never reused.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS canonical_hash BYTEA;
+
+CREATE INDEX IF NOT EXISTS idx_urls_canonical_hash ON urls (workspace_id, canonical_hash)
+    WHERE canonical_hash IS NOT NULL;
@@ -0,0 +1,2 @@
+-- migrations/schema/000017_short_code_counter.down.sql
+DROP TABLE IF EXISTS short_code_counter;
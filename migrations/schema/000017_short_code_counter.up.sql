@@ -0,0 +1,12 @@
+-- Migration: 000017_short_code_counter
+-- Shared counter behind SHORT_CODE_STRATEGY=counter. Each gateway replica
+-- reserves a block of values with one UPDATE ... RETURNING, so replicas
+-- never hand out the same value. The single row is enforced by the
+-- always-true primary key.
+CREATE TABLE IF NOT EXISTS short_code_counter (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    next_value BIGINT NOT NULL
+);
+
+INSERT INTO short_code_counter (id, next_value) VALUES (TRUE, 0)
+    ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,3 @@
+-- migrations/schema/000018_short_code_pool.down.sql
+DROP INDEX IF EXISTS idx_urls_short_code;
+DROP TABLE IF EXISTS short_code_pool;
@@ -0,0 +1,11 @@
+-- Migration: 000018_short_code_pool
+-- Pre-generated codes behind SHORT_CODE_STRATEGY=pool. A background job
+-- keeps the table topped up; replicas lease codes by deleting them and put
+-- back the ones they did not use when they shut down.
+CREATE TABLE IF NOT EXISTS short_code_pool (
+    code VARCHAR(16) PRIMARY KEY
+);
+
+-- Filling skips codes already used by a link in any workspace; the unique
+-- index on (workspace_id, short_code) cannot serve that lookup.
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls (short_code);
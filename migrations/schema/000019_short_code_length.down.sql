@@ -0,0 +1,2 @@
+-- migrations/schema/000019_short_code_length.down.sql
+ALTER TABLE short_code_counter DROP COLUMN IF EXISTS code_length;
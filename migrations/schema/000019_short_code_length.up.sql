@@ -0,0 +1,6 @@
+-- Migration: 000019_short_code_length
+-- Length generated short codes were last promoted to, so a restarted
+-- gateway does not fall back to SHORT_CODE_LENGTH and collide its way up
+-- again. 0 means never promoted. Stored on the short_code_counter row
+-- that already holds the other shared code generation state.
+ALTER TABLE short_code_counter ADD COLUMN IF NOT EXISTS code_length INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- migrations/schema/000020_workspace_link_count.down.sql
+DROP TRIGGER IF EXISTS urls_workspace_link_removed ON urls;
+DROP TRIGGER IF EXISTS urls_workspace_link_added ON urls;
+DROP FUNCTION IF EXISTS workspace_link_removed();
+DROP FUNCTION IF EXISTS workspace_link_added();
+ALTER TABLE workspaces DROP COLUMN IF EXISTS link_count;
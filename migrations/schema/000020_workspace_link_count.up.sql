@@ -0,0 +1,47 @@
+-- Migration: 000020_workspace_link_count
+-- Enforces workspace link quotas in the database. Counting a workspace's
+-- links before inserting cannot stop concurrent creates from overshooting
+-- max_links, so every insert now raises link_count with an UPDATE guarded
+-- by the quota, which also serialises creates on the workspace row, and
+-- fails once the quota is reached. Deletes lower the count again.
+--
+-- Only workspaces with a quota keep a count, so unlimited ones (the default
+-- workspace included) never contend on their row. Setting a quota on an
+-- existing workspace needs a recount, as done below.
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS link_count BIGINT NOT NULL DEFAULT 0;
+
+UPDATE workspaces w
+SET link_count = (SELECT COUNT(*) FROM urls u WHERE u.workspace_id = w.id)
+WHERE w.max_links > 0;
+
+CREATE OR REPLACE FUNCTION workspace_link_added() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE workspaces SET link_count = link_count + 1
+    WHERE id = NEW.workspace_id AND max_links > 0 AND link_count < max_links;
+    IF NOT FOUND AND EXISTS (SELECT 1 FROM workspaces WHERE id = NEW.workspace_id AND max_links > 0) THEN
+        RAISE EXCEPTION 'workspace link quota exceeded'
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'workspace_link_quota';
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION workspace_link_removed() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE workspaces SET link_count = GREATEST(link_count - 1, 0)
+    WHERE id = OLD.workspace_id AND max_links > 0;
+    RETURN NULL;
+END
+$$;
+
+-- AFTER triggers fire only for rows actually written, so batch rows
+-- skipped by ON CONFLICT DO NOTHING use no quota.
+DROP TRIGGER IF EXISTS urls_workspace_link_added ON urls;
+CREATE TRIGGER urls_workspace_link_added AFTER INSERT ON urls
+    FOR EACH ROW EXECUTE FUNCTION workspace_link_added();
+
+DROP TRIGGER IF EXISTS urls_workspace_link_removed ON urls;
+CREATE TRIGGER urls_workspace_link_removed AFTER DELETE ON urls
+    FOR EACH ROW EXECUTE FUNCTION workspace_link_removed();
@@ -160,6 +160,10 @@ func (c *Consumer) Run(ctx context.Context) error {
 }
 
 // flush decodes all deliveries in the batch, bulk-inserts them, then acks or nacks.
+// BulkInsert also folds the batch into the hourly and daily rollups in the same
+// transaction, so raw rows and rollups always agree. Counting is at-least-once:
+// a crash after the commit but before the ack redelivers the batch, and its
+// clicks are then counted twice in both.
 //
 // Ack strategy: a single Ack(multiple=true) on the last delivery tag acks the
 // entire batch in one AMQP frame, matching the single DB round-trip.
@@ -176,10 +180,16 @@ func (c *Consumer) flush(ctx context.Context, deliveries []amqp.Delivery) error
 
 	for _, d := range deliveries {
 		var e struct {
-			ShortCode string    `json:"short_code"`
-			ClickedAt time.Time `json:"clicked_at"`
-			IP        string    `json:"ip"`
-			Referer   string    `json:"referer"`
+			WorkspaceID string    `json:"workspace_id"`
+			ShortCode   string    `json:"short_code"`
+			ClickedAt   time.Time `json:"clicked_at"`
+			IP          string    `json:"ip"`
+			Referer     string    `json:"referer"`
+			Country     string    `json:"country"`
+			Variant     string    `json:"variant"`
+			UTMSource   string    `json:"utm_source"`
+			UTMMedium   string    `json:"utm_medium"`
+			UTMCampaign string    `json:"utm_campaign"`
 		}
 		if err := json.Unmarshal(d.Body, &e); err != nil {
 			c.logger.Warn("analytics-worker: malformed message, sending to DLQ",
@@ -188,10 +198,16 @@ func (c *Consumer) flush(ctx context.Context, deliveries []amqp.Delivery) error
 			continue
 		}
 		events = append(events, repository.ClickEvent{
-			ShortCode: e.ShortCode,
-			ClickedAt: e.ClickedAt,
-			IP:        e.IP,
-			Referer:   e.Referer,
+			WorkspaceID: e.WorkspaceID,
+			ShortCode:   e.ShortCode,
+			ClickedAt:   e.ClickedAt,
+			IP:          e.IP,
+			Referer:     e.Referer,
+			Country:     e.Country,
+			Variant:     e.Variant,
+			UTMSource:   e.UTMSource,
+			UTMMedium:   e.UTMMedium,
+			UTMCampaign: e.UTMCampaign,
 		})
 	}
 
@@ -3,18 +3,51 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// defaultWorkspaceID is the gateway's default workspace (the nil UUID).
+const defaultWorkspaceID = "00000000-0000-0000-0000-000000000000"
+
 // ClickEvent is the analytics record written to the database.
+// Short codes are unique per workspace, so a click is identified by the pair.
 type ClickEvent struct {
-	ShortCode string
-	ClickedAt time.Time
-	IP        string
-	Referer   string
+	WorkspaceID string // UUID; empty means the default workspace
+	ShortCode   string
+	ClickedAt   time.Time
+	IP          string
+	Referer     string
+	Country     string // ISO 3166-1 alpha-2; empty when unknown
+	Variant     string // A/B variant the visit was sent to; empty for links without variants
+	UTMSource   string // UTM tags the link carried; empty when untagged
+	UTMMedium   string
+	UTMCampaign string
+}
+
+// workspace returns the event's workspace ID with the default filled in, for
+// events published before workspaces existed.
+func (e ClickEvent) workspace() string {
+	if e.WorkspaceID == "" {
+		return defaultWorkspaceID
+	}
+	return e.WorkspaceID
+}
+
+// linkKey identifies a link: a short code within its workspace.
+type linkKey struct {
+	workspaceID string
+	shortCode   string
+}
+
+func (k linkKey) less(o linkKey) bool {
+	if k.workspaceID != o.workspaceID {
+		return k.workspaceID < o.workspaceID
+	}
+	return k.shortCode < o.shortCode
 }
 
 // Repository wraps a pgxpool.Pool to write analytics events.
@@ -27,18 +60,25 @@ func New(db *pgxpool.Pool) *Repository {
 	return &Repository{db: db}
 }
 
-// BulkInsert inserts all events in a single SQL statement — one round-trip
-// regardless of batch size.
+// BulkInsert inserts all events and applies the matching urls.click_count
+// increments in one transaction, so the counter never drifts from the stored
+// events: a redelivered batch either failed as a whole or was never acked.
 //
-// For a batch of N events it builds:
+// The events are written in a single SQL statement — one round-trip
+// regardless of batch size. For a batch of N events it builds:
 //
-//	INSERT INTO analytics (short_code, clicked_at, ip, referer)
-//	VALUES ($1,$2,$3,$4), ($5,$6,$7,$8), ...
+//	INSERT INTO analytics (workspace_id, short_code, clicked_at, ip, referer, country, variant,
+//	                       utm_source, utm_medium, utm_campaign)
+//	VALUES ($1,…,$10), ($11,…,$20), ...
 //
 // pgx positional parameters ($1, $2, …) are numbered from 1 and each event
-// occupies 4 consecutive slots: short_code, clicked_at, ip, referer.
-// All values are passed as a flat []any slice and pgx maps each $N to
-// args[N-1].
+// occupies 10 consecutive slots: workspace_id, short_code, clicked_at, ip,
+// referer, country, variant, utm_source, utm_medium, utm_campaign. All values are passed as a flat []any slice and pgx maps
+// each $N to args[N-1].
+//
+// Click counts are aggregated per link first, so a hot link clicked
+// 1,000 times in a batch costs one row update, not 1,000. The same
+// transaction adds the batch to the hourly and daily rollup tables.
 func (r *Repository) BulkInsert(ctx context.Context, events []ClickEvent) error {
 	if len(events) == 0 {
 		return nil
@@ -46,19 +86,86 @@ func (r *Repository) BulkInsert(ctx context.Context, events []ClickEvent) error
 
 	// Pre-allocate one placeholder tuple per event.
 	placeholders := make([]string, len(events))
-	// Pre-allocate the args slice: 4 values × N events.
-	args := make([]any, 0, len(events)*4)
+	// Pre-allocate the args slice: 10 values × N events.
+	args := make([]any, 0, len(events)*10)
 
 	for i, e := range events {
-		base := i * 4
-		// ($1,$2,$3,$4) for i=0, ($5,$6,$7,$8) for i=1, etc.
-		placeholders[i] = fmt.Sprintf("($%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4)
-		args = append(args, e.ShortCode, e.ClickedAt, e.IP, e.Referer)
+		base := i * 10
+		// ($1,…,$10) for i=0, ($11,…,$20) for i=1, etc.
+		placeholders[i] = fmt.Sprintf("($%d::uuid,$%d,$%d,$%d,$%d,NULLIF($%d,''),NULLIF($%d,''),NULLIF($%d,''),NULLIF($%d,''),NULLIF($%d,''))",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10)
+		args = append(args, e.workspace(), e.ShortCode, e.ClickedAt, e.IP, e.Referer, e.Country, e.Variant,
+			e.UTMSource, e.UTMMedium, e.UTMCampaign)
 	}
 
-	query := "INSERT INTO analytics (short_code, clicked_at, ip, referer) VALUES " +
+	query := "INSERT INTO analytics (workspace_id, short_code, clicked_at, ip, referer, country, variant, " +
+		"utm_source, utm_medium, utm_campaign) VALUES " +
 		strings.Join(placeholders, ", ")
 
-	_, err := r.db.Exec(ctx, query, args...)
-	return err
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+
+	links, counts := aggregateClicks(events)
+	workspaces := make([]string, len(links))
+	codes := make([]string, len(links))
+	for i, l := range links {
+		workspaces[i], codes[i] = l.workspaceID, l.shortCode
+	}
+	// Rows are locked in (workspace_id, short_code) order so two workers
+	// flushing overlapping batches cannot deadlock. Codes of deleted links
+	// simply match no row.
+	if _, err := tx.Exec(ctx, `
+		WITH delta AS (
+			SELECT * FROM unnest($1::uuid[], $2::text[], $3::bigint[]) AS d(workspace_id, short_code, clicks)
+		), locked AS (
+			SELECT u.id, delta.clicks
+			FROM urls u
+			JOIN delta ON delta.workspace_id = u.workspace_id AND delta.short_code = u.short_code
+			ORDER BY u.workspace_id, u.short_code
+			FOR UPDATE OF u
+		)
+		UPDATE urls
+		SET click_count = COALESCE(urls.click_count, 0) + locked.clicks
+		FROM locked
+		WHERE urls.id = locked.id`, workspaces, codes, counts); err != nil {
+		return err
+	}
+
+	rollups := newRollupSet()
+	for _, e := range events {
+		rollups.add(e)
+	}
+	if err := rollups.upsert(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// aggregateClicks counts events per link, returning parallel slices sorted
+// by workspace, then short code.
+func aggregateClicks(events []ClickEvent) ([]linkKey, []int64) {
+	byLink := make(map[linkKey]int64)
+	for _, e := range events {
+		byLink[linkKey{e.workspace(), e.ShortCode}]++
+	}
+
+	links := make([]linkKey, 0, len(byLink))
+	for l := range byLink {
+		links = append(links, l)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].less(links[j]) })
+
+	counts := make([]int64, len(links))
+	for i, l := range links {
+		counts[i] = byLink[l]
+	}
+	return links, counts
 }
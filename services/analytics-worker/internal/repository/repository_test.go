@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAggregateClicks verifies per-link counting and the sorted order that
+// BulkInsert relies on to lock urls rows deterministically. The same code in
+// two workspaces is two links.
+func TestAggregateClicks(t *testing.T) {
+	const ws = "6f1c3a52-0000-4000-8000-000000000001"
+	now := time.Now()
+	events := []ClickEvent{
+		{ShortCode: "b", ClickedAt: now},
+		{ShortCode: "a", ClickedAt: now},
+		{WorkspaceID: ws, ShortCode: "a", ClickedAt: now},
+		{ShortCode: "b", ClickedAt: now},
+		{ShortCode: "c", ClickedAt: now},
+		{WorkspaceID: defaultWorkspaceID, ShortCode: "b", ClickedAt: now},
+	}
+
+	links, counts := aggregateClicks(events)
+	assert.Equal(t, []linkKey{
+		{defaultWorkspaceID, "a"},
+		{defaultWorkspaceID, "b"},
+		{defaultWorkspaceID, "c"},
+		{ws, "a"},
+	}, links)
+	assert.Equal(t, []int64{1, 3, 1, 1}, counts)
+}
+
+// TestRollupSet verifies that each event lands in one hour and one day bucket,
+// aligned in UTC, with referer hosts counted per bucket.
+func TestRollupSet(t *testing.T) {
+	paris := time.FixedZone("CET", 3600)
+	// 00:30 in Paris is 23:30 UTC the previous day.
+	at := time.Date(2024, 3, 2, 0, 30, 0, 0, paris)
+	events := []ClickEvent{
+		{ShortCode: "a", ClickedAt: at, IP: "1.1.1.1", Referer: "https://News.example.com:443/x"},
+		{ShortCode: "a", ClickedAt: at.Add(10 * time.Minute), IP: "1.1.1.1"},
+		{ShortCode: "a", ClickedAt: at.Add(-40 * time.Minute), IP: "2.2.2.2", Referer: "https://news.example.com/y"},
+		{ShortCode: "b", ClickedAt: at, IP: "3.3.3.3"},
+	}
+
+	set := newRollupSet()
+	for _, e := range events {
+		set.add(e)
+	}
+
+	hour := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	prevHour := hour.Add(-time.Hour)
+	a := linkKey{defaultWorkspaceID, "a"}
+	b := linkKey{defaultWorkspaceID, "b"}
+
+	require.Len(t, set.counters, 5)
+	assert.Equal(t, int64(2), set.counters[rollupKey{a, granularityHour, hour}].clicks)
+	assert.Equal(t, int64(1), set.counters[rollupKey{a, granularityHour, prevHour}].clicks)
+	assert.Equal(t, int64(3), set.counters[rollupKey{a, granularityDay, day}].clicks)
+	assert.Equal(t, int64(1), set.counters[rollupKey{b, granularityDay, day}].clicks)
+
+	// Only the day bucket saw both of a's IPs.
+	assert.Equal(t, 1, sketchOnes(set.counters[rollupKey{a, granularityHour, hour}].sketch))
+	assert.Equal(t, 2, sketchOnes(set.counters[rollupKey{a, granularityDay, day}].sketch))
+
+	dayKey := rollupKey{a, granularityDay, day}
+	assert.Equal(t, int64(2), set.referers[refererKey{dayKey, "news.example.com"}])
+	assert.Equal(t, int64(1), set.referers[refererKey{dayKey, ""}])
+}
+
+// TestIPSketch checks that the sketch is deterministic and that distinct IPs
+// set roughly one bit each while the bitmap is sparse.
+func TestIPSketch(t *testing.T) {
+	var a, b ipSketch
+	for i := 0; i < 100; i++ {
+		ip := fmt.Sprintf("10.0.0.%d", i)
+		a.add(ip)
+		a.add(ip)
+		b.add(ip)
+	}
+	assert.Equal(t, a, b)
+
+	ones := sketchOnes(a)
+	assert.LessOrEqual(t, ones, 100)
+	assert.Greater(t, ones, 90, "collisions should be rare at this fill rate")
+}
+
+func TestRefererHost(t *testing.T) {
+	assert.Equal(t, "", refererHost(""))
+	assert.Equal(t, "example.com", refererHost("https://EXAMPLE.com:8443/path?q=1"))
+	assert.Equal(t, "", refererHost("not a url"))
+	assert.Equal(t, "", refererHost("%zz"))
+}
+
+func sketchOnes(s ipSketch) int {
+	n := 0
+	for _, b := range s {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
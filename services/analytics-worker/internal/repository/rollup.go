@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rollup granularities stored in analytics_rollups.granularity.
+const (
+	granularityHour = "hour"
+	granularityDay  = "day"
+)
+
+// sketchBits is the width of the ip_sketch bitmap; it must match the BIT(n)
+// column in migration 000003. 2048 bits keep the estimate within a few
+// percent up to roughly ten thousand distinct IPs per bucket.
+const sketchBits = 2048
+
+// rebuildChunk bounds how many links RebuildRollups aggregates in memory at
+// once.
+const rebuildChunk = 500
+
+// ipSketch is a linear-counting bitmap. Adding an IP sets one bit chosen by
+// hash; the sketches of two batches merge with bitwise OR, which is what the
+// upsert does in SQL.
+type ipSketch [sketchBits / 8]byte
+
+func (s *ipSketch) add(ip string) {
+	h := fnv.New64a()
+	h.Write([]byte(ip))
+	bit := h.Sum64() % sketchBits
+	s[bit/8] |= 1 << (7 - bit%8)
+}
+
+type rollupKey struct {
+	linkKey
+	granularity string
+	bucket      time.Time
+}
+
+type refererKey struct {
+	rollupKey
+	host string
+}
+
+type rollupCounter struct {
+	clicks int64
+	sketch ipSketch
+}
+
+// rollupSet accumulates hourly and daily counters for a set of events before
+// they are upserted in one statement per table.
+type rollupSet struct {
+	counters map[rollupKey]*rollupCounter
+	referers map[refererKey]int64
+}
+
+func newRollupSet() *rollupSet {
+	return &rollupSet{
+		counters: make(map[rollupKey]*rollupCounter),
+		referers: make(map[refererKey]int64),
+	}
+}
+
+// add counts e in its hour and day buckets. Buckets are aligned in UTC.
+func (s *rollupSet) add(e ClickEvent) {
+	at := e.ClickedAt.UTC()
+	host := refererHost(e.Referer)
+	link := linkKey{e.workspace(), e.ShortCode}
+	for _, k := range []rollupKey{
+		{link, granularityHour, at.Truncate(time.Hour)},
+		{link, granularityDay, at.Truncate(24 * time.Hour)},
+	} {
+		c, ok := s.counters[k]
+		if !ok {
+			c = &rollupCounter{}
+			s.counters[k] = c
+		}
+		c.clicks++
+		if e.IP != "" {
+			c.sketch.add(e.IP)
+		}
+		s.referers[refererKey{k, host}]++
+	}
+}
+
+func (s *rollupSet) empty() bool {
+	return len(s.counters) == 0
+}
+
+// upsert adds the accumulated counters to the rollup tables inside tx.
+// Rows are written in primary-key order so concurrent flushes touching the
+// same buckets lock them in the same order and cannot deadlock.
+func (s *rollupSet) upsert(ctx context.Context, tx pgx.Tx) error {
+	if s.empty() {
+		return nil
+	}
+
+	keys := make([]rollupKey, 0, len(s.counters))
+	for k := range s.counters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+
+	workspaces := make([]string, len(keys))
+	codes := make([]string, len(keys))
+	grans := make([]string, len(keys))
+	buckets := make([]time.Time, len(keys))
+	clicks := make([]int64, len(keys))
+	sketches := make([][]byte, len(keys))
+	for i, k := range keys {
+		c := s.counters[k]
+		workspaces[i], codes[i], grans[i], buckets[i] = k.workspaceID, k.shortCode, k.granularity, k.bucket
+		clicks[i] = c.clicks
+		sketches[i] = c.sketch[:]
+	}
+
+	// The sketch travels as bytea and is cast through a hex bit literal
+	// ('x…'::bit(n)), which avoids relying on driver support for bit arrays.
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO analytics_rollups (workspace_id, short_code, granularity, bucket, clicks, ip_sketch)
+		SELECT r.workspace_id, r.short_code, r.granularity, r.bucket, r.clicks,
+		       ('x' || encode(r.sketch, 'hex'))::bit(2048)
+		FROM unnest($1::uuid[], $2::text[], $3::text[], $4::timestamptz[], $5::bigint[], $6::bytea[])
+		     AS r(workspace_id, short_code, granularity, bucket, clicks, sketch)
+		ON CONFLICT (workspace_id, short_code, granularity, bucket) DO UPDATE
+		SET clicks    = analytics_rollups.clicks + EXCLUDED.clicks,
+		    ip_sketch = analytics_rollups.ip_sketch | EXCLUDED.ip_sketch`,
+		workspaces, codes, grans, buckets, clicks, sketches); err != nil {
+		return err
+	}
+
+	refKeys := make([]refererKey, 0, len(s.referers))
+	for k := range s.referers {
+		refKeys = append(refKeys, k)
+	}
+	sort.Slice(refKeys, func(i, j int) bool {
+		if !refKeys[i].rollupKey.equal(refKeys[j].rollupKey) {
+			return refKeys[i].rollupKey.less(refKeys[j].rollupKey)
+		}
+		return refKeys[i].host < refKeys[j].host
+	})
+
+	workspaces = make([]string, len(refKeys))
+	codes = make([]string, len(refKeys))
+	grans = make([]string, len(refKeys))
+	buckets = make([]time.Time, len(refKeys))
+	hosts := make([]string, len(refKeys))
+	clicks = make([]int64, len(refKeys))
+	for i, k := range refKeys {
+		workspaces[i], codes[i], grans[i], buckets[i] = k.workspaceID, k.shortCode, k.granularity, k.bucket
+		hosts[i] = k.host
+		clicks[i] = s.referers[k]
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO analytics_referer_rollups (workspace_id, short_code, granularity, bucket, referer_host, clicks)
+		SELECT * FROM unnest($1::uuid[], $2::text[], $3::text[], $4::timestamptz[], $5::text[], $6::bigint[])
+		ON CONFLICT (workspace_id, short_code, granularity, bucket, referer_host) DO UPDATE
+		SET clicks = analytics_referer_rollups.clicks + EXCLUDED.clicks`,
+		workspaces, codes, grans, buckets, hosts, clicks)
+	return err
+}
+
+func (k rollupKey) less(o rollupKey) bool {
+	if k.linkKey != o.linkKey {
+		return k.linkKey.less(o.linkKey)
+	}
+	if k.granularity != o.granularity {
+		return k.granularity < o.granularity
+	}
+	return k.bucket.Before(o.bucket)
+}
+
+func (k rollupKey) equal(o rollupKey) bool {
+	return k.linkKey == o.linkKey && k.granularity == o.granularity && k.bucket.Equal(o.bucket)
+}
+
+// refererHost reduces a Referer header to its lower-cased host name, without
+// port. Clicks without a referer, or with one that has no host, map to "".
+func refererHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// EarliestClick returns the time of the oldest raw analytics row, or the zero
+// time if the table is empty.
+func (r *Repository) EarliestClick(ctx context.Context) (time.Time, error) {
+	var t *time.Time
+	if err := r.db.QueryRow(ctx, `SELECT MIN(clicked_at) FROM analytics`).Scan(&t); err != nil {
+		return time.Time{}, err
+	}
+	if t == nil {
+		return time.Time{}, nil
+	}
+	return t.UTC(), nil
+}
+
+// RebuildRollups recomputes the hourly and daily rollups of the UTC day that
+// contains day from the raw analytics rows, replacing whatever was stored.
+// It returns the number of raw events read.
+//
+// The day is rebuilt in one transaction that holds a lock on the rollup
+// tables, so concurrent worker flushes wait until it commits. A batch whose
+// raw rows committed first is read from analytics; any later batch adds its
+// counters on top of the rebuilt rows. Either way no click is lost or counted
+// twice.
+func (r *Repository) RebuildRollups(ctx context.Context, day time.Time) (int64, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	end := start.Add(24 * time.Hour)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	// SHARE ROW EXCLUSIVE conflicts with the ROW EXCLUSIVE lock taken by
+	// BulkInsert's upserts but not with plain reads.
+	if _, err := tx.Exec(ctx, `
+		LOCK TABLE analytics_rollups, analytics_referer_rollups
+		IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM analytics_rollups
+		WHERE (granularity = 'hour' AND bucket >= $1 AND bucket < $2)
+		   OR (granularity = 'day' AND bucket = $1)`, start, end); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM analytics_referer_rollups
+		WHERE (granularity = 'hour' AND bucket >= $1 AND bucket < $2)
+		   OR (granularity = 'day' AND bucket = $1)`, start, end); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT workspace_id::text, short_code FROM analytics
+		WHERE clicked_at >= $1 AND clicked_at < $2
+		ORDER BY 1, 2`, start, end)
+	if err != nil {
+		return 0, err
+	}
+	links, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (linkKey, error) {
+		var l linkKey
+		err := row.Scan(&l.workspaceID, &l.shortCode)
+		return l, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Aggregate a chunk of links at a time so memory stays bounded on busy
+	// days; every link's rows land in exactly one chunk.
+	var total int64
+	for len(links) > 0 {
+		n := min(rebuildChunk, len(links))
+		workspaces := make([]string, n)
+		codes := make([]string, n)
+		for i, l := range links[:n] {
+			workspaces[i], codes[i] = l.workspaceID, l.shortCode
+		}
+		links = links[n:]
+
+		rows, err := tx.Query(ctx, `
+			SELECT workspace_id::text, short_code, clicked_at, COALESCE(ip, ''), COALESCE(referer, '')
+			FROM analytics
+			WHERE clicked_at >= $1 AND clicked_at < $2
+			  AND (workspace_id, short_code) IN (SELECT * FROM unnest($3::uuid[], $4::text[]))`,
+			start, end, workspaces, codes)
+		if err != nil {
+			return 0, err
+		}
+		set := newRollupSet()
+		var e ClickEvent
+		_, err = pgx.ForEachRow(rows, []any{&e.WorkspaceID, &e.ShortCode, &e.ClickedAt, &e.IP, &e.Referer}, func() error {
+			set.add(e)
+			total++
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if err := set.upsert(ctx, tx); err != nil {
+			return 0, err
+		}
+	}
+
+	return total, tx.Commit(ctx)
+}
@@ -0,0 +1,135 @@
+// Command apikey manages gateway API keys.
+//
+//	apikey create -name ci -owner team-a [-admin] [-workspace acme]
+//	apikey list
+//	apikey revoke -id <uuid>
+//
+// The plaintext key is printed once by create; only its hash is stored.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/config"
+	"github.com/zhejian/url-shortener/gateway/internal/infra"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+const usage = "usage: apikey create -name NAME -owner OWNER [-admin] [-workspace SLUG] | list | revoke -id ID"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	cfg := config.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := infra.NewPostgresPool(ctx, cfg.Database.ConnectionString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	repo := repository.NewAPIKeyRepository(db)
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "create":
+		err = create(ctx, repo, repository.NewWorkspaceRepository(db), args)
+	case "list":
+		err = list(ctx, repo)
+	case "revoke":
+		err = revoke(ctx, repo, args)
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", cmd, usage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func create(ctx context.Context, repo *repository.APIKeyRepository, workspaces *repository.WorkspaceRepository, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	name := fs.String("name", "", "human-readable label for the key")
+	owner := fs.String("owner", "", "identity that owns links created with the key")
+	admin := fs.Bool("admin", false, "grant the admin scope (manage every link in the workspace)")
+	slug := fs.String("workspace", "", "slug of the workspace the key acts in (default workspace if empty)")
+	_ = fs.Parse(args)
+
+	if *name == "" || *owner == "" {
+		return fmt.Errorf("-name and -owner are required")
+	}
+	workspaceID := model.DefaultWorkspaceID
+	if *slug != "" {
+		ws, err := workspaces.GetBySlug(ctx, *slug)
+		if err != nil {
+			return fmt.Errorf("workspace %q: %w", *slug, err)
+		}
+		workspaceID = ws.ID
+	}
+
+	plaintext, err := auth.GenerateKey()
+	if err != nil {
+		return err
+	}
+	key := &model.APIKey{
+		Name:        *name,
+		Owner:       *owner,
+		WorkspaceID: workspaceID,
+		Prefix:      plaintext[:auth.DisplayPrefixLen],
+	}
+	if *admin {
+		key.Scopes = []string{auth.ScopeAdmin}
+	}
+	if err := repo.Create(ctx, key, auth.HashKey(plaintext)); err != nil {
+		return err
+	}
+
+	fmt.Printf("id:    %s\nowner: %s\nkey:   %s\n", key.ID, key.Owner, plaintext)
+	fmt.Fprintln(os.Stderr, "Store the key now; it cannot be shown again.")
+	return nil
+}
+
+func list(ctx context.Context, repo *repository.APIKeyRepository) error {
+	keys, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tNAME\tOWNER\tWORKSPACE\tSCOPES\tCREATED\tREVOKED")
+	for _, k := range keys {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Prefix, k.Name, k.Owner, k.WorkspaceID,
+			strings.Join(k.Scopes, ","), k.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return w.Flush()
+}
+
+func revoke(ctx context.Context, repo *repository.APIKeyRepository, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	idFlag := fs.String("id", "", "ID of the key to revoke")
+	_ = fs.Parse(args)
+
+	id, err := uuid.Parse(*idFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -id %q: %w", *idFlag, err)
+	}
+	if err := repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+	fmt.Printf("revoked %s\n", id)
+	return nil
+}
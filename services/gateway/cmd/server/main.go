@@ -13,10 +13,13 @@ import (
 	"github.com/zhejian/url-shortener/gateway/internal/analytics"
 	"github.com/zhejian/url-shortener/gateway/internal/cache"
 	"github.com/zhejian/url-shortener/gateway/internal/config"
+	"github.com/zhejian/url-shortener/gateway/internal/geoip"
 	"github.com/zhejian/url-shortener/gateway/internal/infra"
+	"github.com/zhejian/url-shortener/gateway/internal/keypool"
 	"github.com/zhejian/url-shortener/gateway/internal/observability"
 	"github.com/zhejian/url-shortener/gateway/internal/ratelimit"
 	"github.com/zhejian/url-shortener/gateway/internal/server"
+	"github.com/zhejian/url-shortener/gateway/internal/service"
 )
 
 func main() {
@@ -89,7 +92,41 @@ func main() {
 		obs.Logger.Info("Analytics publisher enabled")
 	}
 
-	srv := server.NewServer(cfg, db, cacheProvider, rateLimiter, obs, pub)
+	// Setup GeoIP (optional — disabled when GEOIP_DB_PATH is empty)
+	var geo *geoip.Resolver
+	if cfg.GeoIP.Enabled {
+		geo, err = geoip.Open(cfg.GeoIP.DBPath, obs.Logger)
+		if err != nil {
+			log.Fatalf("Failed to load GeoIP database: %v", err)
+		}
+		obs.Logger.Info("GeoIP enabled")
+	}
+
+	// Start the short code pool (only with SHORT_CODE_STRATEGY=pool).
+	// Every replica runs one; a Postgres advisory lock lets only one refill at a time.
+	var keys *keypool.Pool
+	keysCtx, stopKeys := context.WithCancel(ctx)
+	defer stopKeys()
+	if cfg.App.ShortCodeStrategy == service.CodeStrategyPool {
+		keys = server.NewKeyPool(cfg, db, obs)
+		go keys.Run(keysCtx)
+	}
+
+	srv := server.NewServer(cfg, db, cacheProvider, rateLimiter, obs, pub, geo, keys)
+
+	// Start expiry reaper (optional — disabled when REAPER_INTERVAL=0).
+	// Every replica runs one; a Postgres advisory lock lets only one work per tick.
+	reaperCtx, stopReaper := context.WithCancel(ctx)
+	defer stopReaper()
+	if cfg.Reaper.Enabled {
+		go server.NewReaper(cfg, db, cacheProvider, obs).Run(reaperCtx)
+	}
+	// Reload the GeoIP database when it is replaced on disk.
+	geoCtx, stopGeo := context.WithCancel(ctx)
+	defer stopGeo()
+	if geo != nil && cfg.GeoIP.ReloadInterval > 0 {
+		go geo.Watch(geoCtx, cfg.GeoIP.ReloadInterval)
+	}
 
 	// Start server in a goroutine
 	go func() {
@@ -108,6 +145,7 @@ func main() {
 	<-quit
 
 	obs.Logger.Info("Shutting down server...")
+	stopReaper()
 
 	// Create shutdown context with 10 second timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -118,5 +156,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// No more links are created: return this replica's unused codes.
+	stopKeys()
+	if keys != nil {
+		keys.Close(shutdownCtx)
+	}
+
 	obs.Logger.Info("Server exited gracefully")
 }
@@ -0,0 +1,190 @@
+// Command workspace manages gateway workspaces (tenants).
+//
+//	workspace create -slug acme -name "Acme Corp" [-host go.acme.com] [-base-url https://go.acme.com]
+//	                 [-alias-min N] [-alias-max N] [-alias-pattern RE] [-reserved a,b] [-max-links N]
+//	                 [-fallback-url URL]
+//	workspace list
+//	workspace add-domain -workspace acme -host go.acme.com
+//	workspace remove-domain -workspace acme -host go.acme.com
+//
+// Settings left unset inherit the server configuration. -host on create
+// registers the workspace's first domain. Keys for the workspace are issued
+// with apikey create -workspace SLUG.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhejian/url-shortener/gateway/internal/config"
+	"github.com/zhejian/url-shortener/gateway/internal/infra"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/service"
+)
+
+const usage = "usage: workspace create -slug SLUG -name NAME [-host HOST] [-base-url URL] [-alias-min N] [-alias-max N] [-alias-pattern RE] [-reserved LIST] [-max-links N] [-fallback-url URL] | list | add-domain -workspace SLUG -host HOST | remove-domain -workspace SLUG -host HOST"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	cfg := config.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := infra.NewPostgresPool(ctx, cfg.Database.ConnectionString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	repo := repository.NewWorkspaceRepository(db)
+	domains := repository.NewDomainRepository(db)
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "create":
+		err = create(ctx, repo, domains, args)
+	case "list":
+		err = list(ctx, repo, domains)
+	case "add-domain":
+		err = addDomain(ctx, repo, domains, args)
+	case "remove-domain":
+		err = removeDomain(ctx, repo, domains, args)
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", cmd, usage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func create(ctx context.Context, repo *repository.WorkspaceRepository, domains *repository.DomainRepository, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	ws := &model.Workspace{}
+	fs.StringVar(&ws.Slug, "slug", "", "short unique identifier")
+	fs.StringVar(&ws.Name, "name", "", "display name")
+	host := fs.String("host", "", "domain that selects the workspace")
+	fs.StringVar(&ws.BaseURL, "base-url", "", "prefix of short URLs for links without a domain")
+	fs.IntVar(&ws.AliasMinLen, "alias-min", 0, "minimum custom alias length")
+	fs.IntVar(&ws.AliasMaxLen, "alias-max", 0, "maximum custom alias length")
+	fs.StringVar(&ws.AliasPattern, "alias-pattern", "", "regexp custom aliases must match")
+	reserved := fs.String("reserved", "", "comma-separated aliases reserved in addition to the server's")
+	fs.Int64Var(&ws.MaxLinks, "max-links", 0, "maximum number of links (0 = unlimited)")
+	fs.StringVar(&ws.FallbackURL, "fallback-url", "", "where visitors of expired, exhausted or inactive links are sent")
+	_ = fs.Parse(args)
+
+	if ws.Slug == "" || ws.Name == "" {
+		return fmt.Errorf("-slug and -name are required")
+	}
+	for _, word := range strings.Split(*reserved, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			ws.ReservedAliases = append(ws.ReservedAliases, word)
+		}
+	}
+	if ws.FallbackURL != "" {
+		if u, err := url.Parse(ws.FallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-fallback-url must be an absolute http(s) URL")
+		}
+	}
+	if ws.AliasMaxLen > service.MaxAliasLen {
+		return fmt.Errorf("-alias-max must not exceed %d", service.MaxAliasLen)
+	}
+	if _, err := service.DefaultAliasPolicy().Extend(ws.AliasMinLen, ws.AliasMaxLen, ws.AliasPattern, ws.ReservedAliases); err != nil {
+		return fmt.Errorf("invalid alias policy: %w", err)
+	}
+
+	if err := repo.Create(ctx, ws); err != nil {
+		return err
+	}
+	fmt.Printf("id:   %s\nslug: %s\n", ws.ID, ws.Slug)
+	if *host != "" {
+		if err := domains.Create(ctx, &model.Domain{Host: *host, WorkspaceID: ws.ID}); err != nil {
+			return fmt.Errorf("workspace created, but registering %q failed: %w", *host, err)
+		}
+		fmt.Printf("host: %s\n", strings.ToLower(*host))
+	}
+	return nil
+}
+
+func list(ctx context.Context, repo *repository.WorkspaceRepository, domains *repository.DomainRepository) error {
+	workspaces, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSLUG\tNAME\tDOMAINS\tBASE URL\tMAX LINKS\tCREATED")
+	for _, ws := range workspaces {
+		maxLinks := "-"
+		if ws.MaxLinks > 0 {
+			maxLinks = fmt.Sprint(ws.MaxLinks)
+		}
+		ds, err := domains.ListByWorkspace(ctx, ws.ID)
+		if err != nil {
+			return err
+		}
+		hosts := make([]string, len(ds))
+		for i, d := range ds {
+			hosts[i] = d.Host
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", ws.ID, ws.Slug, ws.Name, dash(strings.Join(hosts, ",")), dash(ws.BaseURL),
+			maxLinks, ws.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func addDomain(ctx context.Context, repo *repository.WorkspaceRepository, domains *repository.DomainRepository, args []string) error {
+	ws, host, err := domainFlags(ctx, repo, "add-domain", args)
+	if err != nil {
+		return err
+	}
+	d := &model.Domain{Host: host, WorkspaceID: ws.ID}
+	if err := domains.Create(ctx, d); err != nil {
+		return err
+	}
+	fmt.Printf("added %s to %s\n", d.Host, ws.Slug)
+	return nil
+}
+
+func removeDomain(ctx context.Context, repo *repository.WorkspaceRepository, domains *repository.DomainRepository, args []string) error {
+	ws, host, err := domainFlags(ctx, repo, "remove-domain", args)
+	if err != nil {
+		return err
+	}
+	if err := domains.Delete(ctx, ws.ID, host); err != nil {
+		return err
+	}
+	fmt.Printf("removed %s from %s\n", host, ws.Slug)
+	return nil
+}
+
+// domainFlags parses -workspace and -host and resolves the workspace slug.
+func domainFlags(ctx context.Context, repo *repository.WorkspaceRepository, name string, args []string) (*model.Workspace, string, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	slug := fs.String("workspace", "", "slug of the workspace")
+	host := fs.String("host", "", "domain host name")
+	_ = fs.Parse(args)
+
+	if *slug == "" || *host == "" {
+		return nil, "", fmt.Errorf("-workspace and -host are required")
+	}
+	ws, err := repo.GetBySlug(ctx, *slug)
+	if err != nil {
+		return nil, "", fmt.Errorf("workspace %q: %w", *slug, err)
+	}
+	return ws, *host, nil
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// createTestAPIKey stores a new key for owner and returns its plaintext.
+func createTestAPIKey(t *testing.T, owner string, scopes ...string) string {
+	t.Helper()
+	plaintext, err := auth.GenerateKey()
+	require.NoError(t, err)
+	key := &model.APIKey{Name: owner, Owner: owner, Prefix: plaintext[:auth.DisplayPrefixLen], Scopes: scopes}
+	require.NoError(t, repository.NewAPIKeyRepository(testDB.Pool).Create(context.Background(), key, auth.HashKey(plaintext)))
+	return plaintext
+}
+
+func doWithKey(t *testing.T, method, url, key string, body any) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// TestAuth_OwnershipEnforced verifies that only the owning key or an admin
+// key can delete a link, and that anonymous callers cannot.
+func TestAuth_OwnershipEnforced(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	cfg := *testCfg
+	cfg.Auth.Enabled = true
+	cfg.Auth.AllowAnonymous = true
+	srv, baseURL := setupTestServerWithConfig(t, &cfg)
+	defer srv.Shutdown(ctx)
+
+	alice := createTestAPIKey(t, "alice")
+	bob := createTestAPIKey(t, "bob")
+	admin := createTestAPIKey(t, "ops", auth.ScopeAdmin)
+
+	create := func(key string) string {
+		resp := doWithKey(t, http.MethodPost, baseURL+"/api/v1/shorten", key, map[string]string{"url": "https://owned.example"})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var created model.CreateURLResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		return created.ShortCode
+	}
+	status := func(method, path, key string) int {
+		resp := doWithKey(t, method, baseURL+path, key, nil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	code := create(alice)
+
+	resp := doWithKey(t, http.MethodGet, baseURL+"/api/v1/urls/"+code, "", nil)
+	var meta model.URLResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&meta))
+	resp.Body.Close()
+	assert.Equal(t, "alice", meta.Owner)
+
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodDelete, "/api/v1/urls/"+code, ""))
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodDelete, "/api/v1/urls/"+code, "zk_not-a-real-key"))
+	assert.Equal(t, http.StatusForbidden, status(http.MethodDelete, "/api/v1/urls/"+code, bob))
+	assert.Equal(t, http.StatusNoContent, status(http.MethodDelete, "/api/v1/urls/"+code, alice))
+
+	// Anonymous links have no owner: only admins may manage them.
+	anon := create("")
+	assert.Equal(t, http.StatusForbidden, status(http.MethodDelete, "/api/v1/urls/"+anon, alice))
+	assert.Equal(t, http.StatusNoContent, status(http.MethodDelete, "/api/v1/urls/"+anon, admin))
+}
@@ -73,7 +73,7 @@ func setupTestServerWithRL(t *testing.T, rlAddr string) (*http.Server, string) {
 	t.Cleanup(func() { rlClient.Close() })
 
 	gin.SetMode(gin.TestMode)
-	srv := server.NewServer(testCfg, testDB.Pool, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), rlClient, testObs, nil)
+	srv := server.NewServer(testCfg, testDB.Pool, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), rlClient, testObs, nil, nil, nil)
 
 	lis, err := net.Listen("tcp", "localhost:0")
 	require.NoError(t, err)
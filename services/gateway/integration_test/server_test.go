@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -52,6 +54,8 @@ func TestMain(m *testing.M) {
 
 	// testCfg
 	testCfg.Server.Port = "0"
+	// These tests exercise the API anonymously; auth_test.go covers API keys.
+	testCfg.Auth.Enabled = false
 
 	// test observability
 	testObs, err = observability.Setup(ctx, observability.Config{
@@ -69,8 +73,12 @@ func TestMain(m *testing.M) {
 }
 
 func setupTestServer(t *testing.T) (*http.Server, string) {
+	return setupTestServerWithConfig(t, testCfg)
+}
+
+func setupTestServerWithConfig(t *testing.T, cfg *config.Config) (*http.Server, string) {
 	gin.SetMode(gin.TestMode)
-	srv := server.NewServer(testCfg, testDB.Pool, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), nil, testObs, nil)
+	srv := server.NewServer(cfg, testDB.Pool, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), nil, testObs, nil, nil, nil)
 
 	// Create listener on localhost
 	listener, err := net.Listen("tcp", "localhost:0")
@@ -373,6 +381,201 @@ func TestRedirect_Success(t *testing.T) {
 	assert.Equal(t, "https://www.google.com", resp.Header.Get("Location"))
 }
 
+// TestRedirect_StatusAndQueryPassthrough verifies a per-link redirect type and
+// that forward_query merges the visitor's query string into the destination.
+func TestRedirect_StatusAndQueryPassthrough(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":           "https://example.com/landing?ref=owner",
+		"custom_alias":  "campaign",
+		"redirect_type": http.StatusFound,
+		"forward_query": true,
+	})
+	resp, err := http.Post(baseURL+"/api/v1/shorten", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.Get(baseURL + "/campaign?utm_source=news&ref=visitor")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://example.com/landing?ref=owner&utm_source=news", resp.Header.Get("Location"))
+}
+
+// TestRedirect_PasswordProtected walks a visitor through the password
+// interstitial: prompt, wrong password, unlock cookie, then the redirect.
+func TestRedirect_PasswordProtected(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":          "https://intranet.example/doc",
+		"custom_alias": "secret-doc",
+		"password":     "hunter2",
+	})
+	resp, err := http.Post(baseURL+"/api/v1/shorten", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err = client.Get(baseURL + "/secret-doc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "password form")
+	assert.Empty(t, resp.Header.Get("Location"))
+
+	resp, err = client.PostForm(baseURL+"/secret-doc", url.Values{"password": {"wrong"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = client.PostForm(baseURL+"/secret-doc", url.Values{"password": {"hunter2"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, err = client.Get(baseURL + "/secret-doc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://intranet.example/doc", resp.Header.Get("Location"))
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+}
+
+func TestRedirect_ClickLimit(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":          "https://example.com/invite",
+		"custom_alias": "invite",
+		"max_clicks":   2,
+	})
+	resp, err := http.Post(baseURL+"/api/v1/shorten", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for range 2 {
+		resp, err = client.Get(baseURL + "/invite")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	}
+
+	resp, err = client.Get(baseURL + "/invite")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	resp, err = http.Get(baseURL + "/api/v1/urls/invite")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var meta map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&meta))
+	assert.Equal(t, float64(2), meta["max_clicks"])
+	assert.Equal(t, float64(0), meta["remaining_clicks"])
+}
+
+func TestRedirect_ScheduledLink(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":          "https://example.com/launch",
+		"custom_alias": "launch",
+		"not_before":   time.Now().Add(time.Hour).Format(time.RFC3339),
+		"fallback_url": "https://example.com/coming-soon",
+	})
+	resp, err := http.Post(baseURL+"/api/v1/shorten", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.Get(baseURL + "/launch")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://example.com/coming-soon", resp.Header.Get("Location"))
+
+	// Activating the link takes effect on the next visit.
+	body, _ = json.Marshal(map[string]any{"clear_not_before": true})
+	req, _ := http.NewRequest(http.MethodPatch, baseURL+"/api/v1/urls/launch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get(baseURL + "/launch")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://example.com/launch", resp.Header.Get("Location"))
+}
+
+func TestRedirect_ErrorPageForBrowsers(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+
+	// API routes answer JSON whatever the client accepts.
+	resp = get("/api/v1/urls/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+}
+
 func TestGetURL_NotFound(t *testing.T) {
 	ctx := context.Background()
 	testDB.Cleanup(ctx)
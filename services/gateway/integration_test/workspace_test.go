@@ -0,0 +1,142 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// doOnHost sends a JSON request with the given Host header and, unless key
+// is empty, API key. Redirects are returned rather than followed.
+func doOnHost(t *testing.T, method, url, host, key string, body any) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	req.Host = host
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// TestWorkspaces_IsolatedByHost verifies that two workspaces can hold the same
+// alias and that the request Host selects which one a redirect resolves.
+func TestWorkspaces_IsolatedByHost(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	srv, baseURL := setupTestServer(t)
+	defer srv.Shutdown(ctx)
+
+	acme := &model.Workspace{Slug: "acme", Name: "Acme", BaseURL: "https://go.acme.test", MaxLinks: 1}
+	require.NoError(t, repository.NewWorkspaceRepository(testDB.Pool).Create(ctx, acme))
+	require.NoError(t, repository.NewDomainRepository(testDB.Pool).Create(ctx, &model.Domain{Host: "go.acme.test", WorkspaceID: acme.ID}))
+
+	do := func(method, path, host string, body any) *http.Response {
+		return doOnHost(t, method, baseURL+path, host, "", body)
+	}
+	create := func(host, target string) *http.Response {
+		return do(http.MethodPost, "/api/v1/shorten", host, map[string]string{"url": target, "custom_alias": "promo"})
+	}
+
+	resp := create("go.acme.test", "https://acme.example/promo")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created model.CreateURLResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	assert.Equal(t, "https://go.acme.test/promo", created.ShortURL)
+
+	resp = create("shared.test", "https://default.example/promo")
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "the same alias is free in the default workspace")
+
+	resp = do(http.MethodGet, "/promo", "go.acme.test", nil)
+	resp.Body.Close()
+	assert.Equal(t, "https://acme.example/promo", resp.Header.Get("Location"))
+
+	resp = do(http.MethodGet, "/promo", "shared.test", nil)
+	resp.Body.Close()
+	assert.Equal(t, "https://default.example/promo", resp.Header.Get("Location"))
+
+	resp = do(http.MethodPost, "/api/v1/shorten", "go.acme.test", map[string]string{"url": "https://acme.example/more"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "acme is limited to one link")
+}
+
+// TestDomains_ResolveHostAndCode registers two branded domains through the
+// admin API and verifies that a link answers only on the domain it was
+// created on, with its short URL on that domain.
+func TestDomains_ResolveHostAndCode(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	cfg := *testCfg
+	cfg.Auth.Enabled = true
+	cfg.Auth.AllowAnonymous = true
+	srv, baseURL := setupTestServerWithConfig(t, &cfg)
+	defer srv.Shutdown(ctx)
+
+	admin := createTestAPIKey(t, "ops", auth.ScopeAdmin)
+	member := createTestAPIKey(t, "dev")
+	for _, host := range []string{"go.brand-a.test", "brand-b.test"} {
+		resp := doOnHost(t, http.MethodPost, baseURL+"/api/v1/domains", "localhost", admin, map[string]string{"host": host})
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode, host)
+	}
+	resp := doOnHost(t, http.MethodPost, baseURL+"/api/v1/domains", "localhost", member, map[string]string{"host": "brand-c.test"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "only admins manage domains")
+
+	resp = doOnHost(t, http.MethodPost, baseURL+"/api/v1/shorten", "go.brand-a.test", member,
+		map[string]string{"url": "https://brand-a.example/sale", "custom_alias": "sale"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created model.CreateURLResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	assert.Equal(t, "https://go.brand-a.test/sale", created.ShortURL)
+
+	resp = doOnHost(t, http.MethodPost, baseURL+"/api/v1/shorten", "localhost", member,
+		map[string]string{"url": "https://brand-b.example/spring", "custom_alias": "spring", "domain": "brand-b.test"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	assert.Equal(t, "https://brand-b.test/spring", created.ShortURL)
+
+	for _, tc := range []struct {
+		host, code string
+		want       int
+	}{
+		{"go.brand-a.test", "sale", http.StatusMovedPermanently},
+		{"brand-b.test", "sale", http.StatusNotFound},
+		{"localhost", "sale", http.StatusNotFound},
+		{"brand-b.test", "spring", http.StatusMovedPermanently},
+	} {
+		resp := doOnHost(t, http.MethodGet, baseURL+"/"+tc.code, tc.host, "", nil)
+		resp.Body.Close()
+		assert.Equal(t, tc.want, resp.StatusCode, "%s/%s", tc.host, tc.code)
+	}
+
+	resp = doOnHost(t, http.MethodDelete, baseURL+"/api/v1/domains/brand-b.test", "localhost", admin, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "links still use the domain")
+}
@@ -1,12 +1,24 @@
 package analytics
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ClickEvent represents a single redirect click published to RabbitMQ.
 // It is serialised as JSON in the message body.
 type ClickEvent struct {
-	ShortCode string    `json:"short_code"`
-	ClickedAt time.Time `json:"clicked_at"`
-	IP        string    `json:"ip"`
-	Referer   string    `json:"referer"`
+	WorkspaceID uuid.UUID `json:"workspace_id"` // uuid.Nil for the default workspace
+	ShortCode   string    `json:"short_code"`
+	ClickedAt   time.Time `json:"clicked_at"`
+	IP          string    `json:"ip"`
+	Referer     string    `json:"referer"`
+	Country     string    `json:"country,omitempty"` // ISO 3166-1 alpha-2 from GeoIP; empty when unknown or disabled
+	Variant     string    `json:"variant,omitempty"` // A/B variant the visit was sent to; empty for links without variants
+	// UTM tags the link added to the destination, so clicks stay credited
+	// to the campaign they were made under.
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/service"
+)
+
+// registerDomain handles POST /api/v1/domains
+// Registers a branded domain to the caller's workspace. Requests and links
+// on the domain resolve to the workspace once tenant caches expire.
+// Request body: CreateDomainRequest
+// Response codes:
+//   - 201 Created: Domain registered
+//   - 400 Bad Request: Invalid request body or host name
+//   - 401 Unauthorized: Admin enforcement is on and no API key was sent
+//   - 403 Forbidden: API key lacks the admin scope
+//   - 409 Conflict: Host is already registered
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) registerDomain(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req model.CreateDomainRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WarnContext(ctx, "invalid request body",
+			slog.String("error", err.Error()),
+			slog.String("path", c.Request.URL.Path))
+		h.errorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	d, err := h.domainService.RegisterDomain(ctx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDomain):
+			h.errorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrDomainExists):
+			h.errorResponse(c, http.StatusConflict, "Domain already registered")
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.adminErrorResponse(c, err)
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error registering domain",
+				slog.String("error", err.Error()))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, d)
+}
+
+// listDomains handles GET /api/v1/domains
+// Lists the branded domains of the caller's workspace.
+// Response codes:
+//   - 200 OK: Domains in body
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) listDomains(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	domains, err := h.domainService.ListDomains(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "unexpected error listing domains",
+			slog.String("error", err.Error()))
+		h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, model.ListDomainsResponse{Domains: domains})
+}
+
+// removeDomain handles DELETE /api/v1/domains/:host
+// Removes a branded domain from the caller's workspace.
+// Path parameter: host - the domain to remove
+// Response codes:
+//   - 204 No Content: Domain removed
+//   - 401 Unauthorized: Admin enforcement is on and no API key was sent
+//   - 403 Forbidden: API key lacks the admin scope
+//   - 404 Not Found: Workspace has no such domain
+//   - 409 Conflict: Links created on the domain still exist
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) removeDomain(c *gin.Context) {
+	ctx := c.Request.Context()
+	host := c.Param("host")
+
+	err := h.domainService.RemoveDomain(ctx, host)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDomainNotFound):
+			h.errorResponse(c, http.StatusNotFound, "Domain not found")
+		case errors.Is(err, service.ErrDomainInUse):
+			h.errorResponse(c, http.StatusConflict, "Domain still has links")
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.adminErrorResponse(c, err)
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error removing domain",
+				slog.String("error", err.Error()),
+				slog.String("host", host))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminErrorResponse maps service.ErrUnauthorized to 401 and
+// service.ErrForbidden to 403 for admin-only routes.
+func (h *Handler) adminErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUnauthorized) {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		h.errorResponse(c, http.StatusUnauthorized, "API key required")
+		return
+	}
+	h.errorResponse(c, http.StatusForbidden, "Admin API key required")
+}
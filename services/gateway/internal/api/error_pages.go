@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+)
+
+// defaultErrorPage is shown to browsers for dead links when no template
+// directory is configured, or it has no page for the status.
+var defaultErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body{font-family:system-ui,sans-serif;background:#f5f5f5;display:flex;min-height:100vh;margin:0;align-items:center;justify-content:center}
+main{background:#fff;padding:2rem;border-radius:8px;box-shadow:0 1px 4px rgba(0,0,0,.15);width:min(22rem,90vw)}
+h1{font-size:1.2rem;margin:0 0 1rem}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</main>
+</body>
+</html>
+`))
+
+// ErrorPageData fills error page templates.
+type ErrorPageData struct {
+	Status  int    // HTTP status, 404 or 410
+	Title   string // status text, e.g. "Not Found"
+	Message string // why the link cannot be followed
+	Code    string // the short code that was visited
+	Host    string // the host it was visited on
+}
+
+// ErrorPages renders the HTML shown to browsers instead of JSON when a short
+// link cannot be followed.
+type ErrorPages struct {
+	pages map[int]*template.Template
+	other *template.Template
+}
+
+// LoadErrorPages parses the error page templates in dir: 404.html and
+// 410.html for those statuses, and error.html for any status without its
+// own page. Missing files fall back to the built-in page; an empty dir
+// uses it for everything. Templates are html/template and receive an
+// ErrorPageData.
+func LoadErrorPages(dir string) (*ErrorPages, error) {
+	p := &ErrorPages{pages: map[int]*template.Template{}, other: defaultErrorPage}
+	if dir == "" {
+		return p, nil
+	}
+	parse := func(name string) (*template.Template, error) {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+		t, err := template.ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("error page %s: %w", name, err)
+		}
+		return t, nil
+	}
+	t, err := parse("error.html")
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		p.other = t
+	}
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		t, err := parse(strconv.Itoa(status) + ".html")
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			p.pages[status] = t
+		}
+	}
+	return p, nil
+}
+
+// page returns the template for status.
+func (p *ErrorPages) page(status int) *template.Template {
+	if t, ok := p.pages[status]; ok {
+		return t
+	}
+	return p.other
+}
+
+// WithErrorPages replaces the built-in error pages served to browsers for
+// dead links.
+func (h *Handler) WithErrorPages(p *ErrorPages) *Handler {
+	h.errorPages = p
+	return h
+}
+
+// visitorError answers a visitor of a short link that cannot be followed:
+// with an error page when the client prefers HTML, as browsers do, and
+// with the usual JSON error otherwise. API routes use errorResponse.
+func (h *Handler) visitorError(c *gin.Context, status int, message string) {
+	if c.NegotiateFormat(binding.MIMEJSON, binding.MIMEHTML) != binding.MIMEHTML {
+		h.errorResponse(c, status, message)
+		return
+	}
+	pages := h.errorPages
+	if pages == nil {
+		pages = &ErrorPages{other: defaultErrorPage}
+	}
+	t := pages.page(status)
+	c.Header("X-Robots-Tag", "noindex")
+	c.Render(status, render.HTML{
+		Template: t,
+		Name:     t.Name(),
+		Data: ErrorPageData{
+			Status:  status,
+			Title:   http.StatusText(status),
+			Message: message,
+			Code:    c.Param("code"),
+			Host:    c.Request.Host,
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package api
+
+// CountryResolver maps a client IP to its ISO 3166-1 alpha-2 country code,
+// or "" when unknown. *geoip.Resolver satisfies it.
+type CountryResolver interface {
+	Country(ip string) string
+}
+
+// WithGeoIP resolves each visitor's country for redirect rules and click
+// events. Without one, country rules never match and clicks carry no
+// country.
+func (h *Handler) WithGeoIP(r CountryResolver) *Handler {
+	h.geo = r
+	return h
+}
+
+// country returns the country of ip, or "" without a resolver.
+func (h *Handler) country(ip string) string {
+	if h.geo == nil {
+		return ""
+	}
+	return h.geo.Country(ip)
+}
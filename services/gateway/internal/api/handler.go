@@ -2,9 +2,12 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +15,7 @@ import (
 	"github.com/zhejian/url-shortener/gateway/internal/cache"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
 	"github.com/zhejian/url-shortener/gateway/internal/service"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
 )
 
 // CBStateProvider exposes circuit breaker state for health reporting.
@@ -23,13 +27,19 @@ type CBStateProvider interface {
 // It follows the dependency injection pattern, receiving
 // interfaces rather than concrete implementations for testability.
 type Handler struct {
-	urlService     service.URLServiceInterface // URL shortening business logic
-	db             DBInterface                 // Database connection for health checks
-	cache          cache.ClientProvider        // Cache conneciton for health checks
-	logger         *slog.Logger                // Structured logger for validation/error logging
-	publisher      *analytics.Publisher        // Analytics click event publisher (nil when disabled)
-	cacheCBState   CBStateProvider
-	rateLimCBState CBStateProvider
+	urlService         service.URLServiceInterface    // URL shortening business logic
+	domainService      service.DomainServiceInterface // Branded domain registry (nil = routes not registered)
+	db                 DBInterface                    // Database connection for health checks
+	cache              cache.ClientProvider           // Cache conneciton for health checks
+	logger             *slog.Logger                   // Structured logger for validation/error logging
+	publisher          *analytics.Publisher           // Analytics click event publisher (nil when disabled)
+	cacheCBState       CBStateProvider
+	rateLimCBState     CBStateProvider
+	unlockLimiter      UnlockLimiter     // throttles password attempts on protected links (nil = unthrottled)
+	geo                CountryResolver   // client IP to country for redirect rules and clicks (nil = unknown)
+	errorPages         *ErrorPages       // HTML pages for dead links (nil = built-in)
+	apiMiddleware      []gin.HandlerFunc // applied to the /api/v1 group only (e.g. authentication)
+	redirectMiddleware []gin.HandlerFunc // applied to the public redirect route only (e.g. tenant resolution)
 }
 
 // DBInterface defines the database operations needed by the handler.
@@ -58,21 +68,34 @@ func NewHandler(urlService service.URLServiceInterface, db DBInterface, cache ca
 // Routes are organized into:
 //   - Health check endpoint for monitoring
 //   - API v1 endpoints for URL management (grouped under /api/v1)
-//   - Public redirect endpoint for short URL resolution
+//   - API v1 endpoints for branded domains, when a domain service is set
+//   - Public redirect endpoint for short URL resolution, and the password
+//     submission endpoint for protected links
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	// Health check endpoint
 	r.GET("/health", h.healthCheck)
 
 	// API v1 routes - grouped for versioning
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", h.apiMiddleware...)
 	{
-		v1.POST("/shorten", h.createShortURL) // Create short URL
-		v1.GET("/urls/:code", h.getURL)       // Get URL metadata
-		v1.DELETE("/urls/:code", h.deleteURL) // Delete URL
+		v1.POST("/shorten", h.createShortURL)            // Create short URL
+		v1.POST("/shorten/batch", h.createShortURLBatch) // Create many short URLs
+		v1.GET("/urls", h.listURLs)                      // List and search URLs
+		v1.GET("/urls/:code", h.getURL)                  // Get URL metadata
+		v1.GET("/urls/:code/stats", h.getStats)          // Click statistics
+		v1.PATCH("/urls/:code", h.updateURL)             // Update destination/expiry
+		v1.DELETE("/urls/:code", h.deleteURL)            // Delete URL
+		if h.domainService != nil {
+			v1.POST("/domains", h.registerDomain)       // Register a branded domain
+			v1.GET("/domains", h.listDomains)           // List the workspace's domains
+			v1.DELETE("/domains/:host", h.removeDomain) // Remove a domain
+		}
 	}
 
 	// Redirect route (public) - must be last to avoid conflicts
-	r.GET("/:code", h.redirect)
+	public := r.Group("/", h.redirectMiddleware...)
+	public.GET("/:code", h.redirect)
+	public.POST("/:code", h.unlock)
 }
 
 // healthCheck handles GET /health
@@ -128,7 +151,10 @@ func (h *Handler) healthCheck(c *gin.Context) {
 // Request body: CreateURLRequest (JSON)
 // Response codes:
 //   - 201 Created: Short URL successfully created
-//   - 400 Bad Request: Invalid request body, URL, or custom alias
+//   - 200 OK: reuse_existing was set and an existing short URL for the same
+//     canonical URL is returned, with reused set
+//   - 400 Bad Request: Invalid request body, URL, custom alias, or domain
+//   - 403 Forbidden: Workspace link quota exceeded
 //   - 409 Conflict: Custom alias already exists
 //   - 500 Internal Server Error: Unexpected error
 func (h *Handler) createShortURL(c *gin.Context) {
@@ -147,26 +173,110 @@ func (h *Handler) createShortURL(c *gin.Context) {
 	// Call service layer to create short URL
 	resp, err := h.urlService.CreateShortURL(ctx, &req)
 	if err != nil {
-		// Map service errors to appropriate HTTP status codes
-		switch {
-		case errors.Is(err, service.ErrInvalidURL):
-			h.errorResponse(c, http.StatusBadRequest, "Invalid URL")
-		case errors.Is(err, service.ErrCodeExists):
-			h.errorResponse(c, http.StatusConflict, "Custom alias already exists")
-		case errors.Is(err, service.ErrInvalidAlias):
-			h.errorResponse(c, http.StatusBadRequest, "Invalid custom alias")
-		default:
+		status, message := createErrorStatus(err)
+		if status == http.StatusInternalServerError {
 			h.logger.ErrorContext(ctx, "unexpected error creating short URL",
 				slog.String("error", err.Error()))
-			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
 		}
+		h.errorResponse(c, status, message)
 		return
 	}
 
 	// Return created short URL
+	if resp.Reused {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
 	c.JSON(http.StatusCreated, resp)
 }
 
+// createShortURLBatch handles POST /api/v1/shorten/batch
+// Creates many short URLs in one request.
+// Request body: JSON array of CreateURLRequest
+// Each item is validated independently; one bad item never fails the batch.
+// Response codes:
+//   - 200 OK: Batch processed; per-item status and result or error in body
+//   - 400 Bad Request: Body is not a JSON array, is empty, or exceeds the size limit
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) createShortURLBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	var reqs []model.CreateURLRequest
+
+	// Decode without Gin's validator: it would reject the whole array when a
+	// single item fails binding, and per-item errors are the point here.
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		h.logger.WarnContext(ctx, "invalid request body",
+			slog.String("error", err.Error()),
+			slog.String("path", c.Request.URL.Path))
+		h.errorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	results, err := h.urlService.CreateShortURLBatch(ctx, reqs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBatch):
+			h.errorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error creating short URL batch",
+				slog.String("error", err.Error()))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	resp := model.BatchCreateURLResponse{Results: make([]model.BatchCreateURLResult, len(results))}
+	for i, r := range results {
+		item := model.BatchCreateURLResult{Index: i, Status: http.StatusCreated, URL: r.URL}
+		switch {
+		case r.Err != nil:
+			status, message := createErrorStatus(r.Err)
+			if status == http.StatusInternalServerError {
+				h.logger.ErrorContext(ctx, "unexpected error creating short URL in batch",
+					slog.String("error", r.Err.Error()),
+					slog.Int("index", i))
+			}
+			item.Status = status
+			item.Error = &model.ErrorResponse{Error: http.StatusText(status), Message: message}
+			resp.Failed++
+		case r.URL.Reused:
+			item.Status = http.StatusOK
+			resp.Reused++
+		default:
+			resp.Created++
+		}
+		resp.Results[i] = item
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// createErrorStatus maps a URL creation error to an HTTP status and message.
+// Shared by the single and batch shorten endpoints so both report identically.
+func createErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidURL):
+		return http.StatusBadRequest, "Invalid URL"
+	case errors.Is(err, service.ErrCodeExists):
+		return http.StatusConflict, "Custom alias already exists"
+	case errors.Is(err, service.ErrInvalidAlias):
+		var aliasErr *service.AliasError
+		if errors.As(err, &aliasErr) {
+			return http.StatusBadRequest, "Invalid custom alias: " + aliasErr.Reason
+		}
+		return http.StatusBadRequest, "Invalid custom alias"
+	case errors.Is(err, service.ErrInvalidExpiry), errors.Is(err, service.ErrInvalidDomain),
+		errors.Is(err, service.ErrInvalidRedirect), errors.Is(err, service.ErrInvalidPassword),
+		errors.Is(err, service.ErrInvalidClickLimit), errors.Is(err, service.ErrInvalidRules),
+		errors.Is(err, service.ErrInvalidVariants), errors.Is(err, service.ErrInvalidTracking):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return http.StatusForbidden, "Workspace link quota exceeded"
+	default:
+		return http.StatusInternalServerError, "Internal server error"
+	}
+}
+
 // getURL handles GET /api/v1/urls/:code
 // Retrieves metadata for a short URL without incrementing click count.
 // Path parameter: code - the short code to look up
@@ -190,6 +300,8 @@ func (h *Handler) getURL(c *gin.Context) {
 			h.errorResponse(c, http.StatusNotFound, "URL not found")
 		case errors.Is(err, service.ErrURLExpired):
 			h.errorResponse(c, http.StatusGone, "URL has expired")
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.ownershipErrorResponse(c, err)
 		default:
 			h.logger.ErrorContext(ctx, "unexpected error fetching URL",
 				slog.String("error", err.Error()),
@@ -202,11 +314,153 @@ func (h *Handler) getURL(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// getStats handles GET /api/v1/urls/:code/stats
+// Returns click totals, a time-series histogram and top referers.
+// Path parameter: code - the short code to report on
+// Query parameters: from, to (RFC3339; default is a bucket-dependent window
+// ending now), bucket (minute|hour|day, default hour), top (referers, default 10)
+// Response codes:
+//   - 200 OK: Statistics computed (may be served from a short-lived cache)
+//   - 400 Bad Request: Malformed or out-of-bounds query parameters
+//   - 401 Unauthorized: No API key (when ownership is enforced)
+//   - 403 Forbidden: API key does not own the URL
+//   - 404 Not Found: Short code does not exist
+//   - 503 Service Unavailable: Statistics are not configured
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) getStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+
+	var req model.StatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WarnContext(ctx, "invalid stats query",
+			slog.String("error", err.Error()),
+			slog.String("path", c.Request.URL.Path))
+		h.errorResponse(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	resp, err := h.urlService.GetStats(ctx, code, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidStatsQuery):
+			h.errorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrURLNotFound):
+			h.errorResponse(c, http.StatusNotFound, "URL not found")
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.ownershipErrorResponse(c, err)
+		case errors.Is(err, service.ErrStatsUnavailable):
+			h.errorResponse(c, http.StatusServiceUnavailable, "Statistics unavailable")
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error computing stats",
+				slog.String("error", err.Error()),
+				slog.String("code", code))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// listURLs handles GET /api/v1/urls
+// Lists short URLs with cursor-based pagination.
+// Query parameters: cursor, limit, created_after, created_before (RFC3339),
+// status (active|expired), q (substring of original URL),
+// sort (created_at|click_count), order (asc|desc)
+// Response codes:
+//   - 200 OK: Page of URL metadata (next_cursor set when more results exist)
+//   - 400 Bad Request: Malformed query parameters or cursor
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) listURLs(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req model.ListURLsRequest
+
+	// Bind and validate query string
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WarnContext(ctx, "invalid list query",
+			slog.String("error", err.Error()),
+			slog.String("path", c.Request.URL.Path))
+		h.errorResponse(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	resp, err := h.urlService.ListURLs(ctx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidListQuery):
+			h.errorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.ownershipErrorResponse(c, err)
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error listing URLs",
+				slog.String("error", err.Error()))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// updateURL handles PATCH /api/v1/urls/:code
+// Changes the destination, expiry or redirect behaviour of an existing short URL.
+// Path parameter: code - the short code to update
+// Request body: UpdateURLRequest (JSON)
+// Response codes:
+//   - 200 OK: URL updated, returns the new metadata
+//   - 400 Bad Request: Invalid request body, URL, expiry, redirect_type or password
+//   - 401 Unauthorized: No API key (when ownership is enforced)
+//   - 403 Forbidden: API key does not own the URL
+//   - 404 Not Found: Short code does not exist
+//   - 500 Internal Server Error: Unexpected error
+func (h *Handler) updateURL(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+	var req model.UpdateURLRequest
+
+	// Bind and validate JSON request body
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WarnContext(ctx, "invalid request body",
+			slog.String("error", err.Error()),
+			slog.String("path", c.Request.URL.Path))
+		h.errorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.urlService.UpdateURL(ctx, code, &req)
+	if err != nil {
+		// Map service errors to appropriate HTTP status codes
+		switch {
+		case errors.Is(err, service.ErrURLNotFound):
+			h.errorResponse(c, http.StatusNotFound, "URL not found")
+		case errors.Is(err, service.ErrInvalidURL):
+			h.errorResponse(c, http.StatusBadRequest, "Invalid URL")
+		case errors.Is(err, service.ErrInvalidUpdate), errors.Is(err, service.ErrInvalidRedirect),
+			errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrInvalidRules),
+			errors.Is(err, service.ErrInvalidVariants), errors.Is(err, service.ErrInvalidTracking):
+			h.errorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.ownershipErrorResponse(c, err)
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error updating URL",
+				slog.String("error", err.Error()),
+				slog.String("code", code))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // deleteURL handles DELETE /api/v1/urls/:code
 // Permanently deletes a short URL.
 // Path parameter: code - the short code to delete
 // Response codes:
 //   - 204 No Content: URL successfully deleted
+//   - 401 Unauthorized: No API key (when ownership is enforced)
+//   - 403 Forbidden: API key does not own the URL
 //   - 404 Not Found: Short code does not exist
 //   - 500 Internal Server Error: Unexpected error
 func (h *Handler) deleteURL(c *gin.Context) {
@@ -222,6 +476,8 @@ func (h *Handler) deleteURL(c *gin.Context) {
 		switch {
 		case errors.Is(err, service.ErrURLNotFound):
 			h.errorResponse(c, http.StatusNotFound, "URL not found")
+		case errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrForbidden):
+			h.ownershipErrorResponse(c, err)
 		default:
 			h.logger.ErrorContext(ctx, "unexpected error deleting URL",
 				slog.String("error", err.Error()),
@@ -235,15 +491,55 @@ func (h *Handler) deleteURL(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// fallbackRedirect sends the visitor of a link that cannot be followed to
+// its fallback URL, if the link or its workspace has one, and reports
+// whether it did. The redirect must not be cached: the link may come back
+// to life through activation or an update.
+func (h *Handler) fallbackRedirect(c *gin.Context, err error) bool {
+	var unavailable *service.UnavailableError
+	if !errors.As(err, &unavailable) || unavailable.FallbackURL == "" {
+		return false
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, unavailable.FallbackURL)
+	return true
+}
+
+// notActiveResponse answers a visit to a link before its activation time
+// with 404 and a Retry-After hint. It must not be cached past activation.
+func (h *Handler) notActiveResponse(c *gin.Context, err error) {
+	c.Header("Cache-Control", "no-store")
+	var unavailable *service.UnavailableError
+	if errors.As(err, &unavailable) && !unavailable.ActiveAt.IsZero() {
+		wait := int64(math.Ceil(time.Until(unavailable.ActiveAt).Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(max(wait, 1), 10))
+	}
+	h.visitorError(c, http.StatusNotFound, "URL is not active yet")
+}
+
 // redirect handles GET /:code
 // Redirects the user to the original URL associated with the short code.
 // Also increments the click count for analytics.
-// Path parameter: code - the short code to resolve
+// Path parameter: code - the short code to resolve on the request's host
+// Query string: forwarded to the destination when the link has forward_query set
+// Cookie: link_unlock, set by POST /:code once a protected link's password is accepted;
+// link_variant, set here for links with sticky_variants, keeps the visitor on
+// the A/B variant first picked for them
+// Headers: User-Agent and Accept-Language, matched against the link's rules
+// along with the client IP's country (see WithGeoIP)
 // Response codes:
-//   - 301 Moved Permanently: Redirects to original URL
-//   - 404 Not Found: Short code does not exist
-//   - 410 Gone: URL has expired
+//   - 301, 302, 307 or 308: Redirects to original URL with the link's redirect_type
+//     (REDIRECT_STATUS when it sets none)
+//   - 200 OK: Password form, for a protected link without a valid unlock cookie
+//   - 302 Found: Link is not active yet, has expired or has used up its
+//     max_clicks, and it or its workspace has a fallback_url
+//   - 404 Not Found: Short code does not exist on this host, or the link is
+//     not active yet (Retry-After gives the seconds until it is)
+//   - 410 Gone: URL has expired or has used up its max_clicks
 //   - 500 Internal Server Error: Unexpected error
+//
+// 404 and 410 are an HTML error page for clients that prefer text/html
+// (see WithErrorPages) and JSON otherwise.
 func (h *Handler) redirect(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -251,14 +547,36 @@ func (h *Handler) redirect(c *gin.Context) {
 	code := c.Param("code")
 
 	// Resolve short code to original URL (also increments click count)
-	url, err := h.urlService.Redirect(ctx, code)
+	ip := c.ClientIP()
+	country := h.country(ip)
+	unlock, _ := c.Cookie(unlockCookie)
+	variant, _ := c.Cookie(variantCookie)
+	target, err := h.urlService.Redirect(ctx, &model.RedirectRequest{
+		Code:           code,
+		Query:          c.Request.URL.Query(),
+		Unlock:         unlock,
+		UserAgent:      c.GetHeader("User-Agent"),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Country:        country,
+		Variant:        variant,
+	})
 	if err != nil {
 		// Map service errors to appropriate HTTP status codes
+		if h.fallbackRedirect(c, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, service.ErrURLNotFound):
-			h.errorResponse(c, http.StatusNotFound, "URL not found")
+			h.visitorError(c, http.StatusNotFound, "URL not found")
 		case errors.Is(err, service.ErrURLExpired):
-			h.errorResponse(c, http.StatusGone, "URL has expired")
+			h.visitorError(c, http.StatusGone, "URL has expired")
+		case errors.Is(err, service.ErrURLNotActive):
+			h.notActiveResponse(c, err)
+		case errors.Is(err, service.ErrClickLimitReached):
+			c.Header("Cache-Control", "no-store")
+			h.visitorError(c, http.StatusGone, "URL has reached its click limit")
+		case errors.Is(err, service.ErrPasswordRequired):
+			h.passwordPrompt(c, http.StatusOK, "")
 		default:
 			h.logger.ErrorContext(ctx, "unexpected error during redirect",
 				slog.String("error", err.Error()),
@@ -271,11 +589,30 @@ func (h *Handler) redirect(c *gin.Context) {
 	// Capture Gin-specific values before the handler returns: gin.Context is recycled
 	// by Gin's sync.Pool after ServeHTTP returns, so reading them inside the goroutine
 	// could return data from a different concurrent request.
-	ip := c.ClientIP()
 	referer := c.GetHeader("Referer")
+	workspaceID := tenant.WorkspaceID(ctx)
 
-	// Perform HTTP 301 redirect to original URL
-	c.Redirect(http.StatusMovedPermanently, url)
+	if target.NoStore {
+		c.Header("Cache-Control", "no-store")
+	}
+	if target.Vary != "" {
+		c.Header("Vary", target.Vary)
+	}
+	rememberVariant(c, code, target)
+	c.Redirect(target.Status, target.URL)
+
+	event := analytics.ClickEvent{
+		WorkspaceID: workspaceID,
+		ShortCode:   code,
+		ClickedAt:   time.Now().UTC(),
+		IP:          ip,
+		Referer:     referer,
+		Country:     country,
+		Variant:     target.Variant,
+	}
+	if t := target.Tracking; t != nil {
+		event.UTMSource, event.UTMMedium, event.UTMCampaign = t.Source, t.Medium, t.Campaign
+	}
 
 	// Publish click event after responding — fire-and-forget in a goroutine
 	// so it never adds latency to the redirect response.
@@ -283,12 +620,7 @@ func (h *Handler) redirect(c *gin.Context) {
 	// Go's HTTP server when ServeHTTP returns, before this goroutine is scheduled.
 	// amqp091.PublishWithContext checks ctx.Err() first — a cancelled context would
 	// silently drop every event.
-	go h.publisher.Publish(context.Background(), analytics.ClickEvent{
-		ShortCode: code,
-		ClickedAt: time.Now().UTC(),
-		IP:        ip,
-		Referer:   referer,
-	})
+	go h.publisher.Publish(context.Background(), event)
 }
 
 // errorResponse sends a standardized JSON error response.
@@ -301,6 +633,38 @@ func (h *Handler) errorResponse(c *gin.Context, status int, message string) {
 	})
 }
 
+// ownershipErrorResponse maps service.ErrUnauthorized to 401 and
+// service.ErrForbidden to 403.
+func (h *Handler) ownershipErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUnauthorized) {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		h.errorResponse(c, http.StatusUnauthorized, "API key required")
+		return
+	}
+	h.errorResponse(c, http.StatusForbidden, "Not allowed to manage this URL")
+}
+
+// WithAPIMiddleware adds middleware that runs only on /api/v1 routes, leaving
+// health checks and public redirects untouched. Call before RegisterRoutes.
+func (h *Handler) WithAPIMiddleware(mw ...gin.HandlerFunc) *Handler {
+	h.apiMiddleware = append(h.apiMiddleware, mw...)
+	return h
+}
+
+// WithRedirectMiddleware adds middleware that runs only on the public
+// redirect route. Call before RegisterRoutes.
+func (h *Handler) WithRedirectMiddleware(mw ...gin.HandlerFunc) *Handler {
+	h.redirectMiddleware = append(h.redirectMiddleware, mw...)
+	return h
+}
+
+// WithDomainService enables the /api/v1/domains routes. Call before
+// RegisterRoutes.
+func (h *Handler) WithDomainService(s service.DomainServiceInterface) *Handler {
+	h.domainService = s
+	return h
+}
+
 // WithCBProviders wires circuit breaker state providers for health reporting.
 func (h *Handler) WithCBProviders(cache, rateLimiter CBStateProvider) *Handler {
 	if cache != nil {
@@ -4,18 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/zhejian/url-shortener/gateway/internal/api"
+	"github.com/zhejian/url-shortener/gateway/internal/middleware"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
 	"github.com/zhejian/url-shortener/gateway/internal/service"
 )
 
@@ -46,6 +56,14 @@ func (m *MockURLService) CreateShortURL(ctx context.Context, req *model.CreateUR
 	return args.Get(0).(*model.CreateURLResponse), args.Error(1)
 }
 
+func (m *MockURLService) CreateShortURLBatch(ctx context.Context, reqs []model.CreateURLRequest) ([]service.BatchItemResult, error) {
+	args := m.Called(ctx, reqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.BatchItemResult), args.Error(1)
+}
+
 func (m *MockURLService) GetURL(ctx context.Context, code string) (*model.URLResponse, error) {
 	args := m.Called(ctx, code)
 	if args.Get(0) == nil {
@@ -59,9 +77,44 @@ func (m *MockURLService) DeleteURL(ctx context.Context, code string) error {
 	return args.Error(0)
 }
 
-func (m *MockURLService) Redirect(ctx context.Context, code string) (string, error) {
-	args := m.Called(ctx, code)
-	return args.String(0), args.Error(1)
+func (m *MockURLService) UpdateURL(ctx context.Context, code string, req *model.UpdateURLRequest) (*model.URLResponse, error) {
+	args := m.Called(ctx, code, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.URLResponse), args.Error(1)
+}
+
+func (m *MockURLService) Redirect(ctx context.Context, req *model.RedirectRequest) (*model.RedirectTarget, error) {
+	args := m.Called(ctx, req.Code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RedirectTarget), args.Error(1)
+}
+
+func (m *MockURLService) UnlockURL(ctx context.Context, req *model.UnlockRequest) (*model.UnlockGrant, error) {
+	args := m.Called(ctx, req.Code, req.Password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UnlockGrant), args.Error(1)
+}
+
+func (m *MockURLService) ListURLs(ctx context.Context, req *model.ListURLsRequest) (*model.ListURLsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ListURLsResponse), args.Error(1)
+}
+
+func (m *MockURLService) GetStats(ctx context.Context, code string, req *model.StatsRequest) (*model.ClickStats, error) {
+	args := m.Called(ctx, code, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ClickStats), args.Error(1)
 }
 
 // MockDB for health check
@@ -186,6 +239,27 @@ func TestHandler_HealthCheck(t *testing.T) {
 }
 
 func TestHandler_CreateShortURL(t *testing.T) {
+	t.Run("returns 200 when an existing URL is reused", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("CreateShortURL", mock.Anything, mock.MatchedBy(func(req *model.CreateURLRequest) bool {
+			return req.ReuseExisting
+		})).Return(&model.CreateURLResponse{ShortCode: "abc123", ShortURL: "http://localhost:8081/abc123", Reused: true}, nil)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://example.com", "reuse_existing": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.CreateURLResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.True(t, response.Reused)
+		mockService.AssertExpectations(t)
+	})
+
 	t.Run("returns 201 when URL is successfully created", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
@@ -279,6 +353,47 @@ func TestHandler_CreateShortURL(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("returns 403 when the workspace quota is exhausted", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("CreateShortURL", mock.Anything, mock.Anything).Return(nil, service.ErrQuotaExceeded)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "Workspace link quota exceeded", response.Message)
+	})
+
+	t.Run("returns 400 when the domain is not registered to the workspace", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("CreateShortURL", mock.Anything, mock.MatchedBy(func(r *model.CreateURLRequest) bool {
+			return r.Domain == "go.brand-a.com"
+		})).Return(nil, fmt.Errorf("%w: %q is not registered to this workspace", service.ErrInvalidDomain, "go.brand-a.com"))
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("POST", "/api/v1/shorten",
+			bytes.NewBufferString(`{"url": "https://example.com", "domain": "go.brand-a.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Contains(t, response.Message, "not registered")
+	})
+
 	t.Run("returns 409 when custom alias already exists", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
@@ -340,6 +455,169 @@ func TestHandler_CreateShortURL(t *testing.T) {
 
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("returns 400 with reason when expiry is invalid", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		mockService.On("CreateShortURL", mock.Anything, mock.MatchedBy(func(req *model.CreateURLRequest) bool {
+			return req.ExpiresAfter == "forever"
+		})).Return(nil, fmt.Errorf("%w: expires_after must be a positive duration", service.ErrInvalidExpiry))
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"url": "https://example.com", "expires_after": "forever"}`
+		req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "invalid expiry: expires_after must be a positive duration", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns the policy reason when alias is rejected", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		mockService.On("CreateShortURL", mock.Anything, mock.Anything).Return(
+			nil,
+			&service.AliasError{Alias: "health", Reason: "is reserved"},
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"url": "https://example.com", "custom_alias": "health"}`
+		req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "Invalid custom alias: is reserved", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_CreateShortURLBatch(t *testing.T) {
+	t.Run("returns 200 with per-item results", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock expectation: one success, one alias conflict, one invalid URL, one reused link
+		mockService.On("CreateShortURLBatch", mock.Anything, mock.MatchedBy(func(reqs []model.CreateURLRequest) bool {
+			return len(reqs) == 4 && reqs[1].CustomAlias == "taken"
+		})).Return(
+			[]service.BatchItemResult{
+				{URL: &model.CreateURLResponse{ShortCode: "abc123", ShortURL: "http://localhost:8081/abc123"}},
+				{Err: service.ErrCodeExists},
+				{Err: service.ErrInvalidURL},
+				{URL: &model.CreateURLResponse{ShortCode: "old123", ShortURL: "http://localhost:8081/old123", Reused: true}},
+			},
+			nil,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `[
+			{"url": "https://example.com/a"},
+			{"url": "https://example.com/b", "custom_alias": "taken"},
+			{"url": "not a url"},
+			{"url": "https://example.com/old", "reuse_existing": true}
+		]`
+		req := httptest.NewRequest("POST", "/api/v1/shorten/batch", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.BatchCreateURLResponse
+		err := json.NewDecoder(w.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, response.Created, "reused links are not counted as created")
+		assert.Equal(t, 1, response.Reused)
+		assert.Equal(t, 2, response.Failed)
+		require.Len(t, response.Results, 4)
+
+		assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+		assert.Equal(t, "abc123", response.Results[0].URL.ShortCode)
+		assert.Nil(t, response.Results[0].Error)
+
+		assert.Equal(t, 1, response.Results[1].Index)
+		assert.Equal(t, http.StatusConflict, response.Results[1].Status)
+		assert.Equal(t, "Custom alias already exists", response.Results[1].Error.Message)
+
+		assert.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		assert.Equal(t, "Invalid URL", response.Results[2].Error.Message)
+
+		assert.Equal(t, http.StatusOK, response.Results[3].Status)
+		assert.Equal(t, "old123", response.Results[3].URL.ShortCode)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 400 when body is not an array", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"url": "https://example.com"}`
+		req := httptest.NewRequest("POST", "/api/v1/shorten/batch", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "CreateShortURLBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 400 when batch is rejected", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return invalid batch error
+		mockService.On("CreateShortURLBatch", mock.Anything, mock.Anything).Return(
+			nil,
+			service.ErrInvalidBatch,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("POST", "/api/v1/shorten/batch", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
 }
 
 func TestHandler_GetURL(t *testing.T) {
@@ -439,141 +717,990 @@ func TestHandler_GetURL(t *testing.T) {
 	})
 }
 
-func TestHandler_DeleteURL(t *testing.T) {
-	t.Run("returns 204 when URL is successfully deleted", func(t *testing.T) {
+func TestHandler_GetStats(t *testing.T) {
+	t.Run("returns 200 with stats", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
 		mockCache := &MockCache{shouldFail: false}
 
-		// Setup mock expectation
-		mockService.On("DeleteURL", mock.Anything, "abc123").Return(nil)
+		from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		mockService.On("GetStats", mock.Anything, "abc123", mock.MatchedBy(func(req *model.StatsRequest) bool {
+			return req.Bucket == "day" && req.Top == 5 && req.From.Equal(from)
+		})).Return(
+			&model.ClickStats{
+				ShortCode:   "abc123",
+				Bucket:      "day",
+				TotalClicks: 12,
+				UniqueIPs:   4,
+				Series:      []model.StatsBucket{{Start: from, Clicks: 12}},
+				TopReferers: []model.RefererCount{{Referer: "https://news.example", Clicks: 8}},
+			},
+			nil,
+		)
 
 		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
 		router := setupTestRouter(handler)
 
-		req := httptest.NewRequest("DELETE", "/api/v1/urls/abc123", nil)
+		req := httptest.NewRequest("GET", "/api/v1/urls/abc123/stats?bucket=day&top=5&from=2026-03-01T00:00:00Z", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusNoContent, w.Code)
-		assert.Empty(t, w.Body.String())
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ClickStats
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, int64(12), response.TotalClicks)
+		assert.Equal(t, int64(4), response.UniqueIPs)
+		assert.Len(t, response.Series, 1)
+		assert.Equal(t, "https://news.example", response.TopReferers[0].Referer)
 
 		mockService.AssertExpectations(t)
 	})
 
-	t.Run("returns 404 when URL not found", func(t *testing.T) {
-		mockService := new(MockURLService)
-		mockDB := &MockDB{shouldFail: false}
-		mockCache := &MockCache{shouldFail: false}
+	t.Run("maps service errors to status codes", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{fmt.Errorf("%w: bucket must be one of minute, hour, day", service.ErrInvalidStatsQuery), http.StatusBadRequest},
+			{service.ErrURLNotFound, http.StatusNotFound},
+			{service.ErrStatsUnavailable, http.StatusServiceUnavailable},
+			{errors.New("db down"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			mockService := new(MockURLService)
+			mockService.On("GetStats", mock.Anything, "abc123", mock.Anything).Return(nil, tt.err)
+
+			handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+			router := setupTestRouter(handler)
+
+			req := httptest.NewRequest("GET", "/api/v1/urls/abc123/stats", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, "error %v", tt.err)
+		}
+	})
 
-		// Setup mock to return not found error
-		mockService.On("DeleteURL", mock.Anything, "notfound").Return(
-			service.ErrURLNotFound,
-		)
+	t.Run("returns 400 when query parameters are malformed", func(t *testing.T) {
+		mockService := new(MockURLService)
 
-		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
 		router := setupTestRouter(handler)
 
-		req := httptest.NewRequest("DELETE", "/api/v1/urls/notfound", nil)
+		req := httptest.NewRequest("GET", "/api/v1/urls/abc123/stats?from=yesterday", nil)
 		w := httptest.NewRecorder()
-
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
-
-		var response model.ErrorResponse
-		json.NewDecoder(w.Body).Decode(&response)
-		assert.Equal(t, "Not Found", response.Error)
-		assert.Equal(t, "URL not found", response.Message)
-
-		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "GetStats", mock.Anything, mock.Anything, mock.Anything)
 	})
 }
 
-func TestHandler_Redirect(t *testing.T) {
-	t.Run("returns 301 redirect when URL exists", func(t *testing.T) {
+func TestHandler_ListURLs(t *testing.T) {
+	t.Run("returns 200 with a page of URLs", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
 		mockCache := &MockCache{shouldFail: false}
 
-		// Setup mock expectation
-		mockService.On("Redirect", mock.Anything, "abc123").Return(
-			"https://example.com",
+		// Query parameters are bound into the request passed to the service
+		mockService.On("ListURLs", mock.Anything, mock.MatchedBy(func(req *model.ListURLsRequest) bool {
+			return req.Limit == 2 && req.Status == "active" && req.Query == "example" &&
+				req.SortBy == "click_count" && req.CreatedAfter.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		})).Return(
+			&model.ListURLsResponse{
+				URLs: []model.URLResponse{
+					{ShortCode: "abc123", OriginalURL: "https://example.com/a", ClickCount: 9},
+					{ShortCode: "def456", OriginalURL: "https://example.com/b", ClickCount: 3},
+				},
+				NextCursor: "next-page",
+			},
 			nil,
 		)
 
 		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
 		router := setupTestRouter(handler)
 
-		req := httptest.NewRequest("GET", "/abc123", nil)
+		req := httptest.NewRequest("GET", "/api/v1/urls?limit=2&status=active&q=example&sort=click_count&created_after=2026-01-01T00:00:00Z", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusMovedPermanently, w.Code)
-		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ListURLsResponse
+		err := json.NewDecoder(w.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Len(t, response.URLs, 2)
+		assert.Equal(t, "abc123", response.URLs[0].ShortCode)
+		assert.Equal(t, "next-page", response.NextCursor)
 
 		mockService.AssertExpectations(t)
 	})
 
-	t.Run("returns 404 when URL not found", func(t *testing.T) {
+	t.Run("returns 400 when query parameters are malformed", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
 		mockCache := &MockCache{shouldFail: false}
 
-		// Setup mock to return not found error
-		mockService.On("Redirect", mock.Anything, "notfound").Return(
-			"",
-			service.ErrURLNotFound,
-		)
-
 		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
 		router := setupTestRouter(handler)
 
-		req := httptest.NewRequest("GET", "/notfound", nil)
+		req := httptest.NewRequest("GET", "/api/v1/urls?created_after=yesterday", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
-
-		var response model.ErrorResponse
-		json.NewDecoder(w.Body).Decode(&response)
-		assert.Equal(t, "Not Found", response.Error)
-		assert.Equal(t, "URL not found", response.Message)
-
-		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListURLs", mock.Anything, mock.Anything)
 	})
 
-	t.Run("returns 410 when URL has expired", func(t *testing.T) {
+	t.Run("returns 400 when service rejects the query", func(t *testing.T) {
 		mockService := new(MockURLService)
 		mockDB := &MockDB{shouldFail: false}
 		mockCache := &MockCache{shouldFail: false}
 
-		// Setup mock to return expired error
-		mockService.On("Redirect", mock.Anything, "expired").Return(
-			"",
-			service.ErrURLExpired,
+		// Setup mock to return invalid query error
+		mockService.On("ListURLs", mock.Anything, mock.Anything).Return(
+			nil,
+			service.ErrInvalidListQuery,
 		)
 
 		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
 		router := setupTestRouter(handler)
 
-		req := httptest.NewRequest("GET", "/expired", nil)
+		req := httptest.NewRequest("GET", "/api/v1/urls?sort=random", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
 
 		var response model.ErrorResponse
 		json.NewDecoder(w.Body).Decode(&response)
-		assert.Equal(t, "Gone", response.Error)
-		assert.Equal(t, "URL has expired", response.Message)
+		assert.Equal(t, "Bad Request", response.Error)
+
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_UpdateURL(t *testing.T) {
+	t.Run("returns 200 with updated metadata", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock expectation
+		mockService.On("UpdateURL", mock.Anything, "abc123", mock.MatchedBy(func(req *model.UpdateURLRequest) bool {
+			return req.URL != nil && *req.URL == "https://example.com/fixed" && req.ExpiresAt == nil
+		})).Return(
+			&model.URLResponse{
+				ShortCode:   "abc123",
+				OriginalURL: "https://example.com/fixed",
+			},
+			nil,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"url": "https://example.com/fixed"}`
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/abc123", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.URLResponse
+		err := json.NewDecoder(w.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/fixed", response.OriginalURL)
 
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("returns 400 when URL is not a valid URL", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"url": "not a url"}`
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/abc123", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "UpdateURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 400 when update is rejected", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return invalid update error
+		mockService.On("UpdateURL", mock.Anything, "abc123", mock.Anything).Return(
+			nil,
+			service.ErrInvalidUpdate,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/abc123", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 400 for an unsupported redirect_type", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("UpdateURL", mock.Anything, "abc123", mock.MatchedBy(func(req *model.UpdateURLRequest) bool {
+			return req.RedirectType != nil && *req.RedirectType == 303
+		})).Return(nil, fmt.Errorf("%w: 303 is not one of 301, 302, 307, 308", service.ErrInvalidRedirect))
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/abc123", bytes.NewBufferString(`{"redirect_type": 303}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 404 when URL not found", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return not found error
+		mockService.On("UpdateURL", mock.Anything, "notfound", mock.Anything).Return(
+			nil,
+			service.ErrURLNotFound,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		reqBody := `{"clear_expiry": true}`
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/notfound", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "URL not found", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_DeleteURL(t *testing.T) {
+	t.Run("returns 204 when URL is successfully deleted", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock expectation
+		mockService.On("DeleteURL", mock.Anything, "abc123").Return(nil)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("DELETE", "/api/v1/urls/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 404 when URL not found", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return not found error
+		mockService.On("DeleteURL", mock.Anything, "notfound").Return(
+			service.ErrURLNotFound,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("DELETE", "/api/v1/urls/notfound", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "Not Found", response.Error)
+		assert.Equal(t, "URL not found", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("maps ownership errors to 401 and 403", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{service.ErrUnauthorized, http.StatusUnauthorized},
+			{service.ErrForbidden, http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			mockService := new(MockURLService)
+			mockService.On("DeleteURL", mock.Anything, "abc123").Return(tc.err)
+
+			handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+			router := setupTestRouter(handler)
+
+			req := httptest.NewRequest("DELETE", "/api/v1/urls/abc123", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code, tc.err.Error())
+			mockService.AssertExpectations(t)
+		}
+	})
+}
+
+// MockDomainService mocks the domain registry
+type MockDomainService struct {
+	mock.Mock
+}
+
+func (m *MockDomainService) RegisterDomain(ctx context.Context, req *model.CreateDomainRequest) (*model.Domain, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Domain), args.Error(1)
+}
+
+func (m *MockDomainService) ListDomains(ctx context.Context) ([]*model.Domain, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Domain), args.Error(1)
+}
+
+func (m *MockDomainService) RemoveDomain(ctx context.Context, host string) error {
+	return m.Called(ctx, host).Error(0)
+}
+
+func TestHandler_Domains(t *testing.T) {
+	newRouter := func(ds service.DomainServiceInterface) *gin.Engine {
+		handler := api.NewHandler(new(MockURLService), &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		if ds != nil {
+			handler.WithDomainService(ds)
+		}
+		return setupTestRouter(handler)
+	}
+
+	t.Run("routes are absent without a domain service", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(nil).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/domains", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("registers a domain", func(t *testing.T) {
+		ds := new(MockDomainService)
+		ds.On("RegisterDomain", mock.Anything, &model.CreateDomainRequest{Host: "go.brand-a.com"}).
+			Return(&model.Domain{Host: "go.brand-a.com"}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/domains", bytes.NewBufferString(`{"host": "go.brand-a.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		newRouter(ds).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var d model.Domain
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&d))
+		assert.Equal(t, "go.brand-a.com", d.Host)
+		ds.AssertExpectations(t)
+	})
+
+	t.Run("lists domains", func(t *testing.T) {
+		ds := new(MockDomainService)
+		ds.On("ListDomains", mock.Anything).Return([]*model.Domain{{Host: "brand-b.link"}, {Host: "go.brand-a.com"}}, nil)
+
+		w := httptest.NewRecorder()
+		newRouter(ds).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/domains", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp model.ListDomainsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Len(t, resp.Domains, 2)
+	})
+
+	t.Run("maps register errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{fmt.Errorf("%w: bad", service.ErrInvalidDomain), http.StatusBadRequest},
+			{service.ErrDomainExists, http.StatusConflict},
+			{service.ErrUnauthorized, http.StatusUnauthorized},
+			{service.ErrForbidden, http.StatusForbidden},
+			{errors.New("boom"), http.StatusInternalServerError},
+		}
+		for _, tc := range cases {
+			ds := new(MockDomainService)
+			ds.On("RegisterDomain", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			req := httptest.NewRequest("POST", "/api/v1/domains", bytes.NewBufferString(`{"host": "x.example"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			newRouter(ds).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code, tc.err.Error())
+		}
+	})
+
+	t.Run("maps remove errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{nil, http.StatusNoContent},
+			{service.ErrDomainNotFound, http.StatusNotFound},
+			{service.ErrDomainInUse, http.StatusConflict},
+			{service.ErrForbidden, http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			ds := new(MockDomainService)
+			ds.On("RemoveDomain", mock.Anything, "go.brand-a.com").Return(tc.err)
+
+			w := httptest.NewRecorder()
+			newRouter(ds).ServeHTTP(w, httptest.NewRequest("DELETE", "/api/v1/domains/go.brand-a.com", nil))
+
+			assert.Equal(t, tc.status, w.Code, fmt.Sprint(tc.err))
+			ds.AssertExpectations(t)
+		}
+	})
+}
+
+// TestHandler_APIMiddleware verifies that API middleware guards /api/v1 only,
+// leaving public redirects and health checks reachable.
+func TestHandler_APIMiddleware(t *testing.T) {
+	mockService := new(MockURLService)
+	mockService.On("Redirect", mock.Anything, "abc123").Return(
+		&model.RedirectTarget{URL: "https://example.com", Status: http.StatusMovedPermanently}, nil)
+
+	deny := func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) }
+	handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).WithAPIMiddleware(deny)
+	router := setupTestRouter(handler)
+
+	for path, want := range map[string]int{
+		"/api/v1/urls/abc123": http.StatusUnauthorized,
+		"/abc123":             http.StatusMovedPermanently,
+		"/health":             http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+}
+
+func TestHandler_RedirectMiddleware(t *testing.T) {
+	mockService := new(MockURLService)
+	mockService.On("GetURL", mock.Anything, "abc123").Return(&model.URLResponse{ShortCode: "abc123"}, nil)
+
+	deny := func(c *gin.Context) { c.AbortWithStatus(http.StatusForbidden) }
+	handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).WithRedirectMiddleware(deny)
+	router := setupTestRouter(handler)
+
+	for path, want := range map[string]int{
+		"/abc123":             http.StatusForbidden,
+		"/api/v1/urls/abc123": http.StatusOK,
+		"/health":             http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+}
+
+// brandedStore resolves a single workspace domain.
+type brandedStore struct {
+	host string
+	ws   *model.Workspace
+}
+
+func (s brandedStore) GetByID(_ context.Context, id uuid.UUID) (*model.Workspace, error) {
+	if id == s.ws.ID {
+		return s.ws, nil
+	}
+	return nil, repository.ErrWorkspaceNotFound
+}
+
+func (s brandedStore) GetByHost(_ context.Context, host string) (*model.Workspace, error) {
+	if host == s.host {
+		return s.ws, nil
+	}
+	return nil, repository.ErrWorkspaceNotFound
+}
+
+// TestHandler_WorkspaceDomain wires Tenant as the server does: a branded
+// domain resolves redirects anonymously but not API calls.
+func TestHandler_WorkspaceDomain(t *testing.T) {
+	mockService := new(MockURLService)
+	mockService.On("Redirect", mock.Anything, "abc123").Return(
+		&model.RedirectTarget{URL: "https://example.com", Status: http.StatusMovedPermanently}, nil)
+
+	store := brandedStore{host: "go.acme.example", ws: &model.Workspace{ID: uuid.New(), Slug: "acme"}}
+	tenantMW := middleware.Tenant(store, newTestLogger())
+	handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).
+		WithAPIMiddleware(tenantMW, middleware.RequireKeyOnDomain()).
+		WithRedirectMiddleware(tenantMW)
+	router := setupTestRouter(handler)
+
+	req := httptest.NewRequest("GET", "/api/v1/urls", nil)
+	req.Host = "go.acme.example"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.AssertNotCalled(t, "ListURLs", mock.Anything, mock.Anything)
+
+	req = httptest.NewRequest("GET", "/abc123", nil)
+	req.Host = "go.acme.example"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestHandler_Redirect(t *testing.T) {
+	t.Run("returns 301 redirect when URL exists", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock expectation
+		mockService.On("Redirect", mock.Anything, "abc123").Return(
+			&model.RedirectTarget{URL: "https://example.com", Status: http.StatusMovedPermanently},
+			nil,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("uses the status and destination chosen by the service", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "temp").Return(
+			&model.RedirectTarget{URL: "https://example.com/?utm_source=x", Status: http.StatusTemporaryRedirect},
+			nil,
+		)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/temp?utm_source=x", nil))
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "https://example.com/?utm_source=x", w.Header().Get("Location"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 404 when URL not found", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return not found error
+		mockService.On("Redirect", mock.Anything, "notfound").Return(
+			nil,
+			service.ErrURLNotFound,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("GET", "/notfound", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "Not Found", response.Error)
+		assert.Equal(t, "URL not found", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 410 when URL has expired", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockDB := &MockDB{shouldFail: false}
+		mockCache := &MockCache{shouldFail: false}
+
+		// Setup mock to return expired error
+		mockService.On("Redirect", mock.Anything, "expired").Return(
+			nil,
+			service.ErrURLExpired,
+		)
+
+		handler := api.NewHandler(mockService, mockDB, mockCache, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("GET", "/expired", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "Gone", response.Error)
+		assert.Equal(t, "URL has expired", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 410 when click limit is reached", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "once").Return(nil, service.ErrClickLimitReached)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/once", nil))
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "URL has reached its click limit", response.Message)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 404 with Retry-After before activation", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "launch").Return(nil,
+			&service.UnavailableError{Reason: service.ErrURLNotActive, ActiveAt: time.Now().Add(90 * time.Second)})
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/launch", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 90, retryAfter, 2)
+
+		var response model.ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		assert.Equal(t, "URL is not active yet", response.Message)
+	})
+
+	t.Run("sends visitors to the fallback before activation", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "launch").Return(nil,
+			&service.UnavailableError{Reason: service.ErrURLNotActive, ActiveAt: time.Now().Add(time.Hour), FallbackURL: "https://example.com/soon"})
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/launch", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/soon", w.Header().Get("Location"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("sends visitors of an exhausted link to the fallback", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "once").Return(nil,
+			&service.UnavailableError{Reason: service.ErrClickLimitReached, FallbackURL: "https://example.com/gone"})
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/once", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/gone", w.Header().Get("Location"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("sets Vary for links with redirect rules", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "app").Return(&model.RedirectTarget{
+			URL: "https://apps.apple.com/app/id1", Status: http.StatusFound, Vary: "User-Agent",
+		}, nil)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://apps.apple.com/app/id1", w.Header().Get("Location"))
+		assert.Equal(t, "User-Agent", w.Header().Get("Vary"))
+	})
+
+	t.Run("remembers the variant of sticky A/B links", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "split").Return(&model.RedirectTarget{
+			URL: "https://example.com/v2", Status: http.StatusFound, NoStore: true, Variant: "new", Sticky: true,
+		}, nil)
+		mockService.On("Redirect", mock.Anything, "plain").Return(&model.RedirectTarget{
+			URL: "https://example.com/v1", Status: http.StatusFound, NoStore: true, Variant: "control",
+		}, nil)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/split", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "link_variant", cookies[0].Name)
+		assert.Equal(t, "new", cookies[0].Value)
+		assert.Equal(t, "/split", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/plain", nil))
+		assert.Empty(t, w.Result().Cookies(), "non-sticky links set no cookie")
+	})
+
+	t.Run("renders an error page for browsers", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "old").Return(nil,
+			&service.UnavailableError{Reason: service.ErrURLExpired})
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("GET", "/old", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), "URL has expired")
+
+		req = httptest.NewRequest("GET", "/old", nil)
+		req.Header.Set("Accept", "*/*")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	})
+
+	t.Run("renders configured error pages", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "404.html"),
+			[]byte(`<h1>{{.Status}}</h1><p>Nothing at {{.Host}}/{{.Code}}</p>`), 0o644))
+		pages, err := api.LoadErrorPages(dir)
+		require.NoError(t, err)
+
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "nope").Return(nil, service.ErrURLNotFound)
+		mockService.On("Redirect", mock.Anything, "old").Return(nil, service.ErrURLExpired)
+
+		handler := api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).WithErrorPages(pages)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest("GET", "/nope", nil)
+		req.Host = "go.acme.com"
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "<h1>404</h1><p>Nothing at go.acme.com/nope</p>", w.Body.String())
+
+		req = httptest.NewRequest("GET", "/old", nil)
+		req.Header.Set("Accept", "text/html")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Contains(t, w.Body.String(), "URL has expired", "no 410.html, built-in page")
+	})
+}
+
+func TestLoadErrorPages_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "410.html"), []byte(`{{.Broken`), 0o644))
+
+	_, err := api.LoadErrorPages(dir)
+	assert.Error(t, err)
+}
+
+// denyingLimiter rejects every unlock attempt and records the bucket keys.
+type denyingLimiter struct {
+	keys []string
+}
+
+func (l *denyingLimiter) Check(_ context.Context, key string) (bool, int32, int64, error) {
+	l.keys = append(l.keys, key)
+	return false, 0, 30000, nil
+}
+
+// failingLimiter cannot reach the rate limiter.
+type failingLimiter struct{}
+
+func (failingLimiter) Check(context.Context, string) (bool, int32, int64, error) {
+	return false, 0, 0, errors.New("rate limiter down")
+}
+
+func TestHandler_PasswordProtected(t *testing.T) {
+	postPassword := func(router *gin.Engine, path, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("serves the password form instead of redirecting", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "doc").Return(nil, service.ErrPasswordRequired)
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/doc", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), `<form method="post">`)
+		assert.Empty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("unlocked redirects are not cached", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("Redirect", mock.Anything, "doc").Return(
+			&model.RedirectTarget{URL: "https://intranet.example", Status: http.StatusMovedPermanently, NoStore: true}, nil)
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/doc", nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("correct password sets the unlock cookie", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("UnlockURL", mock.Anything, "doc", "hunter2").Return(
+			&model.UnlockGrant{Token: "123.sig", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil))
+
+		w := postPassword(router, "/doc?utm_source=mail", "hunter2")
+
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "/doc?utm_source=mail", w.Header().Get("Location"))
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "link_unlock", cookies[0].Name)
+		assert.Equal(t, "123.sig", cookies[0].Value)
+		assert.Equal(t, "/doc", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.InDelta(t, 3600, cookies[0].MaxAge, 5)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("wrong password shows the form again", func(t *testing.T) {
+		mockService := new(MockURLService)
+		mockService.On("UnlockURL", mock.Anything, "doc", "guess").Return(nil, service.ErrWrongPassword)
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil))
+
+		w := postPassword(router, "/doc", "guess")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Incorrect password.")
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("throttled attempts never reach the service", func(t *testing.T) {
+		mockService := new(MockURLService)
+		limiter := &denyingLimiter{}
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).
+			WithUnlockLimiter(limiter))
+
+		w := postPassword(router, "/doc", "guess")
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		require.Len(t, limiter.keys, 1)
+		assert.True(t, strings.HasPrefix(limiter.keys[0], "unlock:"))
+		assert.True(t, strings.HasSuffix(limiter.keys[0], ":doc"), "bucket is per client and link")
+		mockService.AssertNotCalled(t, "UnlockURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unavailable throttle refuses the attempt", func(t *testing.T) {
+		mockService := new(MockURLService)
+		router := setupTestRouter(api.NewHandler(mockService, &MockDB{}, &MockCache{}, newTestLogger(), nil).
+			WithUnlockLimiter(&failingLimiter{}))
+
+		w := postPassword(router, "/doc", "guess")
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		mockService.AssertNotCalled(t, "UnlockURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
 }
 
 // TestHealthCheck_ExposesCircuitBreakerState verifies CB state appears in response.
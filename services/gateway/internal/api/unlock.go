@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/service"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// unlockCookie holds the token that lets a visitor past a link's password
+// prompt. It is scoped to the link's path, so each link has its own.
+const unlockCookie = "link_unlock"
+
+// UnlockLimiter throttles password attempts on protected links; key names
+// the bucket. *ratelimit.Client satisfies it.
+type UnlockLimiter interface {
+	Check(ctx context.Context, key string) (allowed bool, remaining int32, retryAfterMs int64, err error)
+}
+
+// WithUnlockLimiter throttles password submissions per client IP and link.
+// Without one, attempts are only slowed by bcrypt.
+func (h *Handler) WithUnlockLimiter(l UnlockLimiter) *Handler {
+	h.unlockLimiter = l
+	return h
+}
+
+// passwordPage is the interstitial served instead of a redirect for
+// password-protected links. The form posts back to the same URL, query
+// string included, so forwarded parameters survive the prompt.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body{font-family:system-ui,sans-serif;background:#f5f5f5;display:flex;min-height:100vh;margin:0;align-items:center;justify-content:center}
+form{background:#fff;padding:2rem;border-radius:8px;box-shadow:0 1px 4px rgba(0,0,0,.15);width:min(22rem,90vw)}
+h1{font-size:1.2rem;margin:0 0 1rem}
+input,button{box-sizing:border-box;width:100%;padding:.6rem;margin-top:.5rem;font-size:1rem}
+.error{color:#b00020}
+</style>
+</head>
+<body>
+<form method="post">
+<h1>This link is password protected</h1>
+{{if .Error}}<p class="error" role="alert">{{.Error}}</p>{{end}}
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// passwordPageData fills passwordPage.
+type passwordPageData struct {
+	Error string
+}
+
+// passwordPrompt renders the password form with the given status and error.
+func (h *Handler) passwordPrompt(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	c.Render(status, render.HTML{
+		Template: passwordPage,
+		Name:     "password",
+		Data:     passwordPageData{Error: message},
+	})
+}
+
+// unlock handles POST /:code
+// Checks the password submitted from the interstitial of a protected link.
+// Path parameter: code - the short code to resolve on the request's host
+// Form field: password
+// Response codes:
+//   - 303 See Other: Password accepted; sets the unlock cookie and sends the
+//     visitor back to GET /:code, which now redirects
+//   - 403 Forbidden: Wrong password (form shown again)
+//   - 302 Found: Link is not active yet or has expired, and it or its
+//     workspace has a fallback_url
+//   - 404 Not Found: Short code does not exist on this host, or is not active yet
+//   - 410 Gone: URL has expired
+//   - 429 Too Many Requests: Too many attempts from this client for this link
+//   - 500 Internal Server Error: Unexpected error
+//   - 503 Service Unavailable: Attempts cannot be throttled right now
+func (h *Handler) unlock(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+
+	// The throttle fails closed: without it nothing but bcrypt stands
+	// between a guesser and the password.
+	if h.unlockLimiter != nil {
+		key := fmt.Sprintf("unlock:%s:%s:%s", c.ClientIP(), tenant.WorkspaceID(ctx), code)
+		allowed, _, retryAfterMs, err := h.unlockLimiter.Check(ctx, key)
+		switch {
+		case err != nil:
+			h.logger.WarnContext(ctx, "unlock throttle unavailable, refusing attempt",
+				slog.String("error", err.Error()),
+				slog.String("code", code))
+			h.passwordPrompt(c, http.StatusServiceUnavailable, "Passwords cannot be checked right now. Please try again later.")
+			return
+		case !allowed:
+			c.Header("Retry-After", fmt.Sprintf("%d", max(retryAfterMs/1000, 1)))
+			h.passwordPrompt(c, http.StatusTooManyRequests, "Too many attempts. Please wait before trying again.")
+			return
+		}
+	}
+
+	grant, err := h.urlService.UnlockURL(ctx, &model.UnlockRequest{
+		Code:     code,
+		Password: c.PostForm("password"),
+	})
+	if err != nil {
+		if h.fallbackRedirect(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			h.passwordPrompt(c, http.StatusForbidden, "Incorrect password.")
+		case errors.Is(err, service.ErrURLNotFound):
+			h.visitorError(c, http.StatusNotFound, "URL not found")
+		case errors.Is(err, service.ErrURLExpired):
+			h.visitorError(c, http.StatusGone, "URL has expired")
+		case errors.Is(err, service.ErrURLNotActive):
+			h.notActiveResponse(c, err)
+		default:
+			h.logger.ErrorContext(ctx, "unexpected error unlocking URL",
+				slog.String("error", err.Error()),
+				slog.String("code", code))
+			h.errorResponse(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(unlockCookie, grant.Token, int(time.Until(grant.ExpiresAt).Seconds()),
+		"/"+code, "", isHTTPS(c), true)
+	c.Redirect(http.StatusSeeOther, c.Request.URL.RequestURI())
+}
+
+// isHTTPS reports whether the visitor reached us over TLS, directly or
+// through a proxy that says so.
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// variantCookie remembers which A/B variant of a sticky link the visitor was
+// sent to. Like unlockCookie it is scoped to the link's path.
+const variantCookie = "link_variant"
+
+// variantCookieTTL is how long a visitor stays on their variant; it only
+// needs to outlast a typical experiment.
+const variantCookieTTL = 90 * 24 * time.Hour
+
+// rememberVariant sets variantCookie when the redirect asks for sticky
+// assignment.
+func rememberVariant(c *gin.Context, code string, target *model.RedirectTarget) {
+	if !target.Sticky {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookie, target.Variant, int(variantCookieTTL.Seconds()),
+		"/"+code, "", isHTTPS(c), true)
+}
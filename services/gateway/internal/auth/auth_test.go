@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKey(t *testing.T) {
+	a, err := GenerateKey()
+	require.NoError(t, err)
+	b, err := GenerateKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, keyPrefix))
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, HashKey(a), HashKey(a))
+	assert.NotEqual(t, HashKey(a), HashKey(b))
+}
+
+func TestIdentity_CanManage(t *testing.T) {
+	alice := &Identity{Owner: "alice"}
+	admin := &Identity{Owner: "ops", Admin: true}
+
+	assert.True(t, alice.CanManage("alice"))
+	assert.False(t, alice.CanManage("bob"))
+	assert.False(t, alice.CanManage(""), "unowned links are admin-only")
+	assert.True(t, admin.CanManage("bob"))
+	assert.True(t, admin.CanManage(""))
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	id := &Identity{Owner: "alice"}
+	got, ok := FromContext(WithIdentity(context.Background(), id))
+	require.True(t, ok)
+	assert.Same(t, id, got)
+}
@@ -18,6 +18,10 @@ type Config struct {
 	Cache       CacheConfig
 	RateLimiter RateLimiterConfig
 	Analytics   AnalyticsConfig
+	Reaper      ReaperConfig
+	Auth        AuthConfig
+	Workspace   WorkspaceConfig
+	GeoIP       GeoIPConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -46,6 +50,8 @@ type CacheConfig struct {
 	Host             string
 	Port             string
 	TTL              time.Duration
+	ClickCountTTL    time.Duration // CACHE_CLICK_COUNT_TTL — max staleness of click_count in GET /api/v1/urls/:code and lifetime of max_clicks use counters
+	StatsTTL         time.Duration // CACHE_STATS_TTL — lifetime of cached GET /api/v1/urls/:code/stats results
 	ReadTimeout      time.Duration // per-operation read deadline; 0 = go-redis default (3 s)
 	WriteTimeout     time.Duration // per-operation write deadline; 0 = go-redis default (3 s)
 	OperationTimeout time.Duration // context deadline for each cache call
@@ -61,12 +67,39 @@ type CacheConfig struct {
 
 // AppConfig holds application-specific configuration
 type AppConfig struct {
-	BaseURL          string // Base URL for generating short links
-	DefaultExpiry    time.Duration
+	BaseURL          string        // Base URL for generating short links
+	DefaultExpiry    time.Duration // DEFAULT_EXPIRY — applied when a create request sets none (0 = never)
+	MaxExpiry        time.Duration // MAX_EXPIRY — furthest allowed expiry (0 = unlimited)
 	ShortCodeLen     int
 	ShortCodeRetries int
-	MaxAliasLen      int
-	MinAliasLen      int
+	MaxAliasLen      int      // ALIAS_MAX_LENGTH; must not exceed the urls.short_code column (16)
+	MinAliasLen      int      // ALIAS_MIN_LENGTH
+	AliasPattern     string   // ALIAS_PATTERN — regexp a custom alias must match
+	ReservedAliases  []string // ALIAS_RESERVED — comma-separated, case-insensitive exact match
+	AliasBlocklist   string   // ALIAS_BLOCKLIST_FILE — optional path, one blocked term per line
+	MaxBatchSize     int      // maximum items per POST /api/v1/shorten/batch (SHORTEN_BATCH_MAX_SIZE)
+	RedirectStatus   int      // REDIRECT_STATUS — 301, 302, 307 or 308 for links that set no redirect_type
+
+	// Generated short codes
+	ShortCodeStrategy  string // SHORT_CODE_STRATEGY — hash, random, counter or pool
+	ShortCodeBlockSize int    // SHORT_CODE_COUNTER_BLOCK — counter values each replica reserves at a time
+	ShortCodeSecret    string // SHORT_CODE_SECRET — key of the counter permutation; shared by all replicas
+
+	// Adaptive short code length
+	ShortCodeMaxLen    int     // SHORT_CODE_MAX_LENGTH — length new codes may grow to (≤ SHORT_CODE_LENGTH = fixed)
+	CollisionThreshold float64 // SHORT_CODE_COLLISION_THRESHOLD — collision rate that lengthens new codes
+	CollisionWindow    int     // SHORT_CODE_COLLISION_WINDOW — insert attempts per collision rate measurement
+
+	// Pre-generated short codes (SHORT_CODE_STRATEGY=pool)
+	ShortCodePoolSize     int           // SHORT_CODE_POOL_SIZE — unleased codes the pool is topped up to
+	ShortCodePoolLease    int           // SHORT_CODE_POOL_LEASE — codes each replica leases per round-trip
+	ShortCodePoolInterval time.Duration // SHORT_CODE_POOL_INTERVAL — time between depth checks and refills
+
+	// Password-protected links
+	UnlockSecret string        // LINK_UNLOCK_SECRET — HMAC key for unlock cookies; shared by all replicas (empty = random per process)
+	UnlockTTL    time.Duration // LINK_UNLOCK_TTL — how long a correct password is remembered
+
+	ErrorPagesDir string // ERROR_PAGES_DIR — templates for the HTML 404/410 pages of dead links (empty = built-in)
 }
 
 type RateLimiterConfig struct {
@@ -80,11 +113,41 @@ type AnalyticsConfig struct {
 	Enabled bool
 }
 
+// ReaperConfig controls background deletion of expired links.
+type ReaperConfig struct {
+	Interval   time.Duration // REAPER_INTERVAL — time between runs (0 = disabled)
+	BatchSize  int           // REAPER_BATCH_SIZE — rows deleted per statement
+	MaxBatches int           // REAPER_MAX_BATCHES — batches per run, bounding how long the lock is held
+	Grace      time.Duration // REAPER_GRACE — how long expired links keep answering 410 before removal
+	Enabled    bool
+}
+
+// AuthConfig controls API key authentication on /api/v1 routes.
+type AuthConfig struct {
+	Enabled        bool // AUTH_ENABLED — authenticate keys and restrict update/delete/stats to the link owner
+	AllowAnonymous bool // AUTH_ALLOW_ANONYMOUS — let requests without a key create and read links
+}
+
+// WorkspaceConfig controls tenant resolution.
+type WorkspaceConfig struct {
+	CacheTTL time.Duration // WORKSPACE_CACHE_TTL — how long host and key lookups are reused in process
+}
+
+// GeoIPConfig controls client country lookups for redirect rules and click
+// analytics.
+type GeoIPConfig struct {
+	DBPath         string        // GEOIP_DB_PATH — MaxMind-format country or city database (empty = disabled)
+	ReloadInterval time.Duration // GEOIP_RELOAD_INTERVAL — how often the file is checked for changes (0 = never)
+	Enabled        bool
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	_ = godotenv.Load("../../../../.env")
 	rateLimiterAddr := getEnv("RATE_LIMITER_ADDR", "")
 	amqpURL := getEnv("AMQP_URL", "")
+	reaperInterval := getEnvDuration("REAPER_INTERVAL", time.Minute)
+	geoIPPath := getEnv("GEOIP_DB_PATH", "")
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8080"),
@@ -107,6 +170,8 @@ func Load() *Config {
 			Host:             getEnv("CACHE_HOST", "localhost"),
 			Port:             getEnv("CACHE_PORT", "6379"),
 			TTL:              getEnvDuration("CACHE_TTL", 5*time.Minute),
+			ClickCountTTL:    getEnvDuration("CACHE_CLICK_COUNT_TTL", 30*time.Second),
+			StatsTTL:         getEnvDuration("CACHE_STATS_TTL", time.Minute),
 			ReadTimeout:      getEnvDuration("CACHE_READ_TIMEOUT", 500*time.Millisecond),
 			WriteTimeout:     getEnvDuration("CACHE_WRITE_TIMEOUT", 500*time.Millisecond),
 			OperationTimeout: getEnvDuration("CACHE_OPERATION_TIMEOUT", 50*time.Millisecond),
@@ -122,10 +187,31 @@ func Load() *Config {
 		},
 		App: AppConfig{
 			BaseURL:          getEnv("BASE_URL", "http://localhost:8080"),
+			DefaultExpiry:    getEnvDuration("DEFAULT_EXPIRY", 0),
+			MaxExpiry:        getEnvDuration("MAX_EXPIRY", 0),
 			ShortCodeLen:     getEnvInt("SHORT_CODE_LENGTH", 6),
 			ShortCodeRetries: getEnvInt("SHORT_CODE_MAX_RETRIES", 3),
-			MaxAliasLen:      20,
-			MinAliasLen:      3,
+			MaxAliasLen:      getEnvInt("ALIAS_MAX_LENGTH", 16),
+			MinAliasLen:      getEnvInt("ALIAS_MIN_LENGTH", 3),
+			AliasPattern:     getEnv("ALIAS_PATTERN", `^[A-Za-z0-9_-]+$`),
+			ReservedAliases:  getEnvList("ALIAS_RESERVED", []string{"api", "health", "metrics", "admin", "static", "assets", "docs"}),
+			AliasBlocklist:   getEnv("ALIAS_BLOCKLIST_FILE", ""),
+			MaxBatchSize:     getEnvInt("SHORTEN_BATCH_MAX_SIZE", 1000),
+			RedirectStatus:   getEnvInt("REDIRECT_STATUS", 301),
+			UnlockSecret:     getEnv("LINK_UNLOCK_SECRET", ""),
+			UnlockTTL:        getEnvDuration("LINK_UNLOCK_TTL", time.Hour),
+			ErrorPagesDir:    getEnv("ERROR_PAGES_DIR", ""),
+
+			ShortCodeStrategy:  getEnv("SHORT_CODE_STRATEGY", "hash"),
+			ShortCodeBlockSize: getEnvInt("SHORT_CODE_COUNTER_BLOCK", 1000),
+			ShortCodeSecret:    getEnv("SHORT_CODE_SECRET", ""),
+			ShortCodeMaxLen:    getEnvInt("SHORT_CODE_MAX_LENGTH", 8),
+			CollisionThreshold: getEnvFloat64("SHORT_CODE_COLLISION_THRESHOLD", 0.1),
+			CollisionWindow:    getEnvInt("SHORT_CODE_COLLISION_WINDOW", 1000),
+
+			ShortCodePoolSize:     getEnvInt("SHORT_CODE_POOL_SIZE", 100000),
+			ShortCodePoolLease:    getEnvInt("SHORT_CODE_POOL_LEASE", 100),
+			ShortCodePoolInterval: getEnvDuration("SHORT_CODE_POOL_INTERVAL", 10*time.Second),
 		},
 		RateLimiter: RateLimiterConfig{
 			Addr:    rateLimiterAddr,
@@ -136,6 +222,25 @@ func Load() *Config {
 			AMQPURL: amqpURL,
 			Enabled: amqpURL != "",
 		},
+		Reaper: ReaperConfig{
+			Interval:   reaperInterval,
+			BatchSize:  getEnvInt("REAPER_BATCH_SIZE", 500),
+			MaxBatches: getEnvInt("REAPER_MAX_BATCHES", 20),
+			Grace:      getEnvDuration("REAPER_GRACE", 24*time.Hour),
+			Enabled:    reaperInterval > 0,
+		},
+		Auth: AuthConfig{
+			Enabled:        getEnvBool("AUTH_ENABLED", true),
+			AllowAnonymous: getEnvBool("AUTH_ALLOW_ANONYMOUS", true),
+		},
+		Workspace: WorkspaceConfig{
+			CacheTTL: getEnvDuration("WORKSPACE_CACHE_TTL", time.Minute),
+		},
+		GeoIP: GeoIPConfig{
+			DBPath:         geoIPPath,
+			ReloadInterval: getEnvDuration("GEOIP_RELOAD_INTERVAL", time.Minute),
+			Enabled:        geoIPPath != "",
+		},
 	}
 }
 
@@ -187,6 +292,30 @@ func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
 	return defaultVal
 }
 
+func getEnvBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// getEnvList splits a comma-separated env var, dropping empty entries.
+func getEnvList(key string, defaultVal []string) []string {
+	val := getEnv(key, "")
+	if val == "" {
+		return defaultVal
+	}
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getCacheNodes(defaultHost, defaultPort string) []string {
 	cacheNodesEnv := getEnv("CACHE_NODES", "")
 	if cacheNodesEnv == "" {
@@ -0,0 +1,123 @@
+// Package geoip resolves client IP addresses to countries using a local
+// MaxMind-format (mmdb) database such as GeoLite2-Country or GeoIP2-City.
+package geoip
+
+//go:generate go run testdata/generate.go testdata/GeoIP2-Country-Test.mmdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// countryRecord is the part of a country or city record the resolver reads.
+// RegisteredCountry covers networks the database has no location for.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// database is one loaded copy of the file. The whole file is read into
+// memory rather than mapped, so rewriting it in place cannot fault readers.
+type database struct {
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// Resolver looks up countries in an mmdb file. Reload and Watch swap in a
+// new copy when the file changes, so databases refreshed by geoipupdate are
+// picked up without a restart. A nil *Resolver resolves nothing.
+type Resolver struct {
+	path   string
+	logger *slog.Logger
+	db     atomic.Pointer[database]
+}
+
+// Open loads the database at path.
+func Open(path string, logger *slog.Logger) (*Resolver, error) {
+	r := &Resolver{path: path, logger: logger}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country ip is in, or ""
+// when it is unknown: unparsable, private, or missing from the database.
+func (r *Resolver) Country(ip string) string {
+	if r == nil {
+		return ""
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	var rec countryRecord
+	if err := r.db.Load().reader.Lookup(addr, &rec); err != nil {
+		// e.g. an IPv6 client and an IPv4-only database
+		return ""
+	}
+	if rec.Country.ISOCode != "" {
+		return rec.Country.ISOCode
+	}
+	return rec.RegisteredCountry.ISOCode
+}
+
+// Reload reads the file again if its modification time or size changed
+// since it was last loaded, and reports whether it did. On error the
+// previous copy stays in use.
+func (r *Resolver) Reload() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %w", err)
+	}
+	if cur := r.db.Load(); cur != nil && cur.modTime.Equal(info.ModTime()) && cur.size == info.Size() {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %s: %w", r.path, err)
+	}
+	r.db.Store(&database{reader: reader, modTime: info.ModTime(), size: info.Size()})
+	r.logger.Info("geoip database loaded",
+		slog.String("path", r.path),
+		slog.String("type", reader.Metadata.DatabaseType),
+		slog.Time("built", time.Unix(int64(reader.Metadata.BuildEpoch), 0)))
+	return true, nil
+}
+
+// Watch calls Reload every interval until ctx is cancelled. Failed reloads
+// are logged and retried on the next tick; a file caught half-written
+// fails to parse and is picked up once complete.
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reload(); err != nil {
+				r.logger.Error("geoip reload failed, keeping previous database",
+					slog.String("error", err.Error()))
+			}
+		}
+	}
+}
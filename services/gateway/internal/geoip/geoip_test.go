@@ -0,0 +1,104 @@
+package geoip
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fixture = "testdata/GeoIP2-Country-Test.mmdb"
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestResolver_Country(t *testing.T) {
+	r, err := Open(fixture, testLogger())
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"81.2.69.142":    "GB",
+		"89.160.20.115":  "SE",
+		"216.160.83.60":  "US",
+		"2.125.160.217":  "DE",
+		"67.43.156.1":    "BT", // registered country only
+		"10.0.0.1":       "",
+		"2001:db8::1":    "", // IPv4-only database
+		"not an address": "",
+	}
+	for ip, want := range tests {
+		assert.Equal(t, want, r.Country(ip), ip)
+	}
+
+	var none *Resolver
+	assert.Empty(t, none.Country("81.2.69.142"), "nil resolver")
+}
+
+func TestOpen_Errors(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"), testLogger())
+	assert.Error(t, err)
+
+	garbage := filepath.Join(t.TempDir(), "garbage.mmdb")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0o644))
+	_, err = Open(garbage, testLogger())
+	assert.Error(t, err)
+}
+
+func TestResolver_Reload(t *testing.T) {
+	data, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	r, err := Open(path, testLogger())
+	require.NoError(t, err)
+
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file is not read again")
+
+	// A broken update keeps the previous database in use.
+	require.NoError(t, os.WriteFile(path, []byte("truncated"), 0o644))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "GB", r.Country("81.2.69.142"))
+
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	reloaded, err = r.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+}
+
+func TestResolver_Watch(t *testing.T) {
+	data, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	r, err := Open(path, testLogger())
+	require.NoError(t, err)
+	first := r.db.Load()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.Eventually(t, func() bool { return r.db.Load() != first }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
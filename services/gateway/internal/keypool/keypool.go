@@ -0,0 +1,374 @@
+// Package keypool pre-generates short codes so link creation does not pay
+// for collision retries on the request path.
+//
+// Codes live in a Postgres table shared by every gateway replica. A
+// background loop keeps the table topped up; each replica leases a block of
+// codes into memory and hands them out one by one, returning whatever is
+// left on shutdown.
+package keypool
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// LockKey identifies the pool filler's Postgres advisory lock.
+const LockKey int64 = 0x7572_6c6b_6579_706c // "urlkeypl"
+
+// fillBatch is the number of codes inserted per statement.
+const fillBatch = 1000
+
+// Store holds the shared pool of unused codes.
+// repository.KeyPoolRepository implements it.
+type Store interface {
+	// Depth returns how many codes are waiting to be leased.
+	Depth(ctx context.Context) (int64, error)
+	// Add inserts codes, skipping any already pooled, and returns how many were new.
+	Add(ctx context.Context, codes []string) (int64, error)
+	// Lease removes up to n codes from the pool and returns them.
+	Lease(ctx context.Context, n int) ([]string, error)
+	// Release puts unused leased codes back.
+	Release(ctx context.Context, codes []string) error
+	// DropShorter removes pooled codes shorter than length and returns how many.
+	DropShorter(ctx context.Context, length int) (int64, error)
+}
+
+// Locker grants exclusive leadership for one fill across gateway replicas.
+// TryLock returns ok=false without error when another holder has it;
+// reaper.PGLocker, created with LockKey, implements it.
+type Locker interface {
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
+
+// Generator produces candidate codes, both to fill the pool and to serve
+// requests while it is dry. service.RandomStrategy implements it.
+type Generator interface {
+	Next(ctx context.Context, longURL string, length, attempt int) (string, error)
+}
+
+// Config controls the size of the pool and how it is used.
+type Config struct {
+	Size      int           // codes the pool is topped up to
+	LeaseSize int           // codes a replica leases per round-trip
+	Interval  time.Duration // time between fills
+	Length    int           // code length until callers ask for longer codes
+}
+
+// Pool is a service.CodeStrategy that serves pre-generated codes. When the
+// shared pool runs dry it falls back to its Generator, so link creation
+// keeps working at the cost of the collision retries the pool avoids.
+//
+// The pool follows the code length its callers ask for: once they ask for
+// longer codes, shorter ones are no longer served, the next fill removes
+// them from the shared pool and tops it up at the new length.
+type Pool struct {
+	store  Store
+	locker Locker
+	gen    Generator
+	cfg    Config
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	buf     []string
+	closed  bool
+	leasing bool // a lease is in flight; p.mu is not held across it
+
+	// dry is set when a lease comes back empty and cleared by the next
+	// fill, so a drained pool is not queried on every request.
+	dry   atomic.Bool
+	depth atomic.Int64
+
+	// length is the longest code length asked for; purged is the length
+	// the shared pool was last cleaned up to, used by the fill loop only.
+	length atomic.Int64
+	purged int64
+
+	generated metric.Int64Counter
+	fallbacks metric.Int64Counter
+	fills     metric.Int64Counter
+}
+
+// New creates a pool. Zero Size, LeaseSize and Length fall back to 100000,
+// 100 and 6.
+func New(store Store, locker Locker, gen Generator, cfg Config, logger *slog.Logger) *Pool {
+	if cfg.Size <= 0 {
+		cfg.Size = 100000
+	}
+	if cfg.LeaseSize <= 0 {
+		cfg.LeaseSize = 100
+	}
+	if cfg.Length <= 0 {
+		cfg.Length = 6
+	}
+
+	p := &Pool{store: store, locker: locker, gen: gen, cfg: cfg, logger: logger}
+	p.depth.Store(-1)
+	p.length.Store(int64(cfg.Length))
+	p.purged = int64(cfg.Length)
+
+	meter := otel.Meter("gateway/keypool")
+	p.generated, _ = meter.Int64Counter("short_code_pool_generated_total",
+		metric.WithDescription("Codes added to the short code pool"),
+	)
+	p.fallbacks, _ = meter.Int64Counter("short_code_pool_fallback_total",
+		metric.WithDescription("Codes generated on the request path because the pool was empty or unreachable"),
+	)
+	p.fills, _ = meter.Int64Counter("short_code_pool_fills_total",
+		metric.WithDescription("Pool fill runs by result (filled, full, skipped, error)"),
+	)
+	_, _ = meter.Int64ObservableGauge("short_code_pool_depth",
+		metric.WithDescription("Unleased codes in the shared pool, as last seen by this replica"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			if d := p.depth.Load(); d >= 0 {
+				o.Observe(d)
+			}
+			return nil
+		}),
+	)
+	_, _ = meter.Int64ObservableGauge("short_code_pool_buffered",
+		metric.WithDescription("Codes leased by this replica and not yet used"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			p.mu.Lock()
+			n := len(p.buf)
+			p.mu.Unlock()
+			o.Observe(int64(n))
+			return nil
+		}),
+	)
+	return p
+}
+
+// Next returns a pooled code of at least length characters, leasing a new
+// block when this replica's buffer is empty. The URL and attempt only
+// matter to the fallback.
+func (p *Pool) Next(ctx context.Context, longURL string, length, attempt int) (string, error) {
+	for cur := p.length.Load(); int64(length) > cur; cur = p.length.Load() {
+		if p.length.CompareAndSwap(cur, int64(length)) {
+			p.logger.InfoContext(ctx, "short code pool switching to longer codes",
+				slog.Int("length", length))
+			break
+		}
+	}
+	if code, ok := p.take(ctx, length); ok {
+		return code, nil
+	}
+	p.fallbacks.Add(ctx, 1)
+	return p.gen.Next(ctx, longURL, length, attempt)
+}
+
+// take pops a buffered code, leasing a block when the buffer is empty. The
+// lease runs without p.mu held, so a slow database only delays the request
+// that triggered it: requests finding the buffer empty meanwhile fall back
+// to the Generator instead of queueing behind the lease.
+func (p *Pool) take(ctx context.Context, length int) (string, bool) {
+	p.mu.Lock()
+	if code, ok := p.pop(length); ok || p.closed || p.leasing || p.dry.Load() {
+		p.mu.Unlock()
+		return code, ok
+	}
+	p.leasing = true
+	p.mu.Unlock()
+
+	codes, err := p.store.Lease(ctx, p.cfg.LeaseSize)
+
+	p.mu.Lock()
+	p.leasing = false
+	if err != nil {
+		p.mu.Unlock()
+		p.logger.WarnContext(ctx, "failed to lease short codes, generating instead",
+			slog.String("error", err.Error()))
+		return "", false
+	}
+	// Codes left over from before a length change; the next fill
+	// removes the rest.
+	codes = slices.DeleteFunc(codes, func(code string) bool { return len(code) < length })
+	if len(codes) == 0 {
+		p.dry.Store(true)
+		p.mu.Unlock()
+		p.logger.WarnContext(ctx, "short code pool is empty, generating instead")
+		return "", false
+	}
+	if p.closed {
+		// Close ran during the lease and will not see these codes.
+		p.mu.Unlock()
+		if err := p.store.Release(ctx, codes); err != nil {
+			p.logger.WarnContext(ctx, "failed to return leased short codes",
+				slog.String("error", err.Error()),
+				slog.Int("codes", len(codes)))
+		}
+		return "", false
+	}
+	p.buf = append(p.buf, codes...)
+	code, ok := p.pop(length)
+	p.mu.Unlock()
+	return code, ok
+}
+
+// pop removes and returns a buffered code of at least length characters,
+// dropping shorter ones. p.mu must be held.
+func (p *Pool) pop(length int) (string, bool) {
+	if p.closed {
+		return "", false
+	}
+	p.buf = slices.DeleteFunc(p.buf, func(code string) bool { return len(code) < length })
+	if len(p.buf) == 0 {
+		return "", false
+	}
+	code := p.buf[len(p.buf)-1]
+	p.buf = p.buf[:len(p.buf)-1]
+	return code, true
+}
+
+// Run fills the pool immediately and then every Interval until ctx is
+// cancelled.
+func (p *Pool) Run(ctx context.Context) {
+	p.logger.Info("short code pool started",
+		slog.Int("size", p.cfg.Size),
+		slog.Int("lease_size", p.cfg.LeaseSize),
+		slog.Duration("interval", p.cfg.Interval))
+
+	p.FillOnce(ctx)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("short code pool stopped")
+			return
+		case <-ticker.C:
+			p.FillOnce(ctx)
+		}
+	}
+}
+
+// FillOnce records the pool depth and, if it is below Size, tops it up with
+// freshly generated codes. Only the replica holding the advisory lock fills;
+// the others just refresh the depth. It returns how many codes were added.
+//
+// After callers switch to longer codes, it first removes the shorter ones
+// from the shared pool. Any replica may do that; deleting twice is harmless.
+func (p *Pool) FillOnce(ctx context.Context) (int64, error) {
+	length := p.length.Load()
+	if length > p.purged {
+		dropped, err := p.store.DropShorter(ctx, int(length))
+		if err != nil {
+			p.logger.Error("failed to drop outdated short codes from pool",
+				slog.String("error", err.Error()))
+		} else {
+			p.purged = length
+			p.logger.Info("dropped outdated short codes from pool",
+				slog.Int64("dropped", dropped),
+				slog.Int64("length", length))
+		}
+	}
+
+	depth, err := p.store.Depth(ctx)
+	if err != nil {
+		p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "error")))
+		p.logger.Error("failed to read short code pool depth",
+			slog.String("error", err.Error()))
+		return 0, err
+	}
+	p.observeDepth(depth)
+	if depth >= int64(p.cfg.Size) {
+		p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "full")))
+		return 0, nil
+	}
+
+	unlock, ok, err := p.locker.TryLock(ctx)
+	if err != nil {
+		p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "error")))
+		p.logger.Error("short code pool failed to acquire lock",
+			slog.String("error", err.Error()))
+		return 0, err
+	}
+	if !ok {
+		p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "skipped")))
+		p.logger.Debug("short code pool lock held by another replica, skipping fill")
+		return 0, nil
+	}
+	defer unlock()
+
+	// Another replica may have filled while we waited for the lock.
+	if depth, err = p.store.Depth(ctx); err != nil {
+		return 0, p.fillFailed(ctx, err, 0)
+	}
+
+	start := time.Now()
+	var added int64
+	for depth < int64(p.cfg.Size) && ctx.Err() == nil {
+		codes := make([]string, 0, min(fillBatch, int64(p.cfg.Size)-depth))
+		for len(codes) < cap(codes) {
+			code, err := p.gen.Next(ctx, "", int(length), 0)
+			if err != nil {
+				return added, p.fillFailed(ctx, err, added)
+			}
+			codes = append(codes, code)
+		}
+		n, err := p.store.Add(ctx, codes)
+		if err != nil {
+			return added, p.fillFailed(ctx, err, added)
+		}
+		if n == 0 {
+			// Every candidate was already pooled: the code space is
+			// too small for the configured size.
+			break
+		}
+		added += n
+		depth += n
+		p.generated.Add(ctx, n)
+		p.observeDepth(depth)
+	}
+
+	p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "filled")))
+	p.logger.Info("filled short code pool",
+		slog.Int64("added", added),
+		slog.Int64("depth", depth),
+		slog.Duration("duration", time.Since(start)))
+	return added, nil
+}
+
+func (p *Pool) fillFailed(ctx context.Context, err error, added int64) error {
+	p.fills.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "error")))
+	p.logger.Error("short code pool fill failed",
+		slog.String("error", err.Error()),
+		slog.Int64("added", added))
+	return err
+}
+
+func (p *Pool) observeDepth(depth int64) {
+	p.depth.Store(depth)
+	if depth > 0 {
+		p.dry.Store(false)
+	}
+}
+
+// Close returns the codes still buffered on this replica to the shared
+// pool. Later calls to Next fall back to the Generator.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if len(p.buf) == 0 {
+		return nil
+	}
+	if err := p.store.Release(ctx, p.buf); err != nil {
+		p.logger.Error("failed to return leased short codes",
+			slog.String("error", err.Error()),
+			slog.Int("codes", len(p.buf)))
+		return err
+	}
+	p.logger.Info("returned leased short codes to pool",
+		slog.Int("codes", len(p.buf)))
+	p.buf = nil
+	return nil
+}
@@ -0,0 +1,309 @@
+package keypool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// fakeStore keeps the pool in a set and records leases.
+type fakeStore struct {
+	codes    map[string]bool
+	leases   []int
+	leaseErr error
+}
+
+func newFakeStore(codes ...string) *fakeStore {
+	s := &fakeStore{codes: map[string]bool{}}
+	for _, c := range codes {
+		s.codes[c] = true
+	}
+	return s
+}
+
+func (s *fakeStore) Depth(context.Context) (int64, error) { return int64(len(s.codes)), nil }
+
+func (s *fakeStore) Add(_ context.Context, codes []string) (int64, error) {
+	var n int64
+	for _, c := range codes {
+		if !s.codes[c] {
+			s.codes[c] = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeStore) Lease(_ context.Context, n int) ([]string, error) {
+	s.leases = append(s.leases, n)
+	if s.leaseErr != nil {
+		return nil, s.leaseErr
+	}
+	var out []string
+	for c := range s.codes {
+		if len(out) == n {
+			break
+		}
+		delete(s.codes, c)
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (s *fakeStore) Release(ctx context.Context, codes []string) error {
+	_, err := s.Add(ctx, codes)
+	return err
+}
+
+func (s *fakeStore) DropShorter(_ context.Context, length int) (int64, error) {
+	var n int64
+	for c := range s.codes {
+		if len(c) < length {
+			delete(s.codes, c)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type fakeLocker struct {
+	held     bool
+	unlocked int
+}
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.unlocked++ }, true, nil
+}
+
+// anyLen is the code length asked for by tests that do not change it;
+// every test code is at least this long.
+const anyLen = 1
+
+// seqGen returns gen-1, gen-2, ... and counts its calls.
+type seqGen struct{ calls int }
+
+func (g *seqGen) Next(context.Context, string, int, int) (string, error) {
+	g.calls++
+	return fmt.Sprintf("gen-%d", g.calls), nil
+}
+
+func TestPool_FillOnce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tops the pool up to Size", func(t *testing.T) {
+		store := newFakeStore("a", "b")
+		locker := &fakeLocker{}
+		p := New(store, locker, &seqGen{}, Config{Size: 2500}, newTestLogger())
+
+		added, err := p.FillOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2498), added)
+		assert.Len(t, store.codes, 2500)
+		assert.Equal(t, int64(2500), p.depth.Load())
+		assert.Equal(t, 1, locker.unlocked)
+
+		added, err = p.FillOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, added, "full pool is left alone")
+		assert.Equal(t, 1, locker.unlocked, "no lock needed when full")
+	})
+
+	t.Run("skips when another replica holds the lock", func(t *testing.T) {
+		store := newFakeStore()
+		p := New(store, &fakeLocker{held: true}, &seqGen{}, Config{Size: 10}, newTestLogger())
+
+		added, err := p.FillOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, added)
+		assert.Zero(t, p.depth.Load(), "depth is still reported")
+	})
+
+	t.Run("stops when the generator only repeats", func(t *testing.T) {
+		store := newFakeStore()
+		p := New(store, &fakeLocker{}, constGen("same"), Config{Size: 10}, newTestLogger())
+
+		added, err := p.FillOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), added)
+	})
+}
+
+type constGen string
+
+func (g constGen) Next(context.Context, string, int, int) (string, error) { return string(g), nil }
+
+func TestPool_Next(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leases blocks and never repeats", func(t *testing.T) {
+		store := newFakeStore()
+		for i := range 25 {
+			store.codes[fmt.Sprintf("c%02d", i)] = true
+		}
+		gen := &seqGen{}
+		p := New(store, &fakeLocker{}, gen, Config{Size: 25, LeaseSize: 10}, newTestLogger())
+
+		seen := map[string]bool{}
+		for range 25 {
+			code, err := p.Next(ctx, "https://example.com", anyLen, 0)
+			require.NoError(t, err)
+			assert.False(t, seen[code], "code %s repeated", code)
+			seen[code] = true
+		}
+		assert.Equal(t, []int{10, 10, 10}, store.leases)
+		assert.Zero(t, gen.calls)
+	})
+
+	t.Run("falls back to the generator while dry", func(t *testing.T) {
+		store := newFakeStore()
+		gen := &seqGen{}
+		p := New(store, &fakeLocker{}, gen, Config{Size: 5, LeaseSize: 5}, newTestLogger())
+
+		for range 3 {
+			code, err := p.Next(ctx, "", anyLen, 0)
+			require.NoError(t, err)
+			assert.Contains(t, code, "gen-")
+		}
+		assert.Len(t, store.leases, 1, "an empty pool is not asked again until refilled")
+
+		added, err := p.FillOnce(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), added)
+		code, err := p.Next(ctx, "", anyLen, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 8, gen.calls, "served from the refilled pool")
+		assert.True(t, code >= "gen-4" && code <= "gen-8", code)
+		assert.Len(t, store.leases, 2)
+	})
+
+	t.Run("falls back when leasing fails", func(t *testing.T) {
+		store := newFakeStore("a")
+		store.leaseErr = errors.New("db down")
+		p := New(store, &fakeLocker{}, constGen("fallback"), Config{}, newTestLogger())
+
+		code, err := p.Next(ctx, "", anyLen, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "fallback", code)
+	})
+}
+
+func TestPool_Close(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore("a", "b", "c", "d")
+	p := New(store, &fakeLocker{}, constGen("fallback"), Config{LeaseSize: 4}, newTestLogger())
+
+	used, err := p.Next(ctx, "", anyLen, 0)
+	require.NoError(t, err)
+	assert.Empty(t, store.codes)
+
+	require.NoError(t, p.Close(ctx))
+	assert.Len(t, store.codes, 3, "unused codes are returned")
+	assert.False(t, store.codes[used])
+
+	code, err := p.Next(ctx, "", anyLen, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", code, "closed pool does not lease")
+}
+
+func TestPool_RunStopsOnCancel(t *testing.T) {
+	store := newFakeStore()
+	p := New(store, &fakeLocker{}, &seqGen{}, Config{Size: 3, Interval: time.Hour}, newTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return p.depth.Load() == 3 }, time.Second, 10*time.Millisecond,
+		"fills on start")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+// lenGen returns numbered codes of the requested length.
+type lenGen struct{ calls int }
+
+func (g *lenGen) Next(_ context.Context, _ string, length, _ int) (string, error) {
+	g.calls++
+	return fmt.Sprintf("%0*d", length, g.calls), nil
+}
+
+func TestPool_LongerCodes(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore("aaaaaa", "bbbbbb", "ccccccc")
+	p := New(store, &fakeLocker{}, &lenGen{}, Config{Size: 3, LeaseSize: 1, Length: 6}, newTestLogger())
+
+	code, err := p.Next(ctx, "", 6, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, code)
+
+	code, err = p.Next(ctx, "", 7, 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 7, "buffered shorter codes are skipped")
+
+	_, err = p.FillOnce(ctx)
+	require.NoError(t, err)
+	assert.Len(t, store.codes, 3)
+	for c := range store.codes {
+		assert.Len(t, c, 7, "pool is refilled at the new length")
+	}
+
+	code, err = p.Next(ctx, "", 6, 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 7, "length never goes back")
+}
+
+// slowStore blocks leases until release is closed.
+type slowStore struct {
+	*fakeStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Lease(ctx context.Context, n int) ([]string, error) {
+	close(s.started)
+	<-s.release
+	return s.fakeStore.Lease(ctx, n)
+}
+
+func TestPool_LeaseDoesNotBlockOthers(t *testing.T) {
+	ctx := context.Background()
+	store := &slowStore{fakeStore: newFakeStore("pooled"), started: make(chan struct{}), release: make(chan struct{})}
+	p := New(store, &fakeLocker{}, constGen("fallback"), Config{LeaseSize: 1}, newTestLogger())
+
+	leased := make(chan string)
+	go func() {
+		code, _ := p.Next(ctx, "", anyLen, 0)
+		leased <- code
+	}()
+	<-store.started
+
+	code, err := p.Next(ctx, "", anyLen, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", code, "served while the lease is in flight")
+	require.NoError(t, p.Close(ctx), "close does not wait for the lease")
+
+	close(store.release)
+	assert.Equal(t, "fallback", <-leased, "pool closed during the lease")
+	assert.True(t, store.codes["pooled"], "codes leased after close are returned")
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// APIKeyStore looks up active API keys by hash.
+type APIKeyStore interface {
+	GetByHash(ctx context.Context, hash []byte) (*model.APIKey, error)
+}
+
+// AuthOptions holds optional configuration for Auth.
+type AuthOptions struct {
+	// AllowAnonymous lets requests without a key through with no identity.
+	// Handlers and services then decide what anonymous callers may do.
+	AllowAnonymous bool
+}
+
+// Auth authenticates API keys sent as "Authorization: Bearer <key>" or
+// "X-API-Key: <key>" and attaches the resulting auth.Identity to the request
+// context. A key that is present but unknown or revoked is always rejected,
+// even when anonymous access is allowed, so a typo never silently downgrades
+// a caller to anonymous.
+//
+// If the key store fails the request is rejected with 503: failing open
+// would let anyone act as an admin during a database outage.
+func Auth(store APIKeyStore, logger *slog.Logger, opts ...AuthOptions) gin.HandlerFunc {
+	var o AuthOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		key := apiKeyFromRequest(c.Request)
+		if key == "" {
+			if o.AllowAnonymous {
+				c.Next()
+				return
+			}
+			unauthorized(c, "API key required")
+			return
+		}
+
+		apiKey, err := store.GetByHash(ctx, auth.HashKey(key))
+		if err != nil {
+			if errors.Is(err, repository.ErrAPIKeyNotFound) {
+				logger.WarnContext(ctx, "rejected invalid API key",
+					slog.String("ip", c.ClientIP()))
+				unauthorized(c, "Invalid API key")
+				return
+			}
+			logger.ErrorContext(ctx, "API key lookup failed",
+				slog.String("error", err.Error()))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, model.ErrorResponse{
+				Error:   http.StatusText(http.StatusServiceUnavailable),
+				Message: "Authentication unavailable",
+			})
+			return
+		}
+
+		id := &auth.Identity{
+			KeyID:       apiKey.ID,
+			Owner:       apiKey.Owner,
+			Admin:       apiKey.HasScope(auth.ScopeAdmin),
+			WorkspaceID: apiKey.WorkspaceID,
+		}
+		c.Request = c.Request.WithContext(auth.WithIdentity(ctx, id))
+		c.Next()
+	}
+}
+
+// apiKeyFromRequest returns the key from the Authorization bearer token or,
+// failing that, the X-API-Key header.
+func apiKeyFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{
+		Error:   http.StatusText(http.StatusUnauthorized),
+		Message: message,
+	})
+}
@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/middleware"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// fakeKeyStore serves keys from memory, keyed by string(hash).
+type fakeKeyStore struct {
+	keys map[string]*model.APIKey
+	err  error
+}
+
+func (f *fakeKeyStore) GetByHash(_ context.Context, hash []byte) (*model.APIKey, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if k, ok := f.keys[string(hash)]; ok {
+		return k, nil
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+func newAuthRouter(store middleware.APIKeyStore, opts middleware.AuthOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Auth(store, slog.New(slog.NewTextHandler(io.Discard, nil)), opts))
+	r.GET("/whoami", func(c *gin.Context) {
+		id, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		if id.Admin {
+			c.String(http.StatusOK, id.Owner+" (admin)")
+			return
+		}
+		c.String(http.StatusOK, id.Owner)
+	})
+	return r
+}
+
+func TestAuth(t *testing.T) {
+	store := &fakeKeyStore{keys: map[string]*model.APIKey{
+		string(auth.HashKey("zk_alice")): {ID: uuid.New(), Owner: "alice"},
+		string(auth.HashKey("zk_root")):  {ID: uuid.New(), Owner: "ops", Scopes: []string{auth.ScopeAdmin}},
+	}}
+
+	tests := []struct {
+		name      string
+		opts      middleware.AuthOptions
+		header    string
+		value     string
+		wantCode  int
+		wantBody  string
+		wantChall bool
+	}{
+		{"bearer token", middleware.AuthOptions{}, "Authorization", "Bearer zk_alice", http.StatusOK, "alice", false},
+		{"x-api-key header", middleware.AuthOptions{}, "X-API-Key", "zk_alice", http.StatusOK, "alice", false},
+		{"admin scope", middleware.AuthOptions{}, "Authorization", "bearer zk_root", http.StatusOK, "ops (admin)", false},
+		{"missing key", middleware.AuthOptions{}, "", "", http.StatusUnauthorized, "", true},
+		{"anonymous allowed", middleware.AuthOptions{AllowAnonymous: true}, "", "", http.StatusOK, "anonymous", false},
+		{"unknown key even when anonymous allowed", middleware.AuthOptions{AllowAnonymous: true}, "X-API-Key", "zk_nope", http.StatusUnauthorized, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			newAuthRouter(store, tt.opts).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+			assert.Equal(t, tt.wantChall, w.Header().Get("WWW-Authenticate") != "")
+		})
+	}
+}
+
+func TestAuth_StoreErrorFailsClosed(t *testing.T) {
+	store := &fakeKeyStore{err: errors.New("connection refused")}
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("X-API-Key", "zk_alice")
+	w := httptest.NewRecorder()
+
+	newAuthRouter(store, middleware.AuthOptions{AllowAnonymous: true}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// WorkspaceStore looks up workspaces by ID and by registered domain host.
+type WorkspaceStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Workspace, error)
+	GetByHost(ctx context.Context, host string) (*model.Workspace, error)
+}
+
+// defaultWorkspaceCacheTTL bounds how long a workspace change (new domain,
+// quota, alias rules) takes to reach every replica.
+const defaultWorkspaceCacheTTL = time.Minute
+
+// maxWorkspaceCacheEntries caps the lookup cache. Host lookups are keyed by
+// a client-supplied header, so misses must not grow it without bound.
+const maxWorkspaceCacheEntries = 10000
+
+// TenantOptions holds optional configuration for Tenant.
+type TenantOptions struct {
+	CacheTTL time.Duration // how long lookups, including misses, are reused (0 = defaultWorkspaceCacheTTL)
+}
+
+// Tenant resolves the workspace of a request and attaches it to the context
+// with tenant.WithWorkspace. Register it after Auth so the key is known.
+//
+// A request Host registered as a domain selects its workspace and is
+// recorded with tenant.WithDomain; otherwise an authenticated key's own
+// workspace applies. A key used on the domain of a different workspace is
+// rejected with 403, so a tenant's key cannot read or create links in another
+// tenant's namespace. Requests that resolve to nothing stay in the default
+// workspace. Anonymous requests are placed by domain too, which is what
+// redirects need; pair it with RequireKeyOnDomain on the API routes.
+//
+// Lookups are cached in process for CacheTTL because this runs on every
+// redirect. If the store fails the request is rejected with 503 rather than
+// served from the wrong namespace.
+func Tenant(store WorkspaceStore, logger *slog.Logger, opts ...TenantOptions) gin.HandlerFunc {
+	ttl := defaultWorkspaceCacheTTL
+	if len(opts) > 0 && opts[0].CacheTTL > 0 {
+		ttl = opts[0].CacheTTL
+	}
+	cache := &workspaceCache{ttl: ttl, entries: make(map[string]workspaceEntry)}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		host := requestHost(c.Request)
+		ws, err := cache.get(ctx, "host:"+host, func(ctx context.Context) (*model.Workspace, error) {
+			return store.GetByHost(ctx, host)
+		})
+		if err != nil {
+			workspaceUnavailable(c, logger, err)
+			return
+		}
+		onDomain := ws != nil
+
+		if id, ok := auth.FromContext(ctx); ok {
+			switch {
+			case ws != nil && ws.ID != id.WorkspaceID:
+				logger.WarnContext(ctx, "API key used outside its workspace",
+					slog.String("key_id", id.KeyID.String()),
+					slog.String("host", host))
+				c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{
+					Error:   http.StatusText(http.StatusForbidden),
+					Message: "API key does not belong to this workspace",
+				})
+				return
+			case ws == nil && id.WorkspaceID != model.DefaultWorkspaceID:
+				ws, err = cache.get(ctx, "id:"+id.WorkspaceID.String(), func(ctx context.Context) (*model.Workspace, error) {
+					return store.GetByID(ctx, id.WorkspaceID)
+				})
+				if err != nil {
+					workspaceUnavailable(c, logger, err)
+					return
+				}
+			}
+		}
+
+		if ws != nil {
+			ctx = tenant.WithWorkspace(ctx, ws)
+			if onDomain {
+				ctx = tenant.WithDomain(ctx, host)
+			}
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// RequireKeyOnDomain rejects anonymous requests that arrived on a
+// workspace's domain with 401. Register it after Tenant on the API routes:
+// the domain alone is enough to resolve redirects, but it must not let
+// callers without a key list or create links in a branded workspace.
+func RequireKeyOnDomain() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if tenant.Domain(ctx) == "" {
+			c.Next()
+			return
+		}
+		if _, ok := auth.FromContext(ctx); !ok {
+			unauthorized(c, "API key required on workspace domains")
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestHost returns the lower-cased request host without port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+func workspaceUnavailable(c *gin.Context, logger *slog.Logger, err error) {
+	logger.ErrorContext(c.Request.Context(), "workspace lookup failed",
+		slog.String("error", err.Error()))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, model.ErrorResponse{
+		Error:   http.StatusText(http.StatusServiceUnavailable),
+		Message: "Workspace lookup unavailable",
+	})
+}
+
+// workspaceCache memoises lookups for ttl. A nil workspace records a miss.
+type workspaceCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]workspaceEntry
+}
+
+type workspaceEntry struct {
+	ws      *model.Workspace
+	expires time.Time
+}
+
+// get returns the cached result for key or calls load. Not-found results are
+// cached as nil; other errors are returned and not cached.
+func (c *workspaceCache) get(ctx context.Context, key string, load func(context.Context) (*model.Workspace, error)) (*model.Workspace, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.ws, nil
+	}
+
+	ws, err := load(ctx)
+	if err != nil {
+		if !errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return nil, err
+		}
+		ws = nil
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= maxWorkspaceCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxWorkspaceCacheEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = workspaceEntry{ws: ws, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return ws, nil
+}
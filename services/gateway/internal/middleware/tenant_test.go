@@ -0,0 +1,188 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/middleware"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// fakeWorkspaceStore serves workspaces and their domains from memory and
+// counts lookups.
+type fakeWorkspaceStore struct {
+	workspaces []*model.Workspace
+	domains    map[string]*model.Workspace
+	err        error
+	calls      int
+}
+
+func (f *fakeWorkspaceStore) GetByID(_ context.Context, id uuid.UUID) (*model.Workspace, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, ws := range f.workspaces {
+		if ws.ID == id {
+			return ws, nil
+		}
+	}
+	return nil, repository.ErrWorkspaceNotFound
+}
+
+func (f *fakeWorkspaceStore) GetByHost(_ context.Context, host string) (*model.Workspace, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if ws, ok := f.domains[host]; ok {
+		return ws, nil
+	}
+	return nil, repository.ErrWorkspaceNotFound
+}
+
+// newTenantRouter installs a stand-in for Auth that attaches id (if any),
+// followed by Tenant, and reports the resolved workspace slug and, in the
+// X-Domain header, the domain the request arrived on.
+func newTenantRouter(store middleware.WorkspaceStore, id *auth.Identity) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if id != nil {
+			c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), id))
+		}
+	})
+	r.Use(middleware.Tenant(store, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	r.GET("/where", func(c *gin.Context) {
+		c.Header("X-Domain", tenant.Domain(c.Request.Context()))
+		ws, ok := tenant.FromContext(c.Request.Context())
+		if !ok {
+			c.String(http.StatusOK, "default")
+			return
+		}
+		c.String(http.StatusOK, ws.Slug)
+	})
+	return r
+}
+
+func TestTenant(t *testing.T) {
+	acme := &model.Workspace{ID: uuid.New(), Slug: "acme"}
+	beta := &model.Workspace{ID: uuid.New(), Slug: "beta"}
+	store := &fakeWorkspaceStore{
+		workspaces: []*model.Workspace{acme, beta},
+		domains: map[string]*model.Workspace{
+			"go.acme.example": acme,
+			"acme.link":       acme,
+			"beta.example":    beta,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		id         *auth.Identity
+		wantCode   int
+		wantBody   string
+		wantDomain string
+	}{
+		{"domain selects workspace", "go.acme.example", nil, http.StatusOK, "acme", "go.acme.example"},
+		{"second domain of a workspace", "acme.link", nil, http.StatusOK, "acme", "acme.link"},
+		{"domain match ignores port and case", "GO.ACME.example:8080", nil, http.StatusOK, "acme", "go.acme.example"},
+		{"unknown host is the default workspace", "shared.example", nil, http.StatusOK, "default", ""},
+		{"key workspace on shared host", "shared.example", &auth.Identity{WorkspaceID: beta.ID}, http.StatusOK, "beta", ""},
+		{"key on its own domain", "beta.example", &auth.Identity{WorkspaceID: beta.ID}, http.StatusOK, "beta", "beta.example"},
+		{"key on another workspace's domain", "go.acme.example", &auth.Identity{WorkspaceID: beta.ID}, http.StatusForbidden, "", ""},
+		{"default key on a workspace domain", "go.acme.example", &auth.Identity{}, http.StatusForbidden, "", ""},
+		{"default key on shared host", "shared.example", &auth.Identity{}, http.StatusOK, "default", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/where", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			newTenantRouter(store, tt.id).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+				assert.Equal(t, tt.wantDomain, w.Header().Get("X-Domain"))
+			}
+		})
+	}
+}
+
+func TestRequireKeyOnDomain(t *testing.T) {
+	acme := &model.Workspace{ID: uuid.New(), Slug: "acme"}
+	store := &fakeWorkspaceStore{
+		workspaces: []*model.Workspace{acme},
+		domains:    map[string]*model.Workspace{"go.acme.example": acme},
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		id       *auth.Identity
+		wantCode int
+	}{
+		{"anonymous on a workspace domain", "go.acme.example", nil, http.StatusUnauthorized},
+		{"key on its own domain", "go.acme.example", &auth.Identity{WorkspaceID: acme.ID}, http.StatusOK},
+		{"anonymous on shared host", "shared.example", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.id != nil {
+					c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), tt.id))
+				}
+			})
+			r.Use(middleware.Tenant(store, slog.New(slog.NewTextHandler(io.Discard, nil))), middleware.RequireKeyOnDomain())
+			r.GET("/where", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/where", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
+
+func TestTenant_CachesLookups(t *testing.T) {
+	acme := &model.Workspace{ID: uuid.New(), Slug: "acme"}
+	store := &fakeWorkspaceStore{
+		workspaces: []*model.Workspace{acme},
+		domains:    map[string]*model.Workspace{"go.acme.example": acme},
+	}
+	r := newTenantRouter(store, nil)
+
+	for _, host := range []string{"go.acme.example", "go.acme.example", "unknown.example", "unknown.example"} {
+		req := httptest.NewRequest(http.MethodGet, "/where", nil)
+		req.Host = host
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 2, store.calls, "hits and misses must both be cached")
+}
+
+func TestTenant_StoreErrorFailsClosed(t *testing.T) {
+	store := &fakeWorkspaceStore{err: errors.New("connection refused")}
+	req := httptest.NewRequest(http.MethodGet, "/where", nil)
+	w := httptest.NewRecorder()
+
+	newTenantRouter(store, nil).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is the stored metadata of an API key. The key itself is never
+// stored, only its hash.
+type APIKey struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	Owner       string     `db:"owner" json:"owner"`
+	WorkspaceID uuid.UUID  `db:"workspace_id" json:"workspace_id"`
+	Prefix      string     `db:"key_prefix" json:"prefix"`
+	Scopes      []string   `db:"scopes" json:"scopes"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain is a branded host registered to a workspace. Requests arriving on
+// it resolve to that workspace, and links created on it get short URLs on
+// it.
+type Domain struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	Host        string    `db:"host" json:"host"` // lower-case, no port
+	WorkspaceID uuid.UUID `db:"workspace_id" json:"workspace_id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// CreateDomainRequest represents the request body for registering a domain.
+type CreateDomainRequest struct {
+	Host string `json:"host" binding:"required"`
+}
+
+// ListDomainsResponse represents the domains of the caller's workspace.
+type ListDomainsResponse struct {
+	Domains []*Domain `json:"domains"`
+}
@@ -0,0 +1,21 @@
+package model
+
+// RedirectRule sends visitors matching all of its conditions to URL instead
+// of the link's destination. Each condition lists alternatives compared
+// case-insensitively; a condition left empty matches every visitor. A
+// link's rules are tried in order and the first match wins.
+type RedirectRule struct {
+	Browser  []string `json:"browser,omitempty"`  // User-Agent family: chrome, edge, firefox, safari, opera, samsung, bot, other
+	OS       []string `json:"os,omitempty"`       // ios, android, windows, macos, linux, chromeos, other
+	Language []string `json:"language,omitempty"` // visitor's preferred language; "de" also matches "de-AT"
+	Country  []string `json:"country,omitempty"`  // ISO 3166-1 alpha-2 code of the visitor's IP, e.g. "US"
+	URL      string   `json:"url"`
+}
+
+// Visitor is what redirect rules are matched against.
+type Visitor struct {
+	Browser  string // User-Agent family, one of the RedirectRule.Browser values
+	OS       string // one of the RedirectRule.OS values
+	Language string // most preferred Accept-Language tag, lower case
+	Country  string // ISO 3166-1 alpha-2 code, upper case; empty when unknown
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Histogram bucket sizes accepted by the stats endpoint.
+const (
+	StatsBucketMinute = "minute"
+	StatsBucketHour   = "hour"
+	StatsBucketDay    = "day"
+)
+
+// StatsRequest holds the query parameters for GET /api/v1/urls/:code/stats.
+// All fields are optional; the service fills in defaults based on Bucket.
+type StatsRequest struct {
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Bucket string    `form:"bucket"`
+	Top    int       `form:"top"` // number of referers to return
+}
+
+// StatsFilter is the validated, bucket-aligned query passed to the stats repository.
+// The range is half-open: From <= clicked_at < To.
+type StatsFilter struct {
+	ShortCode string
+	From      time.Time
+	To        time.Time
+	Bucket    string
+	TopN      int
+}
+
+// ClickStats summarises the clicks recorded for one short URL over a time range.
+// For hour and day buckets UniqueIPs is an estimate merged from the rollups.
+type ClickStats struct {
+	ShortCode   string         `json:"short_code"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Bucket      string         `json:"bucket"`
+	TotalClicks int64          `json:"total_clicks"`
+	UniqueIPs   int64          `json:"unique_ips"`
+	Series      []StatsBucket  `json:"series"`
+	TopReferers []RefererCount `json:"top_referers"`
+	// Variants counts clicks per A/B variant, most clicked first; omitted
+	// when no click in the range was sent to a variant.
+	Variants []VariantCount `json:"variants,omitempty"`
+	// Campaigns counts clicks per combination of UTM tags the link carried
+	// when clicked, most clicked first and at most Top of them; omitted
+	// when no click in the range was tagged.
+	Campaigns []CampaignCount `json:"campaigns,omitempty"`
+}
+
+// StatsBucket is one histogram point; Start is the UTC start of the bucket.
+type StatsBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// RefererCount is the number of clicks from one referer. Hour and day
+// buckets report the referer's host, minute buckets the full URL.
+// Clicks without a Referer header are reported as an empty string.
+type RefererCount struct {
+	Referer string `json:"referer"`
+	Clicks  int64  `json:"clicks"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// TrackingParams are campaign tags added to a link's destination on every
+// redirect. They are kept apart from OriginalURL so a campaign can be
+// retagged without editing the destination, and they win over parameters
+// of the same name already in it.
+type TrackingParams struct {
+	Source   string            `json:"utm_source,omitempty"`
+	Medium   string            `json:"utm_medium,omitempty"`
+	Campaign string            `json:"utm_campaign,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"` // any other parameters, e.g. utm_content or ref
+}
+
+// IsZero reports whether t adds no parameters.
+func (t *TrackingParams) IsZero() bool {
+	return t == nil || (t.Source == "" && t.Medium == "" && t.Campaign == "" && len(t.Extra) == 0)
+}
+
+// CampaignCount is the number of clicks redirected with one combination of
+// UTM tags.
+type CampaignCount struct {
+	Source   string `json:"utm_source"`
+	Medium   string `json:"utm_medium"`
+	Campaign string `json:"utm_campaign"`
+	Clicks   int64  `json:"clicks"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,14 +14,115 @@ type URL struct {
 	OriginalURL string     `db:"original_url" json:"original_url"`
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
 	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	NotBefore   *time.Time `db:"not_before" json:"not_before,omitempty"` // activation time; visits before it are refused
 	ClickCount  int64      `db:"click_count" json:"click_count"`
+	Owner       string     `db:"owner" json:"owner,omitempty"` // API key owner; empty for anonymous links
+	WorkspaceID uuid.UUID  `db:"workspace_id" json:"workspace_id"`
+	Domain      string     `db:"domain" json:"domain,omitempty"` // branded host the link is served on; empty = any host of the workspace
+	// RedirectType is the HTTP status of the redirect: 301, 302, 307 or 308
+	// (0 = server default).
+	RedirectType int  `db:"redirect_type" json:"redirect_type,omitempty"`
+	ForwardQuery bool `db:"forward_query" json:"forward_query,omitempty"` // merge the visitor's query string into the destination
+	// PasswordHash is the bcrypt hash visitors' passwords are checked against
+	// (empty = public link). It is cached with the URL but never returned by
+	// the API.
+	PasswordHash string `db:"password_hash" json:"password_hash,omitempty"`
+	// MaxClicks caps how many visits the link redirects (0 = unlimited).
+	// Uses counts the visits granted so far as of when the row was read;
+	// the live counter is kept by the repository.
+	MaxClicks int64 `db:"max_clicks" json:"max_clicks,omitempty"`
+	Uses      int64 `db:"uses" json:"uses,omitempty"`
+	// FallbackURL is where visitors are sent while the link cannot redirect
+	// to OriginalURL: before NotBefore, after expiry or once MaxClicks are
+	// used up (empty = the workspace's fallback, else an error response).
+	FallbackURL string `db:"fallback_url" json:"fallback_url,omitempty"`
+	// Rules route matching visitors elsewhere, e.g. by OS to an app store.
+	Rules []RedirectRule `db:"rules" json:"rules,omitempty"`
+	// Variants split visitors the rules do not route between weighted
+	// destinations (none = always OriginalURL). With StickyVariants a
+	// visitor keeps their variant across visits.
+	Variants       []Variant `db:"variants" json:"variants,omitempty"`
+	StickyVariants bool      `db:"sticky_variants" json:"sticky_variants,omitempty"`
+	// Tracking tags every redirect's destination with UTM and other
+	// parameters (nil = none).
+	Tracking *TrackingParams `db:"tracking" json:"tracking,omitempty"`
+	// CanonicalHash identifies the canonical destination of links with a
+	// generated code, which reuse_existing requests may be given. It is
+	// written on create but not read back.
+	CanonicalHash []byte `db:"canonical_hash" json:"-"`
 }
 
-// CreateURLRequest represents the request body for creating a short URL
+// CreateURLRequest represents the request body for creating a short URL.
+// At most one of ExpiresIn, ExpiresAt and ExpiresAfter may be set; when none
+// is, the server-wide default expiry applies. NotBefore, if set, must come
+// before the expiry.
 type CreateURLRequest struct {
-	URL         string `json:"url" binding:"required,url"`
-	CustomAlias string `json:"custom_alias,omitempty"`
-	ExpiresIn   int    `json:"expires_in,omitempty"` // Duration in days (0 = never expires)
+	URL          string         `json:"url" binding:"required,url"`
+	CustomAlias  string         `json:"custom_alias,omitempty"`
+	ExpiresIn    int            `json:"expires_in,omitempty"`    // Duration in days
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`    // Absolute RFC3339 timestamp
+	ExpiresAfter string         `json:"expires_after,omitempty"` // Go duration string, e.g. "36h" or "90m"
+	Domain       string         `json:"domain,omitempty"`        // registered branded host; defaults to the request's domain
+	RedirectType int            `json:"redirect_type,omitempty"` // 301, 302, 307 or 308; server default when omitted
+	ForwardQuery bool           `json:"forward_query,omitempty"` // pass the visitor's query string on to the destination
+	Password     string         `json:"password,omitempty"`      // visitors must enter it before being redirected
+	MaxClicks    int64          `json:"max_clicks,omitempty"`    // redirects allowed before the link is gone; 1 makes a one-time link
+	NotBefore    *time.Time     `json:"not_before,omitempty"`    // RFC3339 activation time; the link does not redirect before it
+	FallbackURL  string         `json:"fallback_url,omitempty" binding:"omitempty,url"`
+	Rules        []RedirectRule `json:"rules,omitempty"` // per-visitor destinations, first match wins
+	// Variants split the remaining visitors between weighted destinations
+	// for A/B tests; StickyVariants keeps each visitor on one of them.
+	Variants       []Variant `json:"variants,omitempty"`
+	StickyVariants bool      `json:"sticky_variants,omitempty"`
+	// Tracking adds UTM tags to the destination at redirect time.
+	Tracking *TrackingParams `json:"tracking,omitempty"`
+	// ReuseExisting returns the caller's existing, unexpired link to the
+	// same canonical URL instead of creating another. It only applies to
+	// requests that set nothing but URL and Domain: a custom alias or any
+	// other option needs a link of its own.
+	ReuseExisting bool `json:"reuse_existing,omitempty"`
+}
+
+// UpdateURLRequest represents the request body for changing an existing short URL.
+// Omitted fields are left unchanged; ClearExpiry removes any expiry so the link never expires
+// and ClearNotBefore activates it immediately.
+// A RedirectType of 0 returns the link to the server default, an empty
+// Password makes the link public again, an empty FallbackURL removes it and
+// an empty Rules or Variants list removes all rules or variants. A Tracking
+// object replaces the link's tags as a whole; an empty one removes them.
+type UpdateURLRequest struct {
+	URL            *string         `json:"url,omitempty" binding:"omitempty,url"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	ClearExpiry    bool            `json:"clear_expiry,omitempty"`
+	RedirectType   *int            `json:"redirect_type,omitempty"`
+	ForwardQuery   *bool           `json:"forward_query,omitempty"`
+	Password       *string         `json:"password,omitempty"`
+	NotBefore      *time.Time      `json:"not_before,omitempty"`
+	ClearNotBefore bool            `json:"clear_not_before,omitempty"`
+	FallbackURL    *string         `json:"fallback_url,omitempty"`
+	Rules          *[]RedirectRule `json:"rules,omitempty"`
+	Variants       *[]Variant      `json:"variants,omitempty"`
+	StickyVariants *bool           `json:"sticky_variants,omitempty"`
+	Tracking       *TrackingParams `json:"tracking,omitempty"`
+}
+
+// URLUpdate holds the validated column changes passed to the repository.
+// Nil fields are left untouched.
+type URLUpdate struct {
+	OriginalURL    *string
+	ExpiresAt      *time.Time
+	ClearExpiry    bool
+	RedirectType   *int // 0 clears the per-link status
+	ForwardQuery   *bool
+	PasswordHash   *string // "" removes the password
+	NotBefore      *time.Time
+	ClearNotBefore bool
+	FallbackURL    *string         // "" removes the fallback
+	Rules          *[]RedirectRule // empty removes all rules
+	Variants       *[]Variant      // empty removes all variants
+	StickyVariants *bool
+	Tracking       *TrackingParams // empty removes the tags
+	CanonicalHash  []byte          // follows OriginalURL; links without a hash keep none
 }
 
 // CreateURLResponse represents the response for a created short URL
@@ -28,18 +130,146 @@ type CreateURLResponse struct {
 	ShortCode string `json:"short_code"`
 	ShortURL  string `json:"short_url"`
 	ExpiresAt string `json:"expires_at,omitempty"`
+	Reused    bool   `json:"reused,omitempty"` // an existing link was returned for reuse_existing
+}
+
+// BatchCreateURLResponse represents the outcome of a bulk shorten request.
+// Results are in the same order as the submitted items. Created counts new
+// links only; items answered with an existing link count as Reused.
+type BatchCreateURLResponse struct {
+	Results []BatchCreateURLResult `json:"results"`
+	Created int                    `json:"created"`
+	Reused  int                    `json:"reused"`
+	Failed  int                    `json:"failed"`
+}
+
+// BatchCreateURLResult holds either the created short URL or the error for one item.
+// Status is the HTTP status the item would have received from POST /api/v1/shorten.
+type BatchCreateURLResult struct {
+	Index  int                `json:"index"`
+	Status int                `json:"status"`
+	URL    *CreateURLResponse `json:"url,omitempty"`
+	Error  *ErrorResponse     `json:"error,omitempty"`
 }
 
 // URLResponse represents the full URL metadata response
 type URLResponse struct {
-	ShortCode   string `json:"short_code"`
-	OriginalURL string `json:"original_url"`
-	ShortURL    string `json:"short_url"`
-	CreatedAt   string `json:"created_at"`
-	ExpiresAt   string `json:"expires_at,omitempty"`
-	ClickCount  int64  `json:"click_count"`
+	ShortCode    string         `json:"short_code"`
+	OriginalURL  string         `json:"original_url"`
+	ShortURL     string         `json:"short_url"`
+	CreatedAt    string         `json:"created_at"`
+	ExpiresAt    string         `json:"expires_at,omitempty"`
+	NotBefore    string         `json:"not_before,omitempty"`
+	ClickCount   int64          `json:"click_count"`
+	Owner        string         `json:"owner,omitempty"`
+	Domain       string         `json:"domain,omitempty"`
+	RedirectType int            `json:"redirect_type"` // effective status, the server default when the link sets none
+	ForwardQuery bool           `json:"forward_query"`
+	Protected    bool           `json:"password_protected"`
+	MaxClicks    int64          `json:"max_clicks,omitempty"`
+	FallbackURL  string         `json:"fallback_url,omitempty"`
+	Rules        []RedirectRule `json:"rules,omitempty"`
+	Variants     []Variant      `json:"variants,omitempty"`
+	// StickyVariants is reported only for links with variants.
+	StickyVariants bool            `json:"sticky_variants,omitempty"`
+	Tracking       *TrackingParams `json:"tracking,omitempty"`
+	// RemainingClicks is how many more visits will redirect; omitted for
+	// links without a click limit.
+	RemainingClicks *int64 `json:"remaining_clicks,omitempty"`
+}
+
+// RedirectRequest describes a visit to a short link.
+type RedirectRequest struct {
+	Code   string
+	Query  url.Values // the visitor's query string
+	Unlock string     // unlock token from an earlier password submission, if any
+
+	UserAgent      string // matched against the link's rules
+	AcceptLanguage string
+	Country        string // visitor's country from GeoIP; empty when unknown
+	Variant        string // variant assigned on an earlier visit, from the sticky cookie
+}
+
+// UnlockRequest is a visitor's password submission for a protected link.
+type UnlockRequest struct {
+	Code     string
+	Password string
+}
+
+// UnlockGrant lets the visitor follow a protected link without re-entering
+// the password until ExpiresAt.
+type UnlockGrant struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// RedirectTarget is where a visit is sent and with which status.
+type RedirectTarget struct {
+	URL     string
+	Status  int
+	NoStore bool   // forbid caching the redirect, so it cannot outlive an unlock token or a click limit
+	Vary    string // request headers the destination depends on, for the Vary header
+	// Variant names the A/B variant the visit was sent to (empty when the
+	// link has none or a rule matched); Sticky asks the caller to remember
+	// it for the visitor's next visit.
+	Variant string
+	Sticky  bool
+	// Tracking holds the tags added to URL, for the click event.
+	Tracking *TrackingParams
+}
+
+// ListURLsRequest represents the query parameters for listing short URLs.
+// Cursor is the opaque NextCursor value returned by the previous page.
+type ListURLsRequest struct {
+	Cursor        string    `form:"cursor"`
+	Limit         int       `form:"limit"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Status        string    `form:"status"` // "active", "scheduled", "expired" or empty for all
+	Query         string    `form:"q"`      // substring match on original_url
+	SortBy        string    `form:"sort"`   // "created_at" (default) or "click_count"
+	Order         string    `form:"order"`  // "desc" (default) or "asc"
+}
+
+// ListURLsResponse represents one page of URL metadata.
+// NextCursor is empty when there are no more results.
+type ListURLsResponse struct {
+	URLs       []URLResponse `json:"urls"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
 
+// URLFilter holds the decoded listing criteria passed to the repository.
+type URLFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Status        string
+	Query         string
+	Owner         string // only links created by this owner; "" lists every owner
+	SortBy        string
+	Descending    bool
+	After         *URLCursor // keyset position of the last row on the previous page
+	Limit         int
+}
+
+// URLCursor identifies a row's position in a sorted listing.
+// ID breaks ties between rows sharing the same sort value.
+type URLCursor struct {
+	CreatedAt  time.Time `json:"c"`
+	ClickCount int64     `json:"n"`
+	ID         uuid.UUID `json:"i"`
+}
+
+// Listing sort keys and status filters. Scheduled links have a not_before
+// in the future; active ones have neither that nor an expiry in the past.
+const (
+	SortByCreatedAt  = "created_at"
+	SortByClickCount = "click_count"
+
+	StatusActive    = "active"
+	StatusScheduled = "scheduled"
+	StatusExpired   = "expired"
+)
+
 // ErrorResponse represents an API error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -0,0 +1,16 @@
+package model
+
+// Variant is one destination of an A/B split link. Each visit picks a
+// variant at random in proportion to its Weight; with sticky assignment a
+// returning visitor keeps the variant picked on the first visit.
+type Variant struct {
+	Name   string `json:"name"` // identifies the variant in click stats and the sticky cookie
+	URL    string `json:"url"`
+	Weight int    `json:"weight"` // relative share of visits, 1-10000
+}
+
+// VariantCount is the number of clicks sent to one variant.
+type VariantCount struct {
+	Variant string `json:"variant"`
+	Clicks  int64  `json:"clicks"`
+}
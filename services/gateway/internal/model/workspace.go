@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultWorkspaceID identifies the default workspace, which owns every link
+// created on a request that resolved to no tenant.
+var DefaultWorkspaceID = uuid.Nil
+
+// Workspace is a tenant with its own short-code namespace. Zero-valued
+// settings inherit the server configuration.
+type Workspace struct {
+	ID              uuid.UUID `db:"id" json:"id"`
+	Slug            string    `db:"slug" json:"slug"`
+	Name            string    `db:"name" json:"name"`
+	BaseURL         string    `db:"base_url" json:"base_url,omitempty"` // prefix of short URLs for links without a domain
+	AliasMinLen     int       `db:"alias_min_length" json:"alias_min_length,omitempty"`
+	AliasMaxLen     int       `db:"alias_max_length" json:"alias_max_length,omitempty"`
+	AliasPattern    string    `db:"alias_pattern" json:"alias_pattern,omitempty"`
+	ReservedAliases []string  `db:"reserved_aliases" json:"reserved_aliases,omitempty"` // in addition to the server's
+	MaxLinks        int64     `db:"max_links" json:"max_links,omitempty"`               // 0 = unlimited
+	FallbackURL     string    `db:"fallback_url" json:"fallback_url,omitempty"`         // for dead links without a fallback_url of their own
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+// HasAliasOverrides reports whether the workspace changes the server's custom
+// alias rules.
+func (w *Workspace) HasAliasOverrides() bool {
+	return w.AliasMinLen > 0 || w.AliasMaxLen > 0 || w.AliasPattern != "" || len(w.ReservedAliases) > 0
+}
+
+// LinkRef identifies a link across workspaces.
+type LinkRef struct {
+	WorkspaceID uuid.UUID
+	ShortCode   string
+}
@@ -0,0 +1,202 @@
+package reaper
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// advisoryLockKey identifies the reaper's Postgres advisory lock.
+// Any constant works as long as no other subsystem uses the same value.
+const advisoryLockKey int64 = 0x7572_6c72_6561_7072 // "urlreapr"
+
+// Store deletes one batch of expired links, across all workspaces, and
+// returns references to them. repository.CachedURLRepository implements it
+// and evicts the cache entries.
+type Store interface {
+	DeleteExpired(ctx context.Context, before time.Time, limit int) ([]model.LinkRef, error)
+}
+
+// Locker grants exclusive leadership for one run across gateway replicas.
+// TryLock returns ok=false without error when another holder has it.
+type Locker interface {
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
+
+// Config controls how often and how much the reaper deletes.
+type Config struct {
+	Interval   time.Duration // time between runs
+	BatchSize  int           // rows deleted per statement
+	MaxBatches int           // batches per run; bounds lock hold time and DB load
+	Grace      time.Duration // links expired for less than this are kept (still answer 410)
+}
+
+// Reaper periodically deletes expired links in bounded batches.
+// Only the replica holding the advisory lock does work on a given tick.
+type Reaper struct {
+	store  Store
+	locker Locker
+	cfg    Config
+	logger *slog.Logger
+
+	reaped      metric.Int64Counter
+	runs        metric.Int64Counter
+	runDuration metric.Float64Histogram
+}
+
+// New creates a reaper. Zero BatchSize and MaxBatches fall back to 500 and 20.
+func New(store Store, locker Locker, cfg Config, logger *slog.Logger) *Reaper {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxBatches <= 0 {
+		cfg.MaxBatches = 20
+	}
+
+	r := &Reaper{store: store, locker: locker, cfg: cfg, logger: logger}
+
+	meter := otel.Meter("gateway/reaper")
+	r.reaped, _ = meter.Int64Counter("reaper_deleted_total",
+		metric.WithDescription("Total expired links deleted by the reaper"),
+	)
+	r.runs, _ = meter.Int64Counter("reaper_runs_total",
+		metric.WithDescription("Reaper runs by result (completed, skipped, error)"),
+	)
+	r.runDuration, _ = meter.Float64Histogram("reaper_run_duration_seconds",
+		metric.WithDescription("Duration of reaper runs that held the lock"),
+		metric.WithUnit("s"),
+	)
+	return r
+}
+
+// Run calls RunOnce every Interval until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context) {
+	r.logger.Info("expiry reaper started",
+		slog.Duration("interval", r.cfg.Interval),
+		slog.Int("batch_size", r.cfg.BatchSize),
+		slog.Duration("grace", r.cfg.Grace))
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("expiry reaper stopped")
+			return
+		case <-ticker.C:
+			r.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce deletes up to MaxBatches batches of links that expired before
+// now-Grace and returns how many were deleted. It stops early once a batch
+// comes back short, and returns 0 without error when another replica holds the lock.
+func (r *Reaper) RunOnce(ctx context.Context) (int, error) {
+	unlock, ok, err := r.locker.TryLock(ctx)
+	if err != nil {
+		r.runs.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "error")))
+		r.logger.Error("reaper failed to acquire lock",
+			slog.String("error", err.Error()))
+		return 0, err
+	}
+	if !ok {
+		r.runs.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "skipped")))
+		r.logger.Debug("reaper lock held by another replica, skipping run")
+		return 0, nil
+	}
+	defer unlock()
+
+	start := time.Now()
+	cutoff := start.Add(-r.cfg.Grace)
+	total := 0
+	defer func() {
+		r.runDuration.Record(ctx, time.Since(start).Seconds())
+	}()
+
+	for batch := 0; batch < r.cfg.MaxBatches; batch++ {
+		if ctx.Err() != nil {
+			break
+		}
+		refs, err := r.store.DeleteExpired(ctx, cutoff, r.cfg.BatchSize)
+		if err != nil {
+			r.runs.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "error")))
+			r.logger.Error("reaper batch failed",
+				slog.String("error", err.Error()),
+				slog.Int("batch", batch+1),
+				slog.Int("deleted", total))
+			return total, err
+		}
+		total += len(refs)
+		r.reaped.Add(ctx, int64(len(refs)))
+		if len(refs) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	r.runs.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "completed")))
+	if total > 0 {
+		r.logger.Info("reaped expired links",
+			slog.Int("deleted", total),
+			slog.Time("cutoff", cutoff),
+			slog.Duration("duration", time.Since(start)))
+	}
+	return total, nil
+}
+
+// PGLocker implements Locker with a session-level Postgres advisory lock.
+// The lock lives on one pooled connection, which is held until unlock so the
+// lock cannot leak to an unrelated caller; if the process dies, Postgres
+// releases it when the connection drops.
+type PGLocker struct {
+	pool *pgxpool.Pool
+	key  int64
+}
+
+// NewPGLocker creates a locker for the reaper's advisory lock key.
+func NewPGLocker(pool *pgxpool.Pool) *PGLocker {
+	return &PGLocker{pool: pool, key: advisoryLockKey}
+}
+
+// NewPGLockerKey creates a locker for another background job's advisory
+// lock key, such as keypool.LockKey.
+func NewPGLockerKey(pool *pgxpool.Pool, key int64) *PGLocker {
+	return &PGLocker{pool: pool, key: key}
+}
+
+// TryLock attempts pg_try_advisory_lock without blocking.
+func (l *PGLocker) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var ok bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Use a fresh context: the run's context may already be cancelled.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+			// Drop the connection so the session, and with it the lock, ends.
+			conn.Conn().Close(unlockCtx)
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,135 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// fakeStore returns queued batches in order and records each call's cutoff.
+type fakeStore struct {
+	batches [][]string
+	err     error
+	cutoffs []time.Time
+	limits  []int
+}
+
+func (s *fakeStore) DeleteExpired(_ context.Context, before time.Time, limit int) ([]model.LinkRef, error) {
+	s.cutoffs = append(s.cutoffs, before)
+	s.limits = append(s.limits, limit)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.batches) == 0 {
+		return nil, nil
+	}
+	batch := s.batches[0]
+	s.batches = s.batches[1:]
+	refs := make([]model.LinkRef, len(batch))
+	for i, code := range batch {
+		refs[i] = model.LinkRef{ShortCode: code}
+	}
+	return refs, nil
+}
+
+type fakeLocker struct {
+	held     bool
+	err      error
+	unlocked int
+}
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.unlocked++ }, true, nil
+}
+
+func TestReaper_RunOnce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("deletes batches until one comes back short", func(t *testing.T) {
+		store := &fakeStore{batches: [][]string{{"a", "b"}, {"c", "d"}, {"e"}, {"never"}}}
+		locker := &fakeLocker{}
+		r := New(store, locker, Config{BatchSize: 2, MaxBatches: 10, Grace: time.Hour}, newTestLogger())
+
+		n, err := r.RunOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 5, n)
+		assert.Len(t, store.cutoffs, 3)
+		assert.Equal(t, []int{2, 2, 2}, store.limits)
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), store.cutoffs[0], time.Second)
+		assert.Equal(t, 1, locker.unlocked)
+	})
+
+	t.Run("stops after MaxBatches", func(t *testing.T) {
+		store := &fakeStore{batches: [][]string{{"a"}, {"b"}, {"c"}}}
+		r := New(store, &fakeLocker{}, Config{BatchSize: 1, MaxBatches: 2}, newTestLogger())
+
+		n, err := r.RunOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Len(t, store.batches, 1, "third batch must wait for the next run")
+	})
+
+	t.Run("skips when another replica holds the lock", func(t *testing.T) {
+		store := &fakeStore{batches: [][]string{{"a"}}}
+		r := New(store, &fakeLocker{held: true}, Config{}, newTestLogger())
+
+		n, err := r.RunOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Empty(t, store.cutoffs, "store must not be touched without the lock")
+	})
+
+	t.Run("returns lock errors", func(t *testing.T) {
+		r := New(&fakeStore{}, &fakeLocker{err: errors.New("db down")}, Config{}, newTestLogger())
+
+		_, err := r.RunOnce(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("releases the lock when a batch fails", func(t *testing.T) {
+		locker := &fakeLocker{}
+		r := New(&fakeStore{err: errors.New("db down")}, locker, Config{}, newTestLogger())
+
+		_, err := r.RunOnce(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, 1, locker.unlocked)
+	})
+}
+
+func TestReaper_Run_StopsOnCancel(t *testing.T) {
+	store := &fakeStore{}
+	r := New(store, &fakeLocker{}, Config{Interval: 10 * time.Millisecond}, newTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
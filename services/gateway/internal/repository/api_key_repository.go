@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrAPIKeyNotFound is returned when no active key matches.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository handles database operations for API keys.
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, owner, workspace_id, key_prefix, scopes, created_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Owner, &k.WorkspaceID, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Create stores a new key under hash and fills in its ID and CreatedAt.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey, hash []byte) error {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", "api_keys"),
+		),
+	)
+	defer span.End()
+
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO api_keys (name, owner, workspace_id, key_hash, key_prefix, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		key.Name, key.Owner, key.WorkspaceID, hash, key.Prefix, key.Scopes,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// GetByHash returns the active (non-revoked) key stored under hash.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash []byte) (*model.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "api_keys"),
+		),
+	)
+	defer span.End()
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// Revoke marks the key as revoked. Revoking an already revoked key is a no-op;
+// an unknown ID returns ErrAPIKeyNotFound.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "api_keys"),
+		),
+	)
+	defer span.End()
+
+	result, err := r.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// List returns all keys, newest first, including revoked ones.
+func (r *APIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "api_keys"),
+		),
+	)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	repo := NewAPIKeyRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	hash := []byte("0123456789abcdef0123456789abcdef")
+	key := &model.APIKey{Name: "ci", Owner: "team-a", Prefix: "zk_01234567", Scopes: []string{"admin"}}
+	require.NoError(t, repo.Create(ctx, key, hash))
+	assert.NotEqual(t, uuid.Nil, key.ID)
+
+	t.Run("looks up active keys by hash", func(t *testing.T) {
+		got, err := repo.GetByHash(ctx, hash)
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, got.ID)
+		assert.Equal(t, "team-a", got.Owner)
+		assert.True(t, got.HasScope("admin"))
+	})
+
+	t.Run("unknown hash is not found", func(t *testing.T) {
+		_, err := repo.GetByHash(ctx, []byte("nope"))
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	})
+
+	t.Run("revoked keys no longer authenticate", func(t *testing.T) {
+		require.NoError(t, repo.Revoke(ctx, key.ID))
+		_, err := repo.GetByHash(ctx, hash)
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+		keys, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.NotNil(t, keys[0].RevokedAt)
+
+		assert.ErrorIs(t, repo.Revoke(ctx, uuid.New()), ErrAPIKeyNotFound)
+	})
+}
+
+func TestURLRepository_Owner(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	owned := &model.URL{ID: uuid.New(), ShortCode: "owned1", OriginalURL: "https://example.com", Owner: "team-a"}
+	anon := &model.URL{ID: uuid.New(), ShortCode: "anon1", OriginalURL: "https://example.com"}
+	require.NoError(t, repo.Create(ctx, owned))
+	require.NoError(t, repo.Create(ctx, anon))
+
+	got, err := repo.GetByCode(ctx, "owned1")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", got.Owner)
+
+	got, err = repo.GetByCode(ctx, "anon1")
+	require.NoError(t, err)
+	assert.Empty(t, got.Owner)
+}
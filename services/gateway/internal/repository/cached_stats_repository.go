@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
+	"github.com/zhejian/url-shortener/gateway/internal/cache"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+// StatsRepositoryInterface defines the contract for click statistics queries.
+type StatsRepositoryInterface interface {
+	GetStats(ctx context.Context, filter model.StatsFilter) (*model.ClickStats, error)
+}
+
+// CachedStatsRepository caches computed click statistics in Redis so dashboards
+// polling the same range do not re-aggregate the analytics table each time.
+// Entries are keyed by the exact filter; callers should align ranges to bucket
+// boundaries so repeated requests share a key. The TTL bounds staleness.
+type CachedStatsRepository struct {
+	db              StatsRepositoryInterface
+	cache           cache.ClientProvider
+	ttl             time.Duration
+	requestGroup    *singleflight.Group
+	cacheCB         *gobreaker.CircuitBreaker
+	cacheTimeout    time.Duration
+	logger          *slog.Logger
+	cacheHits       metric.Int64Counter
+	cacheMisses     metric.Int64Counter
+	dbQueryDuration metric.Float64Histogram
+	totalErrors     metric.Int64Counter
+}
+
+// defaultStatsCacheTTL applies when no TTL is configured; a zero TTL would
+// make Redis keep stats entries forever.
+const defaultStatsCacheTTL = time.Minute
+
+// CachedStatsRepositoryOptions holds optional configuration.
+type CachedStatsRepositoryOptions struct {
+	CacheCB *CBSettings
+}
+
+// NewCachedStatsRepository creates a new cached stats repository.
+// A nil cache disables caching; every call then goes to the database.
+// A non-positive ttl falls back to defaultStatsCacheTTL.
+func NewCachedStatsRepository(db StatsRepositoryInterface, cache cache.ClientProvider, ttl time.Duration, logger *slog.Logger, opts ...CachedStatsRepositoryOptions) *CachedStatsRepository {
+	cb := DefaultCBSettings()
+	if len(opts) > 0 && opts[0].CacheCB != nil {
+		cb = *opts[0].CacheCB
+	}
+	if ttl <= 0 {
+		ttl = defaultStatsCacheTTL
+	}
+
+	repo := &CachedStatsRepository{
+		db:           db,
+		cache:        cache,
+		ttl:          ttl,
+		requestGroup: &singleflight.Group{},
+		cacheCB:      newCacheBreaker("redis-stats", cb, logger),
+		cacheTimeout: cb.OperationTimeout,
+		logger:       logger,
+	}
+
+	meter := otel.Meter("gateway/repository")
+	repo.cacheHits, _ = meter.Int64Counter("cache_hits_total",
+		metric.WithDescription("Total cache hits"),
+	)
+	repo.cacheMisses, _ = meter.Int64Counter("cache_misses_total",
+		metric.WithDescription("Total cache misses"),
+	)
+	repo.dbQueryDuration, _ = meter.Float64Histogram("db_query_duration_seconds",
+		metric.WithDescription("Database query duration in seconds"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0),
+	)
+	repo.totalErrors, _ = meter.Int64Counter("errors_total",
+		metric.WithDescription("Total errors by type"),
+	)
+	return repo
+}
+
+// GetStats returns cached stats for filter, computing and caching them on a miss.
+// Concurrent misses for the same key share one database query.
+func (r *CachedStatsRepository) GetStats(ctx context.Context, filter model.StatsFilter) (*model.ClickStats, error) {
+	// Like URL entries, only non-default workspaces add their ID to the key.
+	prefix := "stats:"
+	if ws := tenant.WorkspaceID(ctx); ws != model.DefaultWorkspaceID {
+		prefix += ws.String() + ":"
+	}
+	cacheKey := fmt.Sprintf("%s%s:%s:%d:%d:%d", prefix,
+		filter.ShortCode, filter.Bucket, filter.From.Unix(), filter.To.Unix(), filter.TopN)
+
+	if r.cache != nil {
+		node := r.cache.NodeFor(cacheKey)
+		attrs := metric.WithAttributes(
+			attribute.String("cache.node", node),
+			attribute.String("cache.entity", "stats"),
+		)
+		ctx, span := tracer.Start(ctx, "cache.get",
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", "GET"),
+				attribute.String("cache.key", cacheKey),
+				attribute.String("cache.node", node),
+			),
+		)
+		if stats, ok := r.cacheGet(ctx, cacheKey); ok {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			r.cacheHits.Add(ctx, 1, attrs)
+			span.End()
+			return stats, nil
+		}
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		r.cacheMisses.Add(ctx, 1, attrs)
+		span.End()
+	}
+
+	res, err, _ := r.requestGroup.Do(cacheKey, func() (interface{}, error) {
+		// Detached so one caller's cancellation does not fail the others.
+		dbCtx := context.WithoutCancel(ctx)
+		dbStart := time.Now()
+		stats, err := r.db.GetStats(dbCtx, filter)
+		r.dbQueryDuration.Record(dbCtx, time.Since(dbStart).Seconds(),
+			metric.WithAttributes(attribute.String("operation", "SELECT_STATS")),
+		)
+		if err != nil {
+			r.totalErrors.Add(dbCtx, 1, metric.WithAttributes(attribute.String("type", "db_stats")))
+			return nil, err
+		}
+		if r.cache != nil {
+			r.cacheSet(dbCtx, cacheKey, stats)
+		}
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats, ok := res.(*model.ClickStats)
+	if !ok {
+		return nil, errors.New("unexpected type from singleflight")
+	}
+	return stats, nil
+}
+
+// cacheGet returns the decoded entry, or ok=false on a miss or any cache failure.
+func (r *CachedStatsRepository) cacheGet(ctx context.Context, key string) (*model.ClickStats, bool) {
+	cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+	defer cancel()
+
+	res, err := r.cacheCB.Execute(func() (interface{}, error) {
+		return r.cache.ClientFor(key).Get(cacheCtx, key).Bytes()
+	})
+	if err != nil {
+		if err != redis.Nil && !errors.Is(err, gobreaker.ErrOpenState) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_read")))
+			r.logger.Error("cache read error",
+				slog.Any("error", err),
+				slog.String("key", key))
+		}
+		return nil, false
+	}
+
+	var stats model.ClickStats
+	if err := json.Unmarshal(res.([]byte), &stats); err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_deserialization")))
+		r.logger.Error("cache deserialization error",
+			slog.Any("error", err),
+			slog.String("key", key))
+		return nil, false
+	}
+	return &stats, true
+}
+
+func (r *CachedStatsRepository) cacheSet(ctx context.Context, key string, stats *model.ClickStats) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_serialization")))
+		r.logger.Error("cache serialization error on stats",
+			slog.String("error", err.Error()),
+			slog.String("key", key))
+		return
+	}
+
+	cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+	defer cancel()
+	_, err = r.cacheCB.Execute(func() (interface{}, error) {
+		return nil, r.cache.ClientFor(key).Set(cacheCtx, key, data, r.ttl).Err()
+	})
+	if err != nil && !errors.Is(err, gobreaker.ErrOpenState) {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_write")))
+		r.logger.Error("cache write error",
+			slog.String("error", err.Error()),
+			slog.String("key", key))
+	}
+}
+
+// Compile-time check: CachedStatsRepository must implement StatsRepositoryInterface.
+var _ StatsRepositoryInterface = (*CachedStatsRepository)(nil)
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/cache"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// mockStatsRepository is a testify mock for StatsRepositoryInterface.
+type mockStatsRepository struct{ mock.Mock }
+
+func (m *mockStatsRepository) GetStats(ctx context.Context, filter model.StatsFilter) (*model.ClickStats, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ClickStats), args.Error(1)
+}
+
+func TestCachedStatsRepository_GetStats(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	filter := model.StatsFilter{ShortCode: "stats1", From: from, To: from.Add(time.Hour), Bucket: model.StatsBucketMinute, TopN: 10}
+
+	t.Run("second read is served from cache", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+
+		db := new(mockStatsRepository)
+		db.On("GetStats", mock.Anything, filter).Return(&model.ClickStats{ShortCode: "stats1", TotalClicks: 5}, nil).Once()
+		repo := NewCachedStatsRepository(db, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Minute, newTestLogger())
+
+		stats, err := repo.GetStats(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), stats.TotalClicks)
+
+		stats, err = repo.GetStats(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), stats.TotalClicks)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("different ranges use different keys", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+
+		other := filter
+		other.To = from.Add(2 * time.Hour)
+
+		db := new(mockStatsRepository)
+		db.On("GetStats", mock.Anything, filter).Return(&model.ClickStats{TotalClicks: 1}, nil).Once()
+		db.On("GetStats", mock.Anything, other).Return(&model.ClickStats{TotalClicks: 2}, nil).Once()
+		repo := NewCachedStatsRepository(db, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Minute, newTestLogger())
+
+		a, err := repo.GetStats(ctx, filter)
+		require.NoError(t, err)
+		b, err := repo.GetStats(ctx, other)
+		require.NoError(t, err)
+		assert.NotEqual(t, a.TotalClicks, b.TotalClicks)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("DB errors are not cached", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+
+		db := new(mockStatsRepository)
+		db.On("GetStats", mock.Anything, filter).Return(nil, errors.New("db down")).Once()
+		db.On("GetStats", mock.Anything, filter).Return(&model.ClickStats{TotalClicks: 7}, nil).Once()
+		repo := NewCachedStatsRepository(db, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Minute, newTestLogger())
+
+		_, err := repo.GetStats(ctx, filter)
+		assert.Error(t, err)
+
+		stats, err := repo.GetStats(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), stats.TotalClicks)
+	})
+
+	t.Run("works without a cache", func(t *testing.T) {
+		db := new(mockStatsRepository)
+		db.On("GetStats", mock.Anything, filter).Return(&model.ClickStats{TotalClicks: 3}, nil)
+		repo := NewCachedStatsRepository(db, nil, time.Minute, newTestLogger())
+
+		stats, err := repo.GetStats(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stats.TotalClicks)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -14,10 +15,12 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/singleflight"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/sony/gobreaker"
 	"github.com/zhejian/url-shortener/gateway/internal/cache"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
 )
 
 var tracer = otel.Tracer("gateway/repository")
@@ -37,15 +40,77 @@ type CachedURLRepository struct {
 	totalErrors     metric.Int64Counter
 	stateCB         metric.Float64ObservableGauge
 	cacheTimeout    time.Duration
+	clickCountTTL   time.Duration
 }
 
 // URLRepositoryInterface defines the contract for URL storage operations.
 type URLRepositoryInterface interface {
 	GetByCode(ctx context.Context, code string) (*model.URL, error)
 	Create(ctx context.Context, url *model.URL) error
+	CreateBatch(ctx context.Context, urls []*model.URL) ([]error, error)
 	Delete(ctx context.Context, code string) error
+	Update(ctx context.Context, code string, update model.URLUpdate) (*model.URL, error)
+	List(ctx context.Context, filter model.URLFilter) ([]*model.URL, error)
+	DeleteExpired(ctx context.Context, before time.Time, limit int) ([]model.LinkRef, error)
+	GetClickCount(ctx context.Context, code string) (int64, error)
+	Count(ctx context.Context) (int64, error)
+	FindReusable(ctx context.Context, hash []byte, owner, domain string) (*model.URL, error)
+	ConsumeUse(ctx context.Context, code string) (int64, error)
+	SyncUses(ctx context.Context, code string) error
+	GetUses(ctx context.Context, code string) (int64, error)
 }
 
+// urlCacheKey returns the key a link is cached under. Links in the default
+// workspace keep the original "url:<code>" form so entries cached before
+// workspaces existed remain valid; other workspaces add their ID.
+func urlCacheKey(workspaceID uuid.UUID, code string) string {
+	if workspaceID == model.DefaultWorkspaceID {
+		return "url:" + code
+	}
+	return fmt.Sprintf("url:%s:%s", workspaceID, code)
+}
+
+// clicksCacheKey returns the key of a link's cached click counter, following
+// the same scheme as urlCacheKey.
+func clicksCacheKey(workspaceID uuid.UUID, code string) string {
+	if workspaceID == model.DefaultWorkspaceID {
+		return "clicks:" + code
+	}
+	return fmt.Sprintf("clicks:%s:%s", workspaceID, code)
+}
+
+// usesCacheKey returns the key of a click-limited link's live use counter,
+// following the same scheme as urlCacheKey.
+func usesCacheKey(workspaceID uuid.UUID, code string) string {
+	if workspaceID == model.DefaultWorkspaceID {
+		return "uses:" + code
+	}
+	return fmt.Sprintf("uses:%s:%s", workspaceID, code)
+}
+
+// consumeUseScript grants one use of a click-limited link from a counter
+// hash {n: uses granted, max: limit} on the link's ring node. Running as a
+// script makes the check and increment atomic across gateway replicas.
+// It returns the new use count, -1 once the limit is reached, or -2 when
+// the counter is not loaded; passing ARGV (uses, limit, TTL in ms) loads a
+// missing counter first.
+var consumeUseScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	if #ARGV < 3 then return -2 end
+	redis.call('HSET', KEYS[1], 'n', ARGV[1], 'max', ARGV[2])
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+local v = redis.call('HMGET', KEYS[1], 'n', 'max')
+if tonumber(v[1]) >= tonumber(v[2]) then return -1 end
+return redis.call('HINCRBY', KEYS[1], 'n', 1)
+`)
+
+// Results of consumeUseScript other than a use count.
+const (
+	usesExhausted = -1
+	usesNotLoaded = -2
+)
+
 // notFoundSentinel is cached to prevent repeated DB queries for non-existent URLs.
 var notFoundSentinel = []byte("__NOT_FOUND__")
 
@@ -75,25 +140,37 @@ func DefaultCBSettings() CBSettings {
 	}
 }
 
+// defaultClickCountTTL bounds how stale GetClickCount may be, independent of
+// the (much longer) TTL used for redirect lookups.
+const defaultClickCountTTL = 30 * time.Second
+
 // CachedURLRepositoryOptions holds optional configuration.
 type CachedURLRepositoryOptions struct {
-	CacheCB *CBSettings
+	CacheCB       *CBSettings
+	ClickCountTTL time.Duration // cache lifetime of click counters (0 = defaultClickCountTTL)
 }
 
 // NewCachedURLRepository creates a new cached URL repository.
 func NewCachedURLRepository(db URLRepositoryInterface, cache cache.ClientProvider, ttl time.Duration, logger *slog.Logger, opts ...CachedURLRepositoryOptions) *CachedURLRepository {
 	cb := DefaultCBSettings()
-	if len(opts) > 0 && opts[0].CacheCB != nil {
-		cb = *opts[0].CacheCB
+	clickCountTTL := defaultClickCountTTL
+	if len(opts) > 0 {
+		if opts[0].CacheCB != nil {
+			cb = *opts[0].CacheCB
+		}
+		if opts[0].ClickCountTTL > 0 {
+			clickCountTTL = opts[0].ClickCountTTL
+		}
 	}
 
 	repo := &CachedURLRepository{
-		db:           db,
-		cache:        cache,
-		ttl:          ttl,
-		requestGroup: &singleflight.Group{},
-		logger:       logger,
-		cacheTimeout: cb.OperationTimeout,
+		db:            db,
+		cache:         cache,
+		ttl:           ttl,
+		requestGroup:  &singleflight.Group{},
+		logger:        logger,
+		cacheTimeout:  cb.OperationTimeout,
+		clickCountTTL: clickCountTTL,
 	}
 
 	meter := otel.Meter("gateway/repository")
@@ -112,70 +189,7 @@ func NewCachedURLRepository(db URLRepositoryInterface, cache cache.ClientProvide
 		metric.WithDescription("Total errors by type"),
 	)
 
-	repo.cacheCB = gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:        "redis",
-		MaxRequests: cb.MaxRequests,
-		Interval:    cb.Interval,
-		Timeout:     cb.Timeout,
-		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			// Primary: rate-based check over a meaningful sample window.
-			// Prevents spurious trips from momentary goroutine scheduling pressure.
-			if cb.MinRequestsToTrip > 0 && cb.FailureRateThreshold > 0 &&
-				counts.Requests >= cb.MinRequestsToTrip {
-				failureRate := float64(counts.TotalFailures) / float64(counts.Requests)
-				if failureRate > cb.FailureRateThreshold {
-					repo.logger.Error("circuit breaker about to trip",
-						slog.String("name", "redis"),
-						slog.String("reason", "failure_rate"),
-						slog.Float64("failure_rate", failureRate),
-						slog.Uint64("requests", uint64(counts.Requests)),
-						slog.Uint64("failures", uint64(counts.TotalFailures)),
-						slog.Duration("operation_timeout", cb.OperationTimeout))
-					return true
-				}
-			}
-			// Secondary: consecutive failures — fast path for total outages where
-			// the sample window hasn't filled yet.
-			if cb.ConsecutiveFailures > 0 && counts.ConsecutiveFailures >= cb.ConsecutiveFailures {
-				repo.logger.Error("circuit breaker about to trip",
-					slog.String("name", "redis"),
-					slog.String("reason", "consecutive_failures"),
-					slog.Uint64("consecutive_failures", uint64(counts.ConsecutiveFailures)),
-					slog.Uint64("requests", uint64(counts.Requests)),
-					slog.Duration("operation_timeout", cb.OperationTimeout))
-				return true
-			}
-			return false
-		},
-		IsSuccessful: func(err error) bool {
-			// redis.Nil is a cache miss, not an infrastructure failure.
-			return err == nil || err == redis.Nil
-		},
-		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			logLevel := slog.LevelWarn
-			message := "circuit breaker state change"
-
-			// Use different log levels based on transition
-			switch to {
-			case gobreaker.StateOpen:
-				logLevel = slog.LevelError
-				message = "circuit breaker OPENED - failing fast"
-			case gobreaker.StateClosed:
-				if from == gobreaker.StateHalfOpen {
-					logLevel = slog.LevelInfo
-					message = "circuit breaker RECOVERED"
-				}
-			case gobreaker.StateHalfOpen:
-				logLevel = slog.LevelWarn
-				message = "circuit breaker testing recovery"
-			}
-
-			repo.logger.Log(context.Background(), logLevel, message,
-				slog.String("name", name),
-				slog.String("from", from.String()),
-				slog.String("to", to.String()))
-		},
-	})
+	repo.cacheCB = newCacheBreaker("redis", cb, logger)
 
 	repo.stateCB, _ = meter.Float64ObservableGauge("circuit_breaker_state",
 		metric.WithDescription("Circuit breaker state (0=closed, 1=half-open, 2=open)"),
@@ -194,7 +208,7 @@ func NewCachedURLRepository(db URLRepositoryInterface, cache cache.ClientProvide
 // It checks cache first, falls back to DB on miss, and caches the result.
 // Non-existent URLs are negatively cached to prevent DB stampede.
 func (r *CachedURLRepository) GetByCode(ctx context.Context, code string) (*model.URL, error) {
-	cacheKey := fmt.Sprintf("url:%s", code)
+	cacheKey := urlCacheKey(tenant.WorkspaceID(ctx), code)
 
 	// Try cache first
 	if r.cache != nil {
@@ -273,7 +287,7 @@ func (r *CachedURLRepository) Create(ctx context.Context, url *model.URL) error
 	span.End()
 
 	if r.cache != nil {
-		cacheKey := fmt.Sprintf("url:%s", url.ShortCode)
+		cacheKey := urlCacheKey(url.WorkspaceID, url.ShortCode)
 		ctx, span := tracer.Start(ctx, "cache.set",
 			trace.WithAttributes(
 				attribute.String("db.system", "redis"),
@@ -282,20 +296,72 @@ func (r *CachedURLRepository) Create(ctx context.Context, url *model.URL) error
 				attribute.String("cache.node", r.cache.NodeFor(cacheKey)),
 			),
 		)
-		if data, err := json.Marshal(url); err == nil {
-			r.cacheSet(ctx, cacheKey, data, r.ttl)
-		} else {
+		ttl, ok := r.ttlFor(url)
+		if data, err := json.Marshal(url); err != nil {
 			span.RecordError(err)
 			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_serialization")))
 			r.logger.Error("cache serialization error on create",
 				slog.String("error", err.Error()),
 				slog.String("short_code", url.ShortCode))
+		} else if ok {
+			r.cacheSet(ctx, cacheKey, data, ttl)
 		}
 		span.End()
 	}
 	return nil
 }
 
+// CreateBatch stores many URLs in one DB round-trip, then write-through caches
+// the inserted rows. Cache writes are grouped by owning ring node and sent as
+// one pipeline per node instead of one round-trip per key.
+// The per-item result slice is passed through from URLRepository.CreateBatch.
+func (r *CachedURLRepository) CreateBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.Int("batch.size", len(urls)),
+		),
+	)
+
+	dbStart := time.Now()
+	results, err := r.db.CreateBatch(ctx, urls)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "INSERT_BATCH")),
+	)
+	if err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_insert")))
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.End()
+
+	if r.cache != nil {
+		entries := make(map[string]cacheEntry, len(urls))
+		for i, url := range urls {
+			if results[i] != nil {
+				continue
+			}
+			ttl, ok := r.ttlFor(url)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(url)
+			if err != nil {
+				r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_serialization")))
+				r.logger.Error("cache serialization error on batch create",
+					slog.String("error", err.Error()),
+					slog.String("short_code", url.ShortCode))
+				continue
+			}
+			entries[urlCacheKey(url.WorkspaceID, url.ShortCode)] = cacheEntry{data: data, ttl: ttl}
+		}
+		r.cacheSetMany(ctx, entries)
+	}
+	return results, nil
+}
+
 // Delete removes a URL from DB and invalidates the cache entry.
 func (r *CachedURLRepository) Delete(ctx context.Context, code string) error {
 	ctx, span := tracer.Start(ctx, "db.delete",
@@ -321,7 +387,8 @@ func (r *CachedURLRepository) Delete(ctx context.Context, code string) error {
 	span.End()
 
 	if r.cache != nil {
-		cacheKey := fmt.Sprintf("url:%s", code)
+		ws := tenant.WorkspaceID(ctx)
+		cacheKey := urlCacheKey(ws, code)
 		ctx, span := tracer.Start(ctx, "cache.delete",
 			trace.WithAttributes(
 				attribute.String("db.system", "redis"),
@@ -331,11 +398,397 @@ func (r *CachedURLRepository) Delete(ctx context.Context, code string) error {
 			),
 		)
 		r.cacheDel(ctx, cacheKey)
+		r.cacheDelMany(ctx, []string{clicksCacheKey(ws, code), usesCacheKey(ws, code)})
 		span.End()
 	}
 	return nil
 }
 
+// DeleteExpired removes one batch of expired URLs from the DB, then evicts
+// their cache entries with one DEL per owning ring node.
+func (r *CachedURLRepository) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]model.LinkRef, error) {
+	ctx, span := tracer.Start(ctx, "db.delete",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "DELETE"),
+			attribute.Int("batch.limit", limit),
+		),
+	)
+	dbStart := time.Now()
+	refs, err := r.db.DeleteExpired(ctx, before, limit)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "DELETE_EXPIRED")),
+	)
+	if err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_delete")))
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.End()
+
+	if r.cache != nil && len(refs) > 0 {
+		keys := make([]string, 0, 3*len(refs))
+		for _, ref := range refs {
+			keys = append(keys, urlCacheKey(ref.WorkspaceID, ref.ShortCode), clicksCacheKey(ref.WorkspaceID, ref.ShortCode),
+				usesCacheKey(ref.WorkspaceID, ref.ShortCode))
+		}
+		r.cacheDelMany(ctx, keys)
+	}
+	return refs, nil
+}
+
+// GetClickCount returns a URL's click counter, cached under its own key for
+// clickCountTTL. The full URL entry is cached much longer for redirects, so
+// its embedded ClickCount may be old; callers that report clicks use this
+// instead, and see counts at most clickCountTTL behind the database.
+func (r *CachedURLRepository) GetClickCount(ctx context.Context, code string) (int64, error) {
+	cacheKey := clicksCacheKey(tenant.WorkspaceID(ctx), code)
+
+	if r.cache != nil {
+		if cached, err := r.cacheGet(ctx, cacheKey); err == nil {
+			if count, err := strconv.ParseInt(cached, 10, 64); err == nil {
+				return count, nil
+			}
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_deserialization")))
+		} else if err != redis.Nil && !errors.Is(err, gobreaker.ErrOpenState) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_read")))
+			r.logger.Error("cache read error",
+				slog.Any("error", err),
+				slog.String("key", cacheKey))
+		}
+	}
+
+	dbStart := time.Now()
+	count, err := r.db.GetClickCount(ctx, code)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "SELECT_CLICKS")),
+	)
+	if err != nil {
+		if !isNotFoundError(err) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_read")))
+		}
+		return 0, err
+	}
+
+	if r.cache != nil {
+		r.cacheSet(ctx, cacheKey, strconv.FormatInt(count, 10), r.clickCountTTL)
+	}
+	return count, nil
+}
+
+// ConsumeUse grants one visit of a click-limited link and returns the number
+// of visits granted so far, or ErrUsesExhausted once the limit is reached.
+//
+// While Redis is healthy the counter lives on the link's ring node and is
+// checked and incremented atomically there, loaded from the DB on first use
+// and kept for clickCountTTL. Every granted use is written back to the DB,
+// which remains the source of truth: when the circuit breaker is open or the
+// node fails, uses are consumed by the DB's own atomic check instead. The
+// short counter lifetime bounds how long Redis can lag behind uses granted
+// by the DB during an outage.
+//
+// A use whose write-back fails is refused with the error, so a counter
+// reloaded from the DB can never grant it a second time. Its slot stays
+// taken in Redis, which errs on the side of fewer visits. When the DB
+// refuses the write because it already granted the remaining uses itself,
+// the stale counter is dropped so the next visit reloads it.
+func (r *CachedURLRepository) ConsumeUse(ctx context.Context, code string) (int64, error) {
+	if r.cache == nil {
+		return r.consumeUseFromDB(ctx, code)
+	}
+
+	key := usesCacheKey(tenant.WorkspaceID(ctx), code)
+	ctx, span := tracer.Start(ctx, "cache.eval",
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", "EVALSHA"),
+			attribute.String("cache.key", key),
+			attribute.String("cache.node", r.cache.NodeFor(key)),
+		),
+	)
+	defer span.End()
+
+	uses, err := r.cacheConsumeUse(ctx, key)
+	if err == nil && uses == usesNotLoaded {
+		var url *model.URL
+		dbStart := time.Now()
+		url, err = r.db.GetByCode(ctx, code)
+		r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+			metric.WithAttributes(attribute.String("operation", "SELECT")),
+		)
+		if err != nil {
+			if !isNotFoundError(err) {
+				r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_read")))
+			}
+			span.RecordError(err)
+			return 0, err
+		}
+		uses, err = r.cacheConsumeUse(ctx, key, url.Uses, url.MaxClicks, r.clickCountTTL.Milliseconds())
+	}
+	if err != nil {
+		if !errors.Is(err, gobreaker.ErrOpenState) {
+			span.RecordError(err)
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_write")))
+			r.logger.Error("cache use counter error, falling back to database",
+				slog.String("error", err.Error()),
+				slog.String("key", key))
+		}
+		span.SetAttributes(attribute.Bool("cache.fallback", true))
+		return r.consumeUseFromDB(ctx, code)
+	}
+	if uses == usesExhausted {
+		return 0, ErrUsesExhausted
+	}
+
+	dbStart := time.Now()
+	err = r.db.SyncUses(ctx, code)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "SYNC_USES")),
+	)
+	if errors.Is(err, ErrUsesExhausted) {
+		r.cacheDel(ctx, key)
+		return 0, ErrUsesExhausted
+	}
+	if err != nil {
+		span.RecordError(err)
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_update")))
+		return 0, fmt.Errorf("record link use: %w", err)
+	}
+	return uses, nil
+}
+
+// consumeUseFromDB grants a use with the DB's atomic check. A granted use
+// drops the Redis counter, when it can be reached, so it reloads the new
+// count instead of granting the same slot again.
+func (r *CachedURLRepository) consumeUseFromDB(ctx context.Context, code string) (int64, error) {
+	dbStart := time.Now()
+	uses, err := r.db.ConsumeUse(ctx, code)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "CONSUME_USE")),
+	)
+	if err != nil {
+		if !errors.Is(err, ErrUsesExhausted) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_update")))
+		}
+		return 0, err
+	}
+	if r.cache != nil {
+		r.cacheDel(ctx, usesCacheKey(tenant.WorkspaceID(ctx), code))
+	}
+	return uses, nil
+}
+
+// SyncUses passes through to the DB.
+func (r *CachedURLRepository) SyncUses(ctx context.Context, code string) error {
+	return r.db.SyncUses(ctx, code)
+}
+
+// GetUses returns how many visits of a click-limited link have been granted,
+// read from the live counter when it is loaded and from the DB otherwise.
+func (r *CachedURLRepository) GetUses(ctx context.Context, code string) (int64, error) {
+	if r.cache != nil {
+		key := usesCacheKey(tenant.WorkspaceID(ctx), code)
+		cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+		res, err := r.cacheCB.Execute(func() (interface{}, error) {
+			return r.cache.ClientFor(key).HGet(cacheCtx, key, "n").Int64()
+		})
+		cancel()
+		if err == nil {
+			return res.(int64), nil
+		}
+		if err != redis.Nil && !errors.Is(err, gobreaker.ErrOpenState) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_read")))
+			r.logger.Error("cache read error",
+				slog.Any("error", err),
+				slog.String("key", key))
+		}
+	}
+
+	dbStart := time.Now()
+	uses, err := r.db.GetUses(ctx, code)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "SELECT_USES")),
+	)
+	if err != nil && !isNotFoundError(err) {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_read")))
+	}
+	return uses, err
+}
+
+// Update changes a URL in the DB, then rewrites its cache entry on the owning
+// ring node so redirects pick up the new destination immediately.
+// If the new value cannot be cached the entry is deleted instead, so a stale
+// destination is never served until TTL expiry.
+func (r *CachedURLRepository) Update(ctx context.Context, code string, update model.URLUpdate) (*model.URL, error) {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("short_code", code),
+		),
+	)
+	dbStart := time.Now()
+	url, err := r.db.Update(ctx, code, update)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "UPDATE")),
+	)
+	if err != nil {
+		if !isNotFoundError(err) && !errors.Is(err, ErrInvalidSchedule) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_update")))
+		}
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.End()
+
+	if r.cache != nil {
+		cacheKey := urlCacheKey(tenant.WorkspaceID(ctx), code)
+		ctx, span := tracer.Start(ctx, "cache.set",
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", "SET"),
+				attribute.String("cache.key", cacheKey),
+				attribute.String("cache.node", r.cache.NodeFor(cacheKey)),
+			),
+		)
+		if ttl, ok := r.ttlFor(url); !ok {
+			// Still expired after the update: drop any cached copy.
+			r.cacheDel(ctx, cacheKey)
+		} else if data, err := json.Marshal(url); err == nil {
+			r.cacheSet(ctx, cacheKey, data, ttl)
+		} else {
+			span.RecordError(err)
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_serialization")))
+			r.logger.Error("cache serialization error on update",
+				slog.String("error", err.Error()),
+				slog.String("short_code", code))
+			r.cacheDel(ctx, cacheKey)
+		}
+		span.End()
+	}
+	return url, nil
+}
+
+// List reads a page of URLs straight from the DB.
+// Listings are not cached: filter combinations are effectively unbounded and
+// results go stale as soon as any link is created or deleted.
+func (r *CachedURLRepository) List(ctx context.Context, filter model.URLFilter) ([]*model.URL, error) {
+	dbStart := time.Now()
+	urls, err := r.db.List(ctx, filter)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "LIST")),
+	)
+	if err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_list")))
+		return nil, err
+	}
+	return urls, nil
+}
+
+// FindReusable looks up a link a reuse_existing create may return. It goes
+// straight to the DB: the cache is keyed by short code only.
+func (r *CachedURLRepository) FindReusable(ctx context.Context, hash []byte, owner, domain string) (*model.URL, error) {
+	dbStart := time.Now()
+	url, err := r.db.FindReusable(ctx, hash, owner, domain)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "SELECT")),
+	)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_read")))
+		}
+		return nil, err
+	}
+	return url, nil
+}
+
+// Count reads the number of links in the context's workspace from the DB.
+// It backs quota checks, which must not trust a cached value.
+func (r *CachedURLRepository) Count(ctx context.Context) (int64, error) {
+	dbStart := time.Now()
+	count, err := r.db.Count(ctx)
+	r.dbQueryDuration.Record(ctx, time.Since(dbStart).Seconds(),
+		metric.WithAttributes(attribute.String("operation", "COUNT")),
+	)
+	if err != nil {
+		r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "db_read")))
+		return 0, err
+	}
+	return count, nil
+}
+
+// newCacheBreaker builds the circuit breaker guarding Redis calls.
+// Each cached repository owns one so a slow query type cannot open the
+// breaker for another.
+func newCacheBreaker(name string, cb CBSettings, logger *slog.Logger) *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: cb.MaxRequests,
+		Interval:    cb.Interval,
+		Timeout:     cb.Timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			// Primary: rate-based check over a meaningful sample window.
+			// Prevents spurious trips from momentary goroutine scheduling pressure.
+			if cb.MinRequestsToTrip > 0 && cb.FailureRateThreshold > 0 &&
+				counts.Requests >= cb.MinRequestsToTrip {
+				failureRate := float64(counts.TotalFailures) / float64(counts.Requests)
+				if failureRate > cb.FailureRateThreshold {
+					logger.Error("circuit breaker about to trip",
+						slog.String("name", name),
+						slog.String("reason", "failure_rate"),
+						slog.Float64("failure_rate", failureRate),
+						slog.Uint64("requests", uint64(counts.Requests)),
+						slog.Uint64("failures", uint64(counts.TotalFailures)),
+						slog.Duration("operation_timeout", cb.OperationTimeout))
+					return true
+				}
+			}
+			// Secondary: consecutive failures — fast path for total outages where
+			// the sample window hasn't filled yet.
+			if cb.ConsecutiveFailures > 0 && counts.ConsecutiveFailures >= cb.ConsecutiveFailures {
+				logger.Error("circuit breaker about to trip",
+					slog.String("name", name),
+					slog.String("reason", "consecutive_failures"),
+					slog.Uint64("consecutive_failures", uint64(counts.ConsecutiveFailures)),
+					slog.Uint64("requests", uint64(counts.Requests)),
+					slog.Duration("operation_timeout", cb.OperationTimeout))
+				return true
+			}
+			return false
+		},
+		IsSuccessful: func(err error) bool {
+			// redis.Nil is a cache miss, not an infrastructure failure.
+			return err == nil || err == redis.Nil
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logLevel := slog.LevelWarn
+			message := "circuit breaker state change"
+
+			// Use different log levels based on transition
+			switch to {
+			case gobreaker.StateOpen:
+				logLevel = slog.LevelError
+				message = "circuit breaker OPENED - failing fast"
+			case gobreaker.StateClosed:
+				if from == gobreaker.StateHalfOpen {
+					logLevel = slog.LevelInfo
+					message = "circuit breaker RECOVERED"
+				}
+			case gobreaker.StateHalfOpen:
+				logLevel = slog.LevelWarn
+				message = "circuit breaker testing recovery"
+			}
+
+			logger.Log(context.Background(), logLevel, message,
+				slog.String("name", name),
+				slog.String("from", from.String()),
+				slog.String("to", to.String()))
+		},
+	})
+}
+
 // isNotFoundError checks if the error is a not-found error.
 func isNotFoundError(err error) bool {
 	return errors.Is(err, ErrNotFound)
@@ -346,7 +799,7 @@ func isNotFoundError(err error) bool {
 // A cache re-check inside the callback handles late arrivals after a previous
 // singleflight call has already completed and populated the cache.
 func queryFromDBWithSingleflight(ctx context.Context, r *CachedURLRepository, code string) (*model.URL, error) {
-	cacheKey := fmt.Sprintf("url:%s", code)
+	cacheKey := urlCacheKey(tenant.WorkspaceID(ctx), code)
 	res, gerr, _ := r.requestGroup.Do(cacheKey, func() (interface{}, error) {
 		// Re-check cache: a previous singleflight call may have populated it
 		// before this callback was invoked (double-checked locking pattern).
@@ -388,7 +841,8 @@ func queryFromDBWithSingleflight(ctx context.Context, r *CachedURLRepository, co
 
 // rewriteCache populates the cache after a DB query.
 // On not-found errors, it caches a sentinel value to avoid repeated DB lookups.
-// On success, it caches the URL with the configured TTL.
+// On success, it caches the URL for the configured TTL, capped at the link's
+// remaining lifetime; links that have already expired are not cached.
 func rewriteCache(ctx context.Context, r *CachedURLRepository, cacheKey string, url *model.URL, err error) (*model.URL, error) {
 	if err != nil {
 		if r.cache != nil && isNotFoundError(err) {
@@ -400,13 +854,14 @@ func rewriteCache(ctx context.Context, r *CachedURLRepository, cacheKey string,
 
 	// Store the URL in cache for future requests
 	if r.cache != nil {
-		if data, err := json.Marshal(url); err == nil {
-			r.cacheSet(ctx, cacheKey, data, r.ttl)
-		} else {
+		ttl, ok := r.ttlFor(url)
+		if data, err := json.Marshal(url); err != nil {
 			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_serialization")))
 			r.logger.Error("cache serialization error on rewrite",
 				slog.String("error", err.Error()),
 				slog.String("key", cacheKey))
+		} else if ok {
+			r.cacheSet(ctx, cacheKey, data, ttl)
 		}
 	}
 	return url, nil
@@ -441,6 +896,94 @@ func (r *CachedURLRepository) cacheSet(ctx context.Context, key string, data int
 	}
 }
 
+// cacheConsumeUse runs consumeUseScript on key's node through the circuit
+// breaker; seed, when given, loads a missing counter.
+func (r *CachedURLRepository) cacheConsumeUse(ctx context.Context, key string, seed ...any) (int64, error) {
+	cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+	defer cancel()
+	res, err := r.cacheCB.Execute(func() (interface{}, error) {
+		return consumeUseScript.Run(cacheCtx, r.cache.ClientFor(key), []string{key}, seed...).Int64()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.(int64), nil
+}
+
+// cacheEntry is a value and its TTL for cacheSetMany.
+type cacheEntry struct {
+	data []byte
+	ttl  time.Duration
+}
+
+// ttlFor returns how long url may be cached: the configured TTL, capped at the
+// time left before the link expires so Redis never outlives the link, and
+// at its activation time so an entry read while the link was scheduled is
+// refreshed from the DB as it goes live.
+// ok is false when the link has already expired and must not be cached
+// (a zero TTL would make Redis keep the key forever).
+func (r *CachedURLRepository) ttlFor(url *model.URL) (ttl time.Duration, ok bool) {
+	ttl = r.ttl
+	if url.ExpiresAt != nil {
+		remaining := time.Until(*url.ExpiresAt)
+		if remaining <= 0 {
+			return 0, false
+		}
+		if ttl <= 0 || remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if url.NotBefore != nil {
+		if pending := time.Until(*url.NotBefore); pending > 0 && (ttl <= 0 || pending < ttl) {
+			ttl = pending
+		}
+	}
+	return ttl, true
+}
+
+// cacheSetMany writes entries with one pipeline per owning ring node.
+// Each node's pipeline goes through the circuit breaker independently so a
+// single slow node does not fail writes destined for healthy ones.
+func (r *CachedURLRepository) cacheSetMany(ctx context.Context, entries map[string]cacheEntry) {
+	byNode := make(map[string][]string)
+	for key := range entries {
+		node := r.cache.NodeFor(key)
+		byNode[node] = append(byNode[node], key)
+	}
+
+	for node, keys := range byNode {
+		ctx, span := tracer.Start(ctx, "cache.pipeline",
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", "SET"),
+				attribute.String("cache.node", node),
+				attribute.Int("cache.keys", len(keys)),
+			),
+		)
+		cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+		_, err := r.cacheCB.Execute(func() (interface{}, error) {
+			client := r.cache.ClientFor(keys[0])
+			_, err := client.Pipelined(cacheCtx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Set(cacheCtx, key, entries[key].data, entries[key].ttl)
+				}
+				return nil
+			})
+			return nil, err
+		})
+		cancel()
+		if err != nil && !errors.Is(err, gobreaker.ErrOpenState) {
+			span.RecordError(err)
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_write")))
+			r.logger.Error("cache pipeline write error",
+				slog.String("error", err.Error()),
+				slog.String("node", node),
+				slog.Int("keys", len(keys)))
+		}
+		span.End()
+	}
+}
+
 func (r *CachedURLRepository) cacheDel(ctx context.Context, key string) {
 	cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
 	defer cancel()
@@ -456,6 +999,42 @@ func (r *CachedURLRepository) cacheDel(ctx context.Context, key string) {
 	}
 }
 
+// cacheDelMany deletes keys with one DEL per owning ring node, each through
+// the circuit breaker, mirroring cacheSetMany.
+func (r *CachedURLRepository) cacheDelMany(ctx context.Context, keys []string) {
+	byNode := make(map[string][]string)
+	for _, key := range keys {
+		node := r.cache.NodeFor(key)
+		byNode[node] = append(byNode[node], key)
+	}
+
+	for node, keys := range byNode {
+		ctx, span := tracer.Start(ctx, "cache.delete",
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", "DELETE"),
+				attribute.String("cache.node", node),
+				attribute.Int("cache.keys", len(keys)),
+			),
+		)
+		cacheCtx, cancel := context.WithTimeout(ctx, r.cacheTimeout)
+		_, err := r.cacheCB.Execute(func() (interface{}, error) {
+			client := r.cache.ClientFor(keys[0])
+			return nil, client.Del(cacheCtx, keys...).Err()
+		})
+		cancel()
+		if err != nil && !errors.Is(err, gobreaker.ErrOpenState) {
+			span.RecordError(err)
+			r.totalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("type", "cache_delete")))
+			r.logger.Error("cache delete error",
+				slog.String("error", err.Error()),
+				slog.String("node", node),
+				slog.Int("keys", len(keys)))
+		}
+		span.End()
+	}
+}
+
 func (r *CachedURLRepository) CBState() string {
 	return r.cacheCB.State().String()
 }
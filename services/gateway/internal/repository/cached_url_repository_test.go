@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net"
 	"os"
@@ -142,6 +143,28 @@ func TestCachedURLRepository_GetByCode(t *testing.T) {
 		assert.ErrorIs(t, err, ErrNotFound, "expected ErrNotFound from negative cache")
 	})
 
+	t.Run("redirect rules are cached with the URL", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		rules := []model.RedirectRule{
+			{OS: []string{"ios"}, URL: "https://apps.apple.com/app/id1"},
+			{OS: []string{"android"}, Language: []string{"de"}, URL: "https://play.google.com/store/apps/details?id=x&hl=de"},
+		}
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "app", OriginalURL: "https://example.com/app", Rules: rules}))
+		_, err := repo.GetByCode(ctx, "app")
+		require.NoError(t, err)
+
+		// Served from cache once the row is gone.
+		testDB.Pool.Exec(ctx, "DELETE FROM urls WHERE short_code = $1", "app")
+		url, err := repo.GetByCode(ctx, "app")
+		require.NoError(t, err)
+		assert.Equal(t, rules, url.Rules)
+	})
+
 	t.Run("graceful degradation - works when cache is nil", func(t *testing.T) {
 		testDB.Cleanup(ctx)
 
@@ -252,6 +275,57 @@ func TestCachedURLRepository_Create(t *testing.T) {
 	})
 }
 
+func TestCachedURLRepository_CreateBatch(t *testing.T) {
+	ctx := context.Background()
+	cacheTTL := 5 * time.Minute
+
+	t.Run("write-through - caches inserted rows only", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "existing", OriginalURL: "https://example.com/old"}))
+
+		urls := []*model.URL{
+			{ID: uuid.New(), ShortCode: "bulk1", OriginalURL: "https://example.com/1"},
+			{ID: uuid.New(), ShortCode: "bulk2", OriginalURL: "https://example.com/2"},
+			{ID: uuid.New(), ShortCode: "existing", OriginalURL: "https://example.com/new"},
+		}
+		results, err := repo.CreateBatch(ctx, urls)
+		require.NoError(t, err)
+		assert.NoError(t, results[0])
+		assert.NoError(t, results[1])
+		assert.ErrorIs(t, results[2], ErrCodeConflict)
+
+		for _, key := range []string{"url:bulk1", "url:bulk2"} {
+			ttl, err := testCache.Client.TTL(ctx, key).Result()
+			require.NoError(t, err)
+			assert.True(t, ttl > 0 && ttl <= cacheTTL, "expected %s cached with TTL, got %v", key, ttl)
+		}
+
+		// The conflicting item must not overwrite the existing cached row
+		cached, err := testCache.Client.Get(ctx, "url:existing").Result()
+		require.NoError(t, err)
+		assert.Contains(t, cached, "https://example.com/old")
+	})
+
+	t.Run("succeeds when circuit is open", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"dead": deadRedisClient()}, 1), cacheTTL, newTestLogger(),
+			CachedURLRepositoryOptions{CacheCB: fastCBSettings()})
+
+		results, err := repo.CreateBatch(ctx, []*model.URL{
+			{ID: uuid.New(), ShortCode: "cbbulk1", OriginalURL: "https://example.com/1"},
+		})
+		require.NoError(t, err)
+		assert.NoError(t, results[0])
+	})
+}
+
 func TestCachedURLRepository_Delete(t *testing.T) {
 	ctx := context.Background()
 	cacheTTL := 5 * time.Minute
@@ -327,6 +401,370 @@ func TestCachedURLRepository_Delete(t *testing.T) {
 	})
 }
 
+func TestCachedURLRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	cacheTTL := 5 * time.Minute
+
+	t.Run("rewrites cache entry with new destination", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		// Create and cache a URL
+		url := &model.URL{
+			ID:          uuid.New(),
+			ShortCode:   "toupdate",
+			OriginalURL: "https://example.com/typo",
+			CreatedAt:   time.Now(),
+		}
+		require.NoError(t, repo.Create(ctx, url))
+
+		newURL := "https://example.com/fixed"
+		expiresAt := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		updated, err := repo.Update(ctx, "toupdate", model.URLUpdate{OriginalURL: &newURL, ExpiresAt: &expiresAt})
+		require.NoError(t, err)
+		assert.Equal(t, newURL, updated.OriginalURL)
+		require.NotNil(t, updated.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*updated.ExpiresAt))
+
+		// Subsequent reads must see the new destination, not the stale cached one
+		got, err := repo.GetByCode(ctx, "toupdate")
+		require.NoError(t, err)
+		assert.Equal(t, newURL, got.OriginalURL)
+
+		cached, err := testCache.Client.Get(ctx, "url:toupdate").Result()
+		require.NoError(t, err, "expected cache entry after update")
+		assert.Contains(t, cached, newURL)
+	})
+
+	t.Run("clears expiry and keeps other fields", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		expiresAt := time.Now().Add(time.Hour)
+		url := &model.URL{
+			ID:          uuid.New(),
+			ShortCode:   "noexpiry",
+			OriginalURL: "https://example.com/keep",
+			CreatedAt:   time.Now(),
+			ExpiresAt:   &expiresAt,
+		}
+		require.NoError(t, repo.Create(ctx, url))
+
+		updated, err := repo.Update(ctx, "noexpiry", model.URLUpdate{ClearExpiry: true})
+		require.NoError(t, err)
+		assert.Nil(t, updated.ExpiresAt)
+		assert.Equal(t, "https://example.com/keep", updated.OriginalURL)
+	})
+
+	t.Run("replaces and clears redirect rules", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "ruled", OriginalURL: "https://example.com/"}))
+
+		rules := []model.RedirectRule{{Language: []string{"fr"}, URL: "https://example.com/fr"}}
+		updated, err := repo.Update(ctx, "ruled", model.URLUpdate{Rules: &rules})
+		require.NoError(t, err)
+		assert.Equal(t, rules, updated.Rules)
+
+		newURL := "https://example.com/home"
+		updated, err = repo.Update(ctx, "ruled", model.URLUpdate{OriginalURL: &newURL})
+		require.NoError(t, err)
+		assert.Equal(t, rules, updated.Rules, "rules left untouched")
+
+		updated, err = repo.Update(ctx, "ruled", model.URLUpdate{Rules: &[]model.RedirectRule{}})
+		require.NoError(t, err)
+		assert.Empty(t, updated.Rules)
+	})
+
+	t.Run("stores, replaces and clears variants", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		variants := []model.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 1},
+			{Name: "b", URL: "https://example.com/b", Weight: 2},
+		}
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "split", OriginalURL: "https://example.com/",
+			Variants: variants, StickyVariants: true}))
+		url, err := repo.GetByCode(ctx, "split")
+		require.NoError(t, err)
+		assert.Equal(t, variants, url.Variants)
+		assert.True(t, url.StickyVariants)
+
+		variants = variants[:1]
+		sticky := false
+		updated, err := repo.Update(ctx, "split", model.URLUpdate{Variants: &variants, StickyVariants: &sticky})
+		require.NoError(t, err)
+		assert.Equal(t, variants, updated.Variants)
+		assert.False(t, updated.StickyVariants)
+
+		updated, err = repo.Update(ctx, "split", model.URLUpdate{Variants: &[]model.Variant{}})
+		require.NoError(t, err)
+		assert.Empty(t, updated.Variants)
+	})
+
+	t.Run("retags tracking parameters without touching the destination", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		tracking := &model.TrackingParams{Source: "newsletter", Campaign: "spring", Extra: map[string]string{"ref": "a"}}
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "tagged", OriginalURL: "https://example.com/", Tracking: tracking}))
+		url, err := repo.GetByCode(ctx, "tagged")
+		require.NoError(t, err)
+		assert.Equal(t, tracking, url.Tracking)
+
+		retag := &model.TrackingParams{Source: "newsletter", Campaign: "summer"}
+		updated, err := repo.Update(ctx, "tagged", model.URLUpdate{Tracking: retag})
+		require.NoError(t, err)
+		assert.Equal(t, retag, updated.Tracking)
+		assert.Equal(t, "https://example.com/", updated.OriginalURL)
+
+		updated, err = repo.Update(ctx, "tagged", model.URLUpdate{Tracking: &model.TrackingParams{}})
+		require.NoError(t, err)
+		assert.Nil(t, updated.Tracking)
+	})
+
+	t.Run("update non-existent returns not found", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		newURL := "https://example.com/nowhere"
+		_, err := repo.Update(ctx, "nonexistent", model.URLUpdate{OriginalURL: &newURL})
+		require.ErrorIs(t, err, ErrNotFound)
+
+		exists, _ := testCache.Client.Exists(ctx, "url:nonexistent").Result()
+		assert.Equal(t, int64(0), exists, "expected no cache entry for failed update")
+	})
+}
+
+func TestCachedURLRepository_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts reaped codes from the cache", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+
+		ring := cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1)
+		for _, key := range []string{"url:gone1", "url:gone2", "url:stays"} {
+			require.NoError(t, testCache.Client.Set(ctx, key, "{}", time.Minute).Err())
+		}
+
+		db := new(mockURLRepository)
+		ws := uuid.New()
+		require.NoError(t, testCache.Client.Set(ctx, "url:"+ws.String()+":gone1", "{}", time.Minute).Err())
+		gone := []model.LinkRef{{ShortCode: "gone1"}, {ShortCode: "gone2"}, {WorkspaceID: ws, ShortCode: "gone1"}}
+		db.On("DeleteExpired", mock.Anything, mock.Anything, 100).Return(gone, nil)
+		repo := NewCachedURLRepository(db, ring, time.Minute, newTestLogger())
+
+		refs, err := repo.DeleteExpired(ctx, time.Now(), 100)
+		require.NoError(t, err)
+		assert.Equal(t, gone, refs)
+
+		n, err := testCache.Client.Exists(ctx, "url:gone1", "url:gone2", "url:"+ws.String()+":gone1").Result()
+		require.NoError(t, err)
+		assert.Zero(t, n, "reaped keys must be evicted")
+		n, err = testCache.Client.Exists(ctx, "url:stays").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("returns DB error without touching cache", func(t *testing.T) {
+		db := new(mockURLRepository)
+		db.On("DeleteExpired", mock.Anything, mock.Anything, 10).Return(nil, errors.New("db down"))
+		repo := NewCachedURLRepository(db, cache.NewHashRing(map[string]*redis.Client{"dead": deadRedisClient()}, 1), time.Minute, newTestLogger())
+
+		_, err := repo.DeleteExpired(ctx, time.Now(), 10)
+		assert.Error(t, err)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestCachedURLRepository_GetClickCount(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches the counter for ClickCountTTL only", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+
+		db := new(mockURLRepository)
+		db.On("GetClickCount", mock.Anything, "counted").Return(int64(7), nil).Once()
+		repo := NewCachedURLRepository(db, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger(),
+			CachedURLRepositoryOptions{ClickCountTTL: 10 * time.Second})
+
+		count, err := repo.GetClickCount(ctx, "counted")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+
+		// Second read is served from cache; the mock allows only one DB call.
+		count, err = repo.GetClickCount(ctx, "counted")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+
+		ttl, err := testCache.Client.TTL(ctx, "clicks:counted").Result()
+		require.NoError(t, err)
+		assert.True(t, ttl > 0 && ttl <= 10*time.Second, "expected click count TTL <= 10s, got %v", ttl)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("falls back to DB when cache is unreachable", func(t *testing.T) {
+		badRedis := deadRedisClient()
+		defer badRedis.Close()
+
+		db := new(mockURLRepository)
+		db.On("GetClickCount", mock.Anything, "counted").Return(int64(3), nil)
+		repo := NewCachedURLRepository(db, cache.NewHashRing(map[string]*redis.Client{"dead": badRedis}, 1), time.Hour, newTestLogger(),
+			CachedURLRepositoryOptions{CacheCB: fastCBSettings()})
+
+		count, err := repo.GetClickCount(ctx, "counted")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+}
+
+func TestCachedURLRepository_ConsumeUse(t *testing.T) {
+	ctx := context.Background()
+
+	insertLimited := func(t *testing.T, code string, maxClicks int64) {
+		t.Helper()
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO urls (id, short_code, original_url, created_at, max_clicks)
+			VALUES ($1, $2, $3, $4, $5)
+		`, uuid.New(), code, "https://example.com/"+code, time.Now(), maxClicks)
+		require.NoError(t, err)
+	}
+
+	t.Run("counts on the owning node and records uses in the DB", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+		insertLimited(t, "twice", 2)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger())
+
+		for want := int64(1); want <= 2; want++ {
+			uses, err := repo.ConsumeUse(ctx, "twice")
+			require.NoError(t, err)
+			assert.Equal(t, want, uses)
+		}
+		_, err := repo.ConsumeUse(ctx, "twice")
+		assert.ErrorIs(t, err, ErrUsesExhausted)
+
+		n, err := testCache.Client.HGet(ctx, "uses:twice", "n").Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		stored, err := dbRepo.GetUses(ctx, "twice")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stored, "every granted use is written back")
+	})
+
+	t.Run("concurrent visits never exceed the limit", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+		insertLimited(t, "rush", 5)
+
+		repo := NewCachedURLRepository(NewURLRepository(testDB.Pool), cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger())
+
+		var granted atomic.Int32
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.ConsumeUse(ctx, "rush"); err == nil {
+					granted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(5), granted.Load())
+	})
+
+	t.Run("resumes from the DB count when the counter is not loaded", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+		insertLimited(t, "resumed", 3)
+		_, err := testDB.Pool.Exec(ctx, `UPDATE urls SET uses = 2 WHERE short_code = 'resumed'`)
+		require.NoError(t, err)
+
+		repo := NewCachedURLRepository(NewURLRepository(testDB.Pool), cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger())
+
+		uses, err := repo.ConsumeUse(ctx, "resumed")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), uses)
+		_, err = repo.ConsumeUse(ctx, "resumed")
+		assert.ErrorIs(t, err, ErrUsesExhausted)
+	})
+
+	t.Run("refuses the use when it cannot be recorded", func(t *testing.T) {
+		testCache.Cleanup(ctx)
+		db := new(mockURLRepository)
+		db.On("GetByCode", mock.Anything, "unsynced").Return(&model.URL{ShortCode: "unsynced", MaxClicks: 1}, nil)
+		db.On("SyncUses", mock.Anything, "unsynced").Return(errors.New("db down"))
+		repo := NewCachedURLRepository(db, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger())
+
+		_, err := repo.ConsumeUse(ctx, "unsynced")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUsesExhausted)
+
+		_, err = repo.ConsumeUse(ctx, "unsynced")
+		assert.ErrorIs(t, err, ErrUsesExhausted, "the refused use is not granted again")
+	})
+
+	t.Run("DB enforces the limit when the circuit is open", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		insertLimited(t, "cbuses", 1)
+
+		badRedis := deadRedisClient()
+		defer badRedis.Close()
+		repo := NewCachedURLRepository(NewURLRepository(testDB.Pool), cache.NewHashRing(map[string]*redis.Client{"node": badRedis}, 1), time.Hour, newTestLogger(),
+			CachedURLRepositoryOptions{CacheCB: fastCBSettings()})
+		tripCircuitBreaker(ctx, repo)
+
+		uses, err := repo.ConsumeUse(ctx, "cbuses")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), uses)
+		_, err = repo.ConsumeUse(ctx, "cbuses")
+		assert.ErrorIs(t, err, ErrUsesExhausted)
+	})
+
+	t.Run("a use granted by the DB is not granted again by a stale counter", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+		insertLimited(t, "once", 1)
+
+		repo := NewCachedURLRepository(NewURLRepository(testDB.Pool), cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Hour, newTestLogger())
+		key := usesCacheKey(model.DefaultWorkspaceID, "once")
+		require.NoError(t, testCache.Client.HSet(ctx, key, "n", 0, "max", 1).Err())
+		// The DB grants the only use while Redis is unreachable.
+		_, err := testDB.Pool.Exec(ctx, `UPDATE urls SET uses = 1 WHERE short_code = 'once'`)
+		require.NoError(t, err)
+
+		_, err = repo.ConsumeUse(ctx, "once")
+		assert.ErrorIs(t, err, ErrUsesExhausted)
+		exists, err := testCache.Client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists, "stale counter must be dropped")
+	})
+}
+
 func TestCachedURLRepository_CacheTTL(t *testing.T) {
 	ctx := context.Background()
 
@@ -355,6 +793,81 @@ func TestCachedURLRepository_CacheTTL(t *testing.T) {
 		// TTL should be close to cacheTTL (within 1 second tolerance)
 		assert.True(t, ttl >= cacheTTL-time.Second && ttl <= cacheTTL, "expected TTL close to %v, got %v", cacheTTL, ttl)
 	})
+
+	t.Run("TTL is capped at the link's remaining lifetime", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		cacheTTL := 10 * time.Minute
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, newTestLogger())
+
+		expiresAt := time.Now().Add(30 * time.Second)
+		url := &model.URL{
+			ID:          uuid.New(),
+			ShortCode:   "ttlcap",
+			OriginalURL: "https://example.com/ttlcap",
+			CreatedAt:   time.Now(),
+			ExpiresAt:   &expiresAt,
+		}
+		require.NoError(t, repo.Create(ctx, url))
+
+		ttl, err := testCache.Client.TTL(ctx, "url:ttlcap").Result()
+		require.NoError(t, err, "failed to get TTL")
+		assert.True(t, ttl > 0 && ttl <= 30*time.Second, "expected TTL capped at 30s, got %v", ttl)
+	})
+
+	t.Run("TTL is capped at the link's activation time", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), 10*time.Minute, newTestLogger())
+
+		notBefore := time.Now().Add(20 * time.Second)
+		require.NoError(t, repo.Create(ctx, &model.URL{
+			ID:          uuid.New(),
+			ShortCode:   "launch",
+			OriginalURL: "https://example.com/launch",
+			CreatedAt:   time.Now(),
+			NotBefore:   &notBefore,
+			FallbackURL: "https://example.com/soon",
+		}))
+
+		ttl, err := testCache.Client.TTL(ctx, "url:launch").Result()
+		require.NoError(t, err)
+		assert.True(t, ttl > 0 && ttl <= 20*time.Second, "expected TTL capped at 20s, got %v", ttl)
+
+		got, err := repo.GetByCode(ctx, "launch")
+		require.NoError(t, err)
+		require.NotNil(t, got.NotBefore)
+		assert.WithinDuration(t, notBefore, *got.NotBefore, time.Second)
+		assert.Equal(t, "https://example.com/soon", got.FallbackURL)
+	})
+
+	t.Run("expired links are not cached on read", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := NewURLRepository(testDB.Pool)
+		repo := NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Minute, newTestLogger())
+
+		expired := time.Now().Add(-time.Hour)
+		require.NoError(t, dbRepo.Create(ctx, &model.URL{
+			ID:          uuid.New(),
+			ShortCode:   "ttlexpired",
+			OriginalURL: "https://example.com/expired",
+			CreatedAt:   time.Now().Add(-2 * time.Hour),
+			ExpiresAt:   &expired,
+		}))
+
+		_, err := repo.GetByCode(ctx, "ttlexpired")
+		require.NoError(t, err)
+
+		exists, err := testCache.Client.Exists(ctx, "url:ttlexpired").Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists, "expired link must not be cached")
+	})
 }
 
 type countingRepository struct {
@@ -382,10 +895,74 @@ func (m *mockURLRepository) Create(ctx context.Context, url *model.URL) error {
 	return m.Called(ctx, url).Error(0)
 }
 
+func (m *mockURLRepository) CreateBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	args := m.Called(ctx, urls)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]error), args.Error(1)
+}
+
 func (m *mockURLRepository) Delete(ctx context.Context, code string) error {
 	return m.Called(ctx, code).Error(0)
 }
 
+func (m *mockURLRepository) Update(ctx context.Context, code string, update model.URLUpdate) (*model.URL, error) {
+	args := m.Called(ctx, code, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.URL), args.Error(1)
+}
+
+func (m *mockURLRepository) List(ctx context.Context, filter model.URLFilter) ([]*model.URL, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.URL), args.Error(1)
+}
+
+func (m *mockURLRepository) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]model.LinkRef, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LinkRef), args.Error(1)
+}
+
+func (m *mockURLRepository) FindReusable(ctx context.Context, hash []byte, owner, domain string) (*model.URL, error) {
+	args := m.Called(ctx, hash, owner, domain)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.URL), args.Error(1)
+}
+
+func (m *mockURLRepository) Count(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockURLRepository) GetClickCount(ctx context.Context, code string) (int64, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockURLRepository) ConsumeUse(ctx context.Context, code string) (int64, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockURLRepository) SyncUses(ctx context.Context, code string) error {
+	return m.Called(ctx, code).Error(0)
+}
+
+func (m *mockURLRepository) GetUses(ctx context.Context, code string) (int64, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // hangingRedisClient returns a Redis client connected to a TCP server that accepts
 // connections but never sends data. Every operation hangs until the context expires.
 func hangingRedisClient(t *testing.T) *redis.Client {
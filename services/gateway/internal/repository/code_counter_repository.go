@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CodeCounterRepository reserves blocks of the shared short code counter
+// and keeps the length generated codes were promoted to.
+type CodeCounterRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCodeCounterRepository creates a new code counter repository
+func NewCodeCounterRepository(db *pgxpool.Pool) *CodeCounterRepository {
+	return &CodeCounterRepository{db: db}
+}
+
+// NextBlock reserves size consecutive counter values and returns the first.
+// The row lock taken by the UPDATE serialises concurrent callers, so blocks
+// handed to different replicas never overlap.
+func (r *CodeCounterRepository) NextBlock(ctx context.Context, size int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "short_code_counter"),
+			attribute.Int64("counter.block_size", size),
+		),
+	)
+	defer span.End()
+
+	var start int64
+	err := r.db.QueryRow(ctx, `
+		UPDATE short_code_counter
+		SET next_value = next_value + $1
+		RETURNING next_value - $1`,
+		size,
+	).Scan(&start)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return start, nil
+}
+
+// CodeLength returns the stored length of generated codes, 0 if it was
+// never promoted.
+func (r *CodeCounterRepository) CodeLength(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "short_code_counter"),
+		),
+	)
+	defer span.End()
+
+	var n int
+	if err := r.db.QueryRow(ctx, `SELECT code_length FROM short_code_counter`).Scan(&n); err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// RaiseCodeLength stores n as the length of generated codes. A longer
+// length stored by another replica is kept, so the length never shrinks.
+func (r *CodeCounterRepository) RaiseCodeLength(ctx context.Context, n int) error {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "short_code_counter"),
+			attribute.Int("code_length", n),
+		),
+	)
+	defer span.End()
+
+	_, err := r.db.Exec(ctx, `UPDATE short_code_counter SET code_length = GREATEST(code_length, $1)`, n)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeCounterRepository_NextBlock(t *testing.T) {
+	repo := NewCodeCounterRepository(testDB.Pool)
+	ctx := context.Background()
+
+	first, err := repo.NextBlock(ctx, 100)
+	require.NoError(t, err)
+	second, err := repo.NextBlock(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, first+100, second)
+
+	t.Run("concurrent blocks do not overlap", func(t *testing.T) {
+		const callers = 20
+		starts := make(chan int64, callers)
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start, err := repo.NextBlock(ctx, 10)
+				assert.NoError(t, err)
+				starts <- start
+			}()
+		}
+		wg.Wait()
+		close(starts)
+
+		seen := map[int64]bool{}
+		for start := range starts {
+			assert.Zero(t, (start-second-100)%10, "block boundary")
+			assert.False(t, seen[start], "start %d handed out twice", start)
+			seen[start] = true
+		}
+		assert.Len(t, seen, callers)
+	})
+}
+
+func TestCodeCounterRepository_CodeLength(t *testing.T) {
+	repo := NewCodeCounterRepository(testDB.Pool)
+	ctx := context.Background()
+	_, err := testDB.Pool.Exec(ctx, `UPDATE short_code_counter SET code_length = 0`)
+	require.NoError(t, err)
+
+	n, err := repo.CodeLength(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "never promoted")
+
+	require.NoError(t, repo.RaiseCodeLength(ctx, 8))
+	require.NoError(t, repo.RaiseCodeLength(ctx, 7))
+	n, err = repo.CodeLength(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 8, n, "a shorter length does not replace a longer one")
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrDomainNotFound = errors.New("domain not found")
+	ErrDomainConflict = errors.New("domain already registered")
+	ErrDomainInUse    = errors.New("domain still has links")
+)
+
+// DomainRepository handles database operations for branded domains.
+type DomainRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDomainRepository creates a new domain repository
+func NewDomainRepository(db *pgxpool.Pool) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+const domainColumns = `id, host, workspace_id, created_at`
+
+func scanDomain(row pgx.Row) (*model.Domain, error) {
+	var d model.Domain
+	if err := row.Scan(&d.ID, &d.Host, &d.WorkspaceID, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Create registers d.Host to d.WorkspaceID and fills in its ID and
+// CreatedAt. Hosts are stored lower-cased and belong to one workspace only;
+// a host that is already registered returns ErrDomainConflict.
+func (r *DomainRepository) Create(ctx context.Context, d *model.Domain) error {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", "domains"),
+		),
+	)
+	defer span.End()
+
+	d.Host = strings.ToLower(d.Host)
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO domains (host, workspace_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at`,
+		d.Host, d.WorkspaceID,
+	).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDomainConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByHost returns the domain registered for host, compared
+// case-insensitively.
+func (r *DomainRepository) GetByHost(ctx context.Context, host string) (*model.Domain, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "domains"),
+		),
+	)
+	defer span.End()
+
+	d, err := scanDomain(r.db.QueryRow(ctx,
+		`SELECT `+domainColumns+` FROM domains WHERE host = $1`, strings.ToLower(host)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDomainNotFound
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListByWorkspace returns the domains of a workspace ordered by host.
+func (r *DomainRepository) ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]*model.Domain, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "domains"),
+		),
+	)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx,
+		`SELECT `+domainColumns+` FROM domains WHERE workspace_id = $1 ORDER BY host`, workspaceID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []*model.Domain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		domains = append(domains, d)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return domains, nil
+}
+
+// Delete removes host from a workspace. It returns ErrDomainNotFound when
+// the workspace has no such domain and ErrDomainInUse while links created on
+// it still exist.
+func (r *DomainRepository) Delete(ctx context.Context, workspaceID uuid.UUID, host string) error {
+	ctx, span := tracer.Start(ctx, "db.delete",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "DELETE"),
+			attribute.String("db.sql.table", "domains"),
+		),
+	)
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM domains WHERE workspace_id = $1 AND host = $2`,
+		workspaceID, strings.ToLower(host))
+	if err != nil {
+		span.RecordError(err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrDomainInUse
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDomainNotFound
+	}
+	return nil
+}
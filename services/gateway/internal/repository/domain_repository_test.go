@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+func TestDomainRepository(t *testing.T) {
+	repo := NewDomainRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	acme := createTestWorkspace(t, "acme", "")
+	d := &model.Domain{Host: "Go.Brand-A.com", WorkspaceID: acme.ID}
+	require.NoError(t, repo.Create(ctx, d))
+	assert.NotEqual(t, uuid.Nil, d.ID)
+	assert.Equal(t, "go.brand-a.com", d.Host)
+	require.NoError(t, repo.Create(ctx, &model.Domain{Host: "brand-b.link", WorkspaceID: acme.ID}))
+
+	t.Run("looks up by host case-insensitively", func(t *testing.T) {
+		got, err := repo.GetByHost(ctx, "GO.brand-a.com")
+		require.NoError(t, err)
+		assert.Equal(t, acme.ID, got.WorkspaceID)
+
+		_, err = repo.GetByHost(ctx, "nowhere.example")
+		assert.ErrorIs(t, err, ErrDomainNotFound)
+	})
+
+	t.Run("every domain selects the workspace", func(t *testing.T) {
+		for _, host := range []string{"go.brand-a.com", "brand-b.link"} {
+			ws, err := NewWorkspaceRepository(testDB.Pool).GetByHost(ctx, host)
+			require.NoError(t, err)
+			assert.Equal(t, acme.ID, ws.ID)
+		}
+	})
+
+	t.Run("a host belongs to one workspace", func(t *testing.T) {
+		err := repo.Create(ctx, &model.Domain{Host: "brand-b.link", WorkspaceID: model.DefaultWorkspaceID})
+		assert.ErrorIs(t, err, ErrDomainConflict)
+	})
+
+	t.Run("lists per workspace", func(t *testing.T) {
+		domains, err := repo.ListByWorkspace(ctx, acme.ID)
+		require.NoError(t, err)
+		require.Len(t, domains, 2)
+		assert.Equal(t, "brand-b.link", domains[0].Host)
+
+		domains, err = repo.ListByWorkspace(ctx, model.DefaultWorkspaceID)
+		require.NoError(t, err)
+		assert.Empty(t, domains)
+	})
+
+	t.Run("domain with links cannot be removed", func(t *testing.T) {
+		urls := NewURLRepository(testDB.Pool)
+		wsCtx := tenant.WithWorkspace(ctx, acme)
+		require.NoError(t, urls.Create(wsCtx, &model.URL{ID: uuid.New(), ShortCode: "sale",
+			OriginalURL: "https://brand-a.example/sale", WorkspaceID: acme.ID, Domain: "go.brand-a.com"}))
+
+		got, err := urls.GetByCode(wsCtx, "sale")
+		require.NoError(t, err)
+		assert.Equal(t, "go.brand-a.com", got.Domain)
+
+		assert.ErrorIs(t, repo.Delete(ctx, acme.ID, "go.brand-a.com"), ErrDomainInUse)
+		require.NoError(t, urls.Delete(wsCtx, "sale"))
+		assert.NoError(t, repo.Delete(ctx, acme.ID, "go.brand-a.com"))
+	})
+
+	t.Run("remove only within the owning workspace", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Delete(ctx, model.DefaultWorkspaceID, "brand-b.link"), ErrDomainNotFound)
+		assert.NoError(t, repo.Delete(ctx, acme.ID, "BRAND-B.link"))
+	})
+}
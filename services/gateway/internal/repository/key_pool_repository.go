@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// KeyPoolRepository stores pre-generated short codes waiting to be leased.
+type KeyPoolRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewKeyPoolRepository creates a new key pool repository
+func NewKeyPoolRepository(db *pgxpool.Pool) *KeyPoolRepository {
+	return &KeyPoolRepository{db: db}
+}
+
+// Depth returns the number of pooled codes.
+func (r *KeyPoolRepository) Depth(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "short_code_pool"),
+		),
+	)
+	defer span.End()
+
+	var n int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM short_code_pool`).Scan(&n); err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// Add inserts codes into the pool and returns how many were new. Codes that
+// are already pooled or used by a link in any workspace are skipped, so a
+// leased code only collides with a custom alias taken in the meantime.
+func (r *KeyPoolRepository) Add(ctx context.Context, codes []string) (int64, error) {
+	return r.insert(ctx, codes)
+}
+
+// Release returns leased but unused codes to the pool, except any that a
+// link has taken since.
+func (r *KeyPoolRepository) Release(ctx context.Context, codes []string) error {
+	_, err := r.insert(ctx, codes)
+	return err
+}
+
+func (r *KeyPoolRepository) insert(ctx context.Context, codes []string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", "short_code_pool"),
+			attribute.Int("batch.size", len(codes)),
+		),
+	)
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO short_code_pool (code)
+		SELECT c.code FROM unnest($1::text[]) AS c(code)
+		WHERE NOT EXISTS (SELECT 1 FROM urls u WHERE u.short_code = c.code)
+		ON CONFLICT (code) DO NOTHING`,
+		codes,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DropShorter removes pooled codes shorter than length, left over from
+// before new codes were lengthened, and returns how many were removed.
+func (r *KeyPoolRepository) DropShorter(ctx context.Context, length int) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.delete",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "DELETE"),
+			attribute.String("db.sql.table", "short_code_pool"),
+		),
+	)
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM short_code_pool WHERE length(code) < $1`, length)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Lease removes up to n codes from the pool and returns them. Rows locked
+// by a concurrent lease are skipped, so replicas leasing at the same time
+// get disjoint codes without waiting on each other.
+func (r *KeyPoolRepository) Lease(ctx context.Context, n int) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "db.delete",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "DELETE"),
+			attribute.String("db.sql.table", "short_code_pool"),
+			attribute.Int("batch.limit", n),
+		),
+	)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, `
+		WITH leased AS (
+			SELECT code FROM short_code_pool
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		DELETE FROM short_code_pool p
+		USING leased l
+		WHERE p.code = l.code
+		RETURNING p.code`,
+		n,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("db.rows_affected", len(codes)))
+	return codes, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestKeyPoolRepository(t *testing.T) {
+	repo := NewKeyPoolRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	n, err := repo.Add(ctx, []string{"aaaaaa", "bbbbbb", "cccccc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	n, err = repo.Add(ctx, []string{"cccccc", "dddddd"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "pooled codes are skipped")
+
+	depth, err := repo.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), depth)
+
+	t.Run("lease removes codes", func(t *testing.T) {
+		first, err := repo.Lease(ctx, 3)
+		require.NoError(t, err)
+		assert.Len(t, first, 3)
+		rest, err := repo.Lease(ctx, 3)
+		require.NoError(t, err)
+		assert.Len(t, rest, 1)
+		assert.NotContains(t, first, rest[0])
+
+		empty, err := repo.Lease(ctx, 3)
+		require.NoError(t, err)
+		assert.Empty(t, empty)
+
+		require.NoError(t, repo.Release(ctx, first))
+		depth, err := repo.Depth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), depth)
+	})
+
+	t.Run("codes used by a link are not pooled", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		require.NoError(t, NewURLRepository(testDB.Pool).Create(ctx, &model.URL{
+			ID: uuid.New(), ShortCode: "taken1", OriginalURL: "https://example.com",
+		}))
+
+		n, err := repo.Add(ctx, []string{"taken1", "free01"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		require.NoError(t, repo.Release(ctx, []string{"taken1"}))
+		codes, err := repo.Lease(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"free01"}, codes)
+	})
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StatsRepository reads click statistics from the analytics and rollup
+// tables written by the analytics worker.
+type StatsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewStatsRepository creates a new stats repository
+func NewStatsRepository(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// GetStats computes totals, a bucketed histogram, top referers, clicks per
+// A/B variant and top UTM campaigns for one short code of the context's
+// workspace over [filter.From, filter.To). The five aggregates are sent as
+// one pgx batch, so the whole call costs a single round-trip.
+//
+// Hour and day buckets read totals, the histogram and referers from the
+// rollup tables of the same granularity, so their cost grows with the number
+// of buckets rather than clicks; unique IPs are then an estimate merged from
+// the per-bucket sketches, and referers are reported by host. Minute
+// buckets, variants and campaigns are not rolled up and read the raw
+// analytics rows.
+//
+// Only non-empty buckets are returned; filling gaps is left to the caller,
+// which knows the full range. Buckets are truncated in UTC so day boundaries
+// do not depend on the session time zone.
+func (r *StatsRepository) GetStats(ctx context.Context, filter model.StatsFilter) (*model.ClickStats, error) {
+	rollups := filter.Bucket == model.StatsBucketHour || filter.Bucket == model.StatsBucketDay
+	table := "analytics"
+	if rollups {
+		table = "analytics_rollups"
+	}
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", table),
+			attribute.String("short_code", filter.ShortCode),
+			attribute.String("stats.bucket", filter.Bucket),
+		),
+	)
+	defer span.End()
+
+	const where = `workspace_id = $1 AND short_code = $2 AND clicked_at >= $3 AND clicked_at < $4`
+	ws := tenant.WorkspaceID(ctx)
+
+	batch := &pgx.Batch{}
+	if rollups {
+		// From and To are aligned to the bucket width, so whole rollup
+		// buckets cover the range exactly.
+		const rollupWhere = `workspace_id = $1 AND short_code = $2 AND bucket >= $3 AND bucket < $4 AND granularity = $5`
+		batch.Queue(`
+			SELECT COALESCE(SUM(clicks), 0), COALESCE(analytics_unique_ips(bit_or(ip_sketch)), 0)
+			FROM analytics_rollups
+			WHERE `+rollupWhere,
+			ws, filter.ShortCode, filter.From, filter.To, filter.Bucket)
+		batch.Queue(`
+			SELECT bucket, clicks
+			FROM analytics_rollups
+			WHERE `+rollupWhere+`
+			ORDER BY bucket`,
+			ws, filter.ShortCode, filter.From, filter.To, filter.Bucket)
+		batch.Queue(`
+			SELECT referer_host, SUM(clicks) AS clicks
+			FROM analytics_referer_rollups
+			WHERE `+rollupWhere+`
+			GROUP BY referer_host
+			ORDER BY clicks DESC, referer_host
+			LIMIT $6`,
+			ws, filter.ShortCode, filter.From, filter.To, filter.Bucket, filter.TopN)
+	} else {
+		batch.Queue(`SELECT COUNT(*), COUNT(DISTINCT ip) FROM analytics WHERE `+where,
+			ws, filter.ShortCode, filter.From, filter.To)
+		batch.Queue(`
+			SELECT date_trunc($5, clicked_at, 'UTC') AS bucket, COUNT(*)
+			FROM analytics
+			WHERE `+where+`
+			GROUP BY bucket
+			ORDER BY bucket`,
+			ws, filter.ShortCode, filter.From, filter.To, filter.Bucket)
+		batch.Queue(`
+			SELECT COALESCE(referer, '') AS ref, COUNT(*) AS clicks
+			FROM analytics
+			WHERE `+where+`
+			GROUP BY ref
+			ORDER BY clicks DESC, ref
+			LIMIT $5`,
+			ws, filter.ShortCode, filter.From, filter.To, filter.TopN)
+	}
+	batch.Queue(`
+		SELECT variant, COUNT(*) AS clicks
+		FROM analytics
+		WHERE `+where+` AND variant IS NOT NULL
+		GROUP BY variant
+		ORDER BY clicks DESC, variant`,
+		ws, filter.ShortCode, filter.From, filter.To)
+	batch.Queue(`
+		SELECT COALESCE(utm_source, '') AS source, COALESCE(utm_medium, '') AS medium,
+		       COALESCE(utm_campaign, '') AS campaign, COUNT(*) AS clicks
+		FROM analytics
+		WHERE `+where+` AND (utm_source IS NOT NULL OR utm_medium IS NOT NULL OR utm_campaign IS NOT NULL)
+		GROUP BY source, medium, campaign
+		ORDER BY clicks DESC, source, medium, campaign
+		LIMIT $5`,
+		ws, filter.ShortCode, filter.From, filter.To, filter.TopN)
+
+	results := r.db.SendBatch(ctx, batch)
+	defer results.Close()
+
+	stats := &model.ClickStats{
+		ShortCode:   filter.ShortCode,
+		From:        filter.From,
+		To:          filter.To,
+		Bucket:      filter.Bucket,
+		Series:      []model.StatsBucket{},
+		TopReferers: []model.RefererCount{},
+	}
+
+	if err := results.QueryRow().Scan(&stats.TotalClicks, &stats.UniqueIPs); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	rows, err := results.Query()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	for rows.Next() {
+		var b model.StatsBucket
+		if err := rows.Scan(&b.Start, &b.Clicks); err != nil {
+			rows.Close()
+			span.RecordError(err)
+			return nil, err
+		}
+		b.Start = b.Start.UTC()
+		stats.Series = append(stats.Series, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	rows, err = results.Query()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	for rows.Next() {
+		var rc model.RefererCount
+		if err := rows.Scan(&rc.Referer, &rc.Clicks); err != nil {
+			rows.Close()
+			span.RecordError(err)
+			return nil, err
+		}
+		stats.TopReferers = append(stats.TopReferers, rc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	rows, err = results.Query()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	for rows.Next() {
+		var vc model.VariantCount
+		if err := rows.Scan(&vc.Variant, &vc.Clicks); err != nil {
+			rows.Close()
+			span.RecordError(err)
+			return nil, err
+		}
+		stats.Variants = append(stats.Variants, vc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	rows, err = results.Query()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cc model.CampaignCount
+		if err := rows.Scan(&cc.Source, &cc.Medium, &cc.Campaign, &cc.Clicks); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		stats.Campaigns = append(stats.Campaigns, cc)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestStatsRepository_GetStats(t *testing.T) {
+	repo := NewStatsRepository(testDB.Pool)
+	ctx := context.Background()
+
+	base := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	seed := func(code string, at time.Time, ip, referer string) {
+		var ref any
+		if referer != "" {
+			ref = referer
+		}
+		_, err := testDB.Pool.Exec(ctx,
+			`INSERT INTO analytics (short_code, clicked_at, ip, referer) VALUES ($1, $2, $3, $4)`,
+			code, at, ip, ref)
+		require.NoError(t, err)
+	}
+
+	// rollup writes one rollup row and its referer rows the way the
+	// analytics worker does; ipBits are the sketch bits the clicks set.
+	rollup := func(code, granularity string, bucket time.Time, referers map[string]int64, ipBits ...int) {
+		sketch := []byte(strings.Repeat("0", 2048))
+		for _, b := range ipBits {
+			sketch[b] = '1'
+		}
+		var clicks int64
+		for host, n := range referers {
+			clicks += n
+			_, err := testDB.Pool.Exec(ctx,
+				`INSERT INTO analytics_referer_rollups (short_code, granularity, bucket, referer_host, clicks)
+				 VALUES ($1, $2, $3, $4, $5)`,
+				code, granularity, bucket, host, n)
+			require.NoError(t, err)
+		}
+		_, err := testDB.Pool.Exec(ctx,
+			`INSERT INTO analytics_rollups (short_code, granularity, bucket, clicks, ip_sketch)
+			 VALUES ($1, $2, $3, $4, $5::text::bit(2048))`,
+			code, granularity, bucket, clicks, string(sketch))
+		require.NoError(t, err)
+	}
+
+	t.Run("aggregates totals, buckets and referers within the range", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		seed("stats1", base.Add(10*time.Minute), "1.1.1.1", "https://a.example")
+		seed("stats1", base.Add(10*time.Minute+30*time.Second), "1.1.1.1", "https://a.example")
+		seed("stats1", base.Add(2*time.Hour+5*time.Minute), "2.2.2.2", "https://b.example")
+		seed("stats1", base.Add(2*time.Hour+6*time.Minute), "3.3.3.3", "")
+		seed("stats1", base.Add(-time.Minute), "9.9.9.9", "https://a.example") // before range
+		seed("other", base.Add(time.Minute), "1.1.1.1", "https://a.example")   // other code
+
+		stats, err := repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "stats1",
+			From:      base,
+			To:        base.Add(3 * time.Hour),
+			Bucket:    model.StatsBucketMinute,
+			TopN:      2,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(4), stats.TotalClicks)
+		assert.Equal(t, int64(3), stats.UniqueIPs)
+		assert.Equal(t, []model.StatsBucket{
+			{Start: base.Add(10 * time.Minute), Clicks: 2},
+			{Start: base.Add(2*time.Hour + 5*time.Minute), Clicks: 1},
+			{Start: base.Add(2*time.Hour + 6*time.Minute), Clicks: 1},
+		}, stats.Series)
+		assert.Equal(t, []model.RefererCount{
+			{Referer: "https://a.example", Clicks: 2},
+			{Referer: "", Clicks: 1},
+		}, stats.TopReferers)
+	})
+
+	t.Run("reads hour and day buckets from the rollups", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		rollup("stats1", "hour", base, map[string]int64{"a.example": 2}, 7)
+		rollup("stats1", "hour", base.Add(2*time.Hour), map[string]int64{"b.example": 1, "": 1}, 7, 100, 2000)
+		rollup("stats1", "hour", base.Add(-time.Hour), map[string]int64{"a.example": 5}, 1) // before range
+		rollup("stats1", "day", base, map[string]int64{"a.example": 9}, 1)                  // other granularity
+		rollup("other", "hour", base, map[string]int64{"a.example": 5}, 1)                  // other code
+		seed("stats1", base.Add(time.Minute), "4.4.4.4", "https://c.example")               // raw rows are not read
+
+		stats, err := repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "stats1",
+			From:      base,
+			To:        base.Add(3 * time.Hour),
+			Bucket:    model.StatsBucketHour,
+			TopN:      2,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(4), stats.TotalClicks)
+		assert.Equal(t, int64(3), stats.UniqueIPs, "sketches are merged before estimating")
+		assert.Equal(t, []model.StatsBucket{
+			{Start: base, Clicks: 2},
+			{Start: base.Add(2 * time.Hour), Clicks: 2},
+		}, stats.Series)
+		assert.Equal(t, []model.RefererCount{
+			{Referer: "a.example", Clicks: 2},
+			{Referer: "", Clicks: 1},
+		}, stats.TopReferers)
+
+		stats, err = repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "stats1",
+			From:      base,
+			To:        base.Add(24 * time.Hour),
+			Bucket:    model.StatsBucketDay,
+			TopN:      2,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), stats.TotalClicks)
+		assert.Equal(t, int64(1), stats.UniqueIPs)
+		assert.Equal(t, []model.StatsBucket{{Start: base, Clicks: 9}}, stats.Series)
+	})
+
+	t.Run("returns empty slices when there are no clicks", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		stats, err := repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "quiet",
+			From:      base,
+			To:        base.Add(24 * time.Hour),
+			Bucket:    model.StatsBucketDay,
+			TopN:      10,
+		})
+		require.NoError(t, err)
+		assert.Zero(t, stats.TotalClicks)
+		assert.NotNil(t, stats.Series)
+		assert.NotNil(t, stats.TopReferers)
+		assert.Empty(t, stats.Variants)
+	})
+
+	t.Run("counts clicks per variant", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		for _, v := range []string{"a", "b", "b", ""} {
+			var variant any
+			if v != "" {
+				variant = v
+			}
+			_, err := testDB.Pool.Exec(ctx,
+				`INSERT INTO analytics (short_code, clicked_at, ip, variant) VALUES ($1, $2, $3, $4)`,
+				"split", base.Add(time.Minute), "1.1.1.1", variant)
+			require.NoError(t, err)
+		}
+
+		stats, err := repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "split",
+			From:      base,
+			To:        base.Add(time.Hour),
+			Bucket:    model.StatsBucketHour,
+			TopN:      10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []model.VariantCount{
+			{Variant: "b", Clicks: 2},
+			{Variant: "a", Clicks: 1},
+		}, stats.Variants)
+	})
+
+	t.Run("counts clicks per UTM campaign", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		for _, campaign := range []string{"spring", "summer", "summer", ""} {
+			var source, c any
+			if campaign != "" {
+				source, c = "newsletter", campaign
+			}
+			_, err := testDB.Pool.Exec(ctx,
+				`INSERT INTO analytics (short_code, clicked_at, ip, utm_source, utm_campaign) VALUES ($1, $2, $3, $4, $5)`,
+				"tagged", base.Add(time.Minute), "1.1.1.1", source, c)
+			require.NoError(t, err)
+		}
+
+		stats, err := repo.GetStats(ctx, model.StatsFilter{
+			ShortCode: "tagged",
+			From:      base,
+			To:        base.Add(time.Hour),
+			Bucket:    model.StatsBucketHour,
+			TopN:      10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []model.CampaignCount{
+			{Source: "newsletter", Campaign: "summer", Clicks: 2},
+			{Source: "newsletter", Campaign: "spring", Clicks: 1},
+		}, stats.Campaigns)
+	})
+}
@@ -2,12 +2,18 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -15,9 +21,21 @@ import (
 var (
 	ErrNotFound     = errors.New("url not found")
 	ErrCodeConflict = errors.New("short code already exists")
+	// ErrUsesExhausted is returned by ConsumeUse once a link has redirected
+	// max_clicks times.
+	ErrUsesExhausted = errors.New("click limit reached")
+	// ErrQuotaExceeded is returned by Create and CreateBatch when the
+	// workspace already holds max_links links.
+	ErrQuotaExceeded = errors.New("workspace link quota exceeded")
+	// ErrInvalidSchedule is returned by Update when the link would no
+	// longer activate before it expires.
+	ErrInvalidSchedule = errors.New("not_before must be before expires_at")
 )
 
-// URLRepository handles database operations for URLs
+// URLRepository handles database operations for URLs.
+// Short codes are unique per workspace: every lookup is scoped to the
+// workspace in the context (tenant.WorkspaceID), and inserts use the one set
+// on the URL.
 type URLRepository struct {
 	db *pgxpool.Pool
 }
@@ -27,6 +45,84 @@ func NewURLRepository(db *pgxpool.Pool) *URLRepository {
 	return &URLRepository{db: db}
 }
 
+// urlColumns is the select list read by scanURL.
+const urlColumns = `id, short_code, original_url, created_at, expires_at, COALESCE(click_count, 0), COALESCE(owner, ''), workspace_id, COALESCE(domain, ''),
+	COALESCE(redirect_type, 0), forward_query, COALESCE(password_hash, ''), COALESCE(max_clicks, 0), uses,
+	not_before, COALESCE(fallback_url, ''), rules, variants, sticky_variants, tracking`
+
+// scanURL scans one row selected with urlColumns.
+func scanURL(row pgx.Row) (*model.URL, error) {
+	var url model.URL
+	if err := row.Scan(&url.ID,
+		&url.ShortCode,
+		&url.OriginalURL,
+		&url.CreatedAt,
+		&url.ExpiresAt,
+		&url.ClickCount,
+		&url.Owner,
+		&url.WorkspaceID,
+		&url.Domain,
+		&url.RedirectType,
+		&url.ForwardQuery,
+		&url.PasswordHash,
+		&url.MaxClicks,
+		&url.Uses,
+		&url.NotBefore,
+		&url.FallbackURL,
+		&url.Rules,
+		&url.Variants,
+		&url.StickyVariants,
+		&url.Tracking,
+	); err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
+// listJSON encodes redirect rules or variants for their JSONB column; an
+// empty list is NULL.
+func listJSON[T model.RedirectRule | model.Variant](items []T) []byte {
+	if len(items) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(items) // strings and ints only, cannot fail
+	return data
+}
+
+// updateListJSON encodes a rules or variants change: nil leaves the column
+// untouched and an empty list clears it.
+func updateListJSON[T model.RedirectRule | model.Variant](items *[]T) []byte {
+	if items == nil {
+		return nil
+	}
+	if len(*items) == 0 {
+		return []byte("[]")
+	}
+	return listJSON(*items)
+}
+
+// trackingJSON encodes tracking parameters for the tracking column; none is
+// NULL.
+func trackingJSON(t *model.TrackingParams) []byte {
+	if t.IsZero() {
+		return nil
+	}
+	data, _ := json.Marshal(t) // strings only, cannot fail
+	return data
+}
+
+// updateTrackingJSON encodes a tracking change: nil leaves the column
+// untouched and empty parameters clear it.
+func updateTrackingJSON(t *model.TrackingParams) []byte {
+	if t == nil {
+		return nil
+	}
+	if t.IsZero() {
+		return []byte("{}")
+	}
+	return trackingJSON(t)
+}
+
 // Create inserts a new URL record into the database
 func (r *URLRepository) Create(ctx context.Context, url *model.URL) error {
 	ctx, span := tracer.Start(ctx, "db.insert",
@@ -39,12 +135,16 @@ func (r *URLRepository) Create(ctx context.Context, url *model.URL) error {
 	)
 	defer span.End()
 
-	// Insert a new URL record. If the short code already exists the
-	// database will return a unique-constraint error which we map to
-	// ErrCodeConflict so callers can handle alias collisions.
+	// Insert a new URL record. If the short code already exists in the
+	// workspace the database will return a unique-constraint error which we
+	// map to ErrCodeConflict so callers can handle alias collisions. A full
+	// workspace fails the quota trigger, mapped to ErrQuotaExceeded.
 	query := `
-		INSERT INTO urls (id, short_code, original_url, expires_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO urls (id, short_code, original_url, expires_at, owner, workspace_id, domain,
+		                  redirect_type, forward_query, password_hash, max_clicks, not_before, fallback_url, rules,
+		                  variants, sticky_variants, tracking, canonical_hash)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8::smallint, 0), $9, NULLIF($10, ''),
+		        NULLIF($11::bigint, 0), $12, NULLIF($13, ''), $14, $15, $16, $17, $18)
 		RETURNING id, created_at
 	`
 	err := r.db.QueryRow(
@@ -54,6 +154,20 @@ func (r *URLRepository) Create(ctx context.Context, url *model.URL) error {
 		url.ShortCode,
 		url.OriginalURL,
 		url.ExpiresAt,
+		url.Owner,
+		url.WorkspaceID,
+		url.Domain,
+		url.RedirectType,
+		url.ForwardQuery,
+		url.PasswordHash,
+		url.MaxClicks,
+		url.NotBefore,
+		url.FallbackURL,
+		listJSON(url.Rules),
+		listJSON(url.Variants),
+		url.StickyVariants,
+		trackingJSON(url.Tracking),
+		url.CanonicalHash,
 	).Scan(&url.ID, &url.CreatedAt)
 
 	if err != nil {
@@ -62,13 +176,101 @@ func (r *URLRepository) Create(ctx context.Context, url *model.URL) error {
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return ErrCodeConflict
 		}
-		return err
+		return quotaError(err)
 	}
 
 	return nil
 }
 
-// GetByCode retrieves a URL by its short code
+// quotaError maps a failure of the workspace_link_quota trigger to
+// ErrQuotaExceeded and returns other errors unchanged.
+func quotaError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "workspace_link_quota" {
+		return ErrQuotaExceeded
+	}
+	return err
+}
+
+// CreateBatch inserts all URLs in a single statement — one round-trip and
+// one implicit transaction regardless of batch size.
+//
+// Rows whose short code already exists (in the table or earlier in the same
+// batch) are skipped via ON CONFLICT DO NOTHING rather than aborting the whole
+// statement. The returned slice has one entry per input URL: nil when the row
+// was inserted (ID and CreatedAt are populated) or ErrCodeConflict. A batch
+// that would take its workspace past max_links inserts nothing and fails
+// with ErrQuotaExceeded.
+func (r *URLRepository) CreateBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.Int("batch.size", len(urls)),
+		),
+	)
+	defer span.End()
+
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	// Each URL occupies 18 consecutive positional parameters.
+	placeholders := make([]string, len(urls))
+	args := make([]any, 0, len(urls)*18)
+	for i, u := range urls {
+		base := i * 18
+		placeholders[i] = fmt.Sprintf("($%d,$%d,$%d,$%d,NULLIF($%d,''),$%d,NULLIF($%d,''),NULLIF($%d::smallint,0),$%d,NULLIF($%d,''),NULLIF($%d::bigint,0),$%d,NULLIF($%d,''),$%d::jsonb,$%d::jsonb,$%d::boolean,$%d::jsonb,$%d::bytea)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12, base+13, base+14, base+15, base+16, base+17, base+18)
+		args = append(args, u.ID, u.ShortCode, u.OriginalURL, u.ExpiresAt, u.Owner, u.WorkspaceID, u.Domain,
+			u.RedirectType, u.ForwardQuery, u.PasswordHash, u.MaxClicks, u.NotBefore, u.FallbackURL, listJSON(u.Rules),
+			listJSON(u.Variants), u.StickyVariants, trackingJSON(u.Tracking), u.CanonicalHash)
+	}
+
+	query := "INSERT INTO urls (id, short_code, original_url, expires_at, owner, workspace_id, domain, " +
+		"redirect_type, forward_query, password_hash, max_clicks, not_before, fallback_url, rules, variants, sticky_variants, tracking, " +
+		"canonical_hash) VALUES " +
+		strings.Join(placeholders, ", ") +
+		" ON CONFLICT (workspace_id, short_code) DO NOTHING RETURNING id, created_at"
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, quotaError(err)
+	}
+	defer rows.Close()
+
+	// IDs are generated by the caller and unique per row, so they identify
+	// which inputs were inserted even when short codes repeat in the batch.
+	inserted := make(map[uuid.UUID]time.Time, len(urls))
+	for rows.Next() {
+		var id uuid.UUID
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		inserted[id] = createdAt
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, quotaError(err)
+	}
+
+	results := make([]error, len(urls))
+	for i, u := range urls {
+		createdAt, ok := inserted[u.ID]
+		if !ok {
+			results[i] = ErrCodeConflict
+			continue
+		}
+		u.CreatedAt = createdAt
+	}
+	return results, nil
+}
+
+// GetByCode retrieves a URL by its short code in the context's workspace.
 func (r *URLRepository) GetByCode(ctx context.Context, code string) (*model.URL, error) {
 	ctx, span := tracer.Start(ctx, "db.select",
 		trace.WithAttributes(
@@ -80,18 +282,46 @@ func (r *URLRepository) GetByCode(ctx context.Context, code string) (*model.URL,
 	)
 	defer span.End()
 
-	query :=
-		`SELECT id, short_code, original_url, created_at, expires_at
-		FROM urls
-		WHERE short_code = $1`
-	var url model.URL
-	err := r.db.QueryRow(ctx, query, code).Scan(&url.ID,
-		&url.ShortCode,
-		&url.OriginalURL,
-		&url.CreatedAt,
-		&url.ExpiresAt,
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE workspace_id = $1 AND short_code = $2`
+	url, err := scanURL(r.db.QueryRow(ctx, query, tenant.WorkspaceID(ctx), code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+	return url, nil
+}
+
+// FindReusable returns the newest unexpired link of the context's workspace
+// whose canonical destination hashes to hash, with the given owner and
+// domain ("" = none) and no options of its own: a link with a password,
+// click limit, schedule, rules, variants, tracking or non-default redirect
+// behaviour is never handed to a caller who did not ask for it. Returns
+// ErrNotFound when there is none.
+func (r *URLRepository) FindReusable(ctx context.Context, hash []byte, owner, domain string) (*model.URL, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "urls"),
+		),
 	)
+	defer span.End()
 
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE workspace_id = $1 AND canonical_hash = $2
+		  AND COALESCE(owner, '') = $3 AND COALESCE(domain, '') = $4
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND password_hash IS NULL AND max_clicks IS NULL AND not_before IS NULL
+		  AND rules IS NULL AND variants IS NULL AND tracking IS NULL
+		  AND redirect_type IS NULL AND NOT forward_query AND fallback_url IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1`
+	url, err := scanURL(r.db.QueryRow(ctx, query, tenant.WorkspaceID(ctx), hash, owner, domain))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -99,10 +329,10 @@ func (r *URLRepository) GetByCode(ctx context.Context, code string) (*model.URL,
 		span.RecordError(err)
 		return nil, err
 	}
-	return &url, nil
+	return url, nil
 }
 
-// Delete removes a URL by its short code
+// Delete removes a URL by its short code in the context's workspace.
 func (r *URLRepository) Delete(ctx context.Context, code string) error {
 	ctx, span := tracer.Start(ctx, "db.delete",
 		trace.WithAttributes(
@@ -116,8 +346,8 @@ func (r *URLRepository) Delete(ctx context.Context, code string) error {
 
 	// Delete a URL by short code and return ErrNotFound when no rows
 	// are affected so callers can translate to a 404 response.
-	query := `DELETE FROM urls WHERE short_code=$1`
-	result, err := r.db.Exec(ctx, query, code)
+	query := `DELETE FROM urls WHERE workspace_id = $1 AND short_code = $2`
+	result, err := r.db.Exec(ctx, query, tenant.WorkspaceID(ctx), code)
 	if err != nil {
 		span.RecordError(err)
 		return err
@@ -128,9 +358,383 @@ func (r *URLRepository) Delete(ctx context.Context, code string) error {
 	return nil
 }
 
-// // IncrementClickCount increments the click counter for a URL
-// func (r *URLRepository) IncrementClickCount(ctx context.Context, code string) error {
-// 	// TODO: Implement click count increment
-// 	// - UPDATE urls SET click_count = click_count + 1 WHERE short_code = $1
-// 	return nil
-// }
+// DeleteExpired removes up to limit URLs that expired before the given time,
+// across all workspaces, and returns references to them. Rows are claimed with SKIP LOCKED so a
+// concurrent update of the same link is never blocked on, and the expires_at
+// index keeps each batch cheap regardless of table size.
+func (r *URLRepository) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]model.LinkRef, error) {
+	ctx, span := tracer.Start(ctx, "db.delete",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "DELETE"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.Int("batch.limit", limit),
+		),
+	)
+	defer span.End()
+
+	query := `
+		WITH expired AS (
+			SELECT id FROM urls
+			WHERE expires_at < $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		DELETE FROM urls u
+		USING expired e
+		WHERE u.id = e.id
+		RETURNING u.workspace_id, u.short_code`
+	rows, err := r.db.Query(ctx, query, before, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []model.LinkRef
+	for rows.Next() {
+		var ref model.LinkRef
+		if err := rows.Scan(&ref.WorkspaceID, &ref.ShortCode); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("db.rows_affected", len(refs)))
+	return refs, nil
+}
+
+// Update applies the given changes to the URL with the given short code and
+// returns the updated row. Returns ErrNotFound when no row matches, and
+// ErrInvalidSchedule when the resulting not_before would not precede the
+// resulting expires_at.
+func (r *URLRepository) Update(ctx context.Context, code string, update model.URLUpdate) (*model.URL, error) {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("short_code", code),
+		),
+	)
+	defer span.End()
+
+	// A single statement keeps the change atomic: NULL parameters fall back
+	// to the current column value, $4 and $10 explicitly clear the expiry and
+	// activation time, a redirect type of 0 clears the per-link status, an
+	// empty password hash or fallback URL removes it, an empty rule or
+	// variant list removes all rules or variants and empty tracking
+	// parameters remove the tags. The canonical hash follows a new
+	// destination only on links that have one, so custom aliases never
+	// become reusable. The activation window is checked against the row
+	// being written, so an update setting one bound cannot race another
+	// setting the other.
+	query := `
+		UPDATE urls
+		SET original_url = COALESCE($2, original_url),
+		    expires_at = CASE WHEN $4 THEN NULL ELSE COALESCE($3, expires_at) END,
+		    redirect_type = CASE WHEN $6::smallint IS NULL THEN redirect_type ELSE NULLIF($6::smallint, 0) END,
+		    forward_query = COALESCE($7, forward_query),
+		    password_hash = CASE WHEN $8::text IS NULL THEN password_hash ELSE NULLIF($8::text, '') END,
+		    not_before = CASE WHEN $10 THEN NULL ELSE COALESCE($9, not_before) END,
+		    fallback_url = CASE WHEN $11::text IS NULL THEN fallback_url ELSE NULLIF($11::text, '') END,
+		    rules = CASE WHEN $12::jsonb IS NULL THEN rules ELSE NULLIF($12::jsonb, '[]'::jsonb) END,
+		    variants = CASE WHEN $13::jsonb IS NULL THEN variants ELSE NULLIF($13::jsonb, '[]'::jsonb) END,
+		    sticky_variants = COALESCE($14, sticky_variants),
+		    tracking = CASE WHEN $15::jsonb IS NULL THEN tracking ELSE NULLIF($15::jsonb, '{}'::jsonb) END,
+		    canonical_hash = CASE WHEN canonical_hash IS NULL THEN NULL ELSE COALESCE($16, canonical_hash) END
+		WHERE workspace_id = $5 AND short_code = $1
+		  AND COALESCE(
+		    (CASE WHEN $10 THEN NULL ELSE COALESCE($9, not_before) END) <
+		    (CASE WHEN $4 THEN NULL ELSE COALESCE($3, expires_at) END), TRUE)
+		RETURNING ` + urlColumns
+	url, err := scanURL(r.db.QueryRow(ctx, query,
+		code,
+		update.OriginalURL,
+		update.ExpiresAt,
+		update.ClearExpiry,
+		tenant.WorkspaceID(ctx),
+		update.RedirectType,
+		update.ForwardQuery,
+		update.PasswordHash,
+		update.NotBefore,
+		update.ClearNotBefore,
+		update.FallbackURL,
+		updateListJSON(update.Rules),
+		updateListJSON(update.Variants),
+		update.StickyVariants,
+		updateTrackingJSON(update.Tracking),
+		update.CanonicalHash,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.updateMiss(ctx, code)
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+	return url, nil
+}
+
+// updateMiss tells why Update changed no row: the link is missing, or the
+// schedule check refused the change.
+func (r *URLRepository) updateMiss(ctx context.Context, code string) error {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM urls WHERE workspace_id = $1 AND short_code = $2)`,
+		tenant.WorkspaceID(ctx), code,
+	).Scan(&exists)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return ErrInvalidSchedule
+	default:
+		return ErrNotFound
+	}
+}
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// List returns up to filter.Limit URLs of the context's workspace matching
+// filter, ordered by
+// filter.SortBy with the row ID as a tie-breaker.
+//
+// Pagination is keyset-based: when filter.After is set, only rows strictly
+// after that (sort value, id) position are returned, so pages stay stable
+// while new links are being created.
+func (r *URLRepository) List(ctx context.Context, filter model.URLFilter) ([]*model.URL, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("list.sort", filter.SortBy),
+		),
+	)
+	defer span.End()
+
+	var (
+		conds []string
+		args  []any
+	)
+	// arg appends v to args and returns its positional placeholder ($N).
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds = append(conds, "workspace_id = "+arg(tenant.WorkspaceID(ctx)))
+	if filter.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedBefore))
+	}
+	switch filter.Status {
+	case model.StatusActive:
+		conds = append(conds, "(expires_at IS NULL OR expires_at > NOW())", "(not_before IS NULL OR not_before <= NOW())")
+	case model.StatusScheduled:
+		conds = append(conds, "not_before > NOW()")
+	case model.StatusExpired:
+		conds = append(conds, "expires_at <= NOW()")
+	}
+	if filter.Owner != "" {
+		conds = append(conds, "owner = "+arg(filter.Owner))
+	}
+	if filter.Query != "" {
+		conds = append(conds, "original_url ILIKE '%' || "+arg(likeEscaper.Replace(filter.Query))+" || '%'")
+	}
+
+	sortCol := "created_at"
+	if filter.SortBy == model.SortByClickCount {
+		sortCol = "COALESCE(click_count, 0)"
+	}
+	dir, cmp := "ASC", ">"
+	if filter.Descending {
+		dir, cmp = "DESC", "<"
+	}
+	if filter.After != nil {
+		var after any = filter.After.CreatedAt
+		if filter.SortBy == model.SortByClickCount {
+			after = filter.After.ClickCount
+		}
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", sortCol, cmp, arg(after), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE ` + strings.Join(conds, " AND ")
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", sortCol, dir, dir, arg(filter.Limit))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := make([]*model.URL, 0, filter.Limit)
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return urls, nil
+}
+
+// GetClickCount returns only the click counter for a URL.
+// The counter is maintained by the analytics worker, which adds each flushed
+// batch's clicks in the same transaction that stores the events.
+func (r *URLRepository) GetClickCount(ctx context.Context, code string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("short_code", code),
+		),
+	)
+	defer span.End()
+
+	var count int64
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(click_count, 0) FROM urls WHERE workspace_id = $1 AND short_code = $2`,
+		tenant.WorkspaceID(ctx), code,
+	).Scan(&count)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		span.RecordError(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ConsumeUse grants one visit of a click-limited link and returns the
+// number of visits granted so far. The check and increment are a single
+// statement, so concurrent visits can never exceed max_clicks; once they
+// have, ErrUsesExhausted is returned. Links without a limit, or missing
+// ones, also return ErrUsesExhausted: callers only consume uses of links
+// they have already resolved.
+func (r *URLRepository) ConsumeUse(ctx context.Context, code string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("short_code", code),
+		),
+	)
+	defer span.End()
+
+	var uses int64
+	err := r.db.QueryRow(ctx, `
+		UPDATE urls SET uses = uses + 1
+		WHERE workspace_id = $1 AND short_code = $2 AND uses < max_clicks
+		RETURNING uses`,
+		tenant.WorkspaceID(ctx), code,
+	).Scan(&uses)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrUsesExhausted
+		}
+		span.RecordError(err)
+		return 0, err
+	}
+	return uses, nil
+}
+
+// SyncUses records one visit already granted by a counter kept elsewhere.
+// The write is conditional like ConsumeUse's, so a visit the DB has already
+// granted on its own, e.g. while the counter was unreachable, is not
+// granted twice: ErrUsesExhausted is returned when no row changes. Each
+// call adds one use rather than copying the counter's value, so concurrent
+// visits may record out of order without refusing each other.
+func (r *URLRepository) SyncUses(ctx context.Context, code string) error {
+	ctx, span := tracer.Start(ctx, "db.update",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "UPDATE"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("short_code", code),
+		),
+	)
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx,
+		`UPDATE urls SET uses = uses + 1
+		WHERE workspace_id = $1 AND short_code = $2 AND uses < max_clicks`,
+		tenant.WorkspaceID(ctx), code,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUsesExhausted
+	}
+	return nil
+}
+
+// GetUses returns how many visits of a click-limited link have been granted.
+func (r *URLRepository) GetUses(ctx context.Context, code string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "urls"),
+			attribute.String("short_code", code),
+		),
+	)
+	defer span.End()
+
+	var uses int64
+	err := r.db.QueryRow(ctx,
+		`SELECT uses FROM urls WHERE workspace_id = $1 AND short_code = $2`,
+		tenant.WorkspaceID(ctx), code,
+	).Scan(&uses)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		span.RecordError(err)
+		return 0, err
+	}
+	return uses, nil
+}
+
+// Count returns how many links the context's workspace holds, expired ones
+// included until the reaper removes them.
+func (r *URLRepository) Count(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "urls"),
+		),
+	)
+	defer span.End()
+
+	var count int64
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM urls WHERE workspace_id = $1`, tenant.WorkspaceID(ctx),
+	).Scan(&count)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -115,6 +115,60 @@ func TestURLRepository_Create(t *testing.T) {
 	})
 }
 
+func TestURLRepository_CreateBatch(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+
+	t.Run("success - inserts all rows in one statement", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		urls := []*model.URL{
+			{ID: uuid.New(), ShortCode: "batch1", OriginalURL: "https://example.com/1"},
+			{ID: uuid.New(), ShortCode: "batch2", OriginalURL: "https://example.com/2"},
+		}
+
+		results, err := repo.CreateBatch(ctx, urls)
+		require.NoError(t, err)
+		assert.Equal(t, []error{nil, nil}, results)
+		assert.False(t, urls[0].CreatedAt.IsZero(), "expected created_at to be populated")
+
+		var count int
+		testDB.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM urls WHERE short_code IN ('batch1', 'batch2')").Scan(&count)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("partial - conflicts are reported per item without aborting", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		testDB.Pool.Exec(ctx, `
+			INSERT INTO urls (id, short_code, original_url, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, uuid.New(), "taken1", "https://example.com/existing", time.Now())
+
+		urls := []*model.URL{
+			{ID: uuid.New(), ShortCode: "taken1", OriginalURL: "https://example.com/a"},
+			{ID: uuid.New(), ShortCode: "fresh1", OriginalURL: "https://example.com/b"},
+			{ID: uuid.New(), ShortCode: "fresh1", OriginalURL: "https://example.com/c"},
+		}
+
+		results, err := repo.CreateBatch(ctx, urls)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.ErrorIs(t, results[0], ErrCodeConflict)
+
+		// Exactly one of the in-batch duplicates wins
+		wins := 0
+		for _, r := range results[1:] {
+			if r == nil {
+				wins++
+			} else {
+				assert.ErrorIs(t, r, ErrCodeConflict)
+			}
+		}
+		assert.Equal(t, 1, wins)
+	})
+}
+
 func TestURLRepository_GetByCode(t *testing.T) {
 	repo := NewURLRepository(testDB.Pool)
 	ctx := context.Background()
@@ -156,6 +210,26 @@ func TestURLRepository_GetByCode(t *testing.T) {
 		assert.Nil(t, url.ExpiresAt, "expected expires_at to be nil")
 	})
 
+	t.Run("success - returns stored click count", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		testDB.Pool.Exec(ctx, `
+            INSERT INTO urls (id, short_code, original_url, created_at, click_count)
+            VALUES ($1, $2, $3, $4, $5)
+        `, uuid.New(), "clicked", "https://example.com/clicked", time.Now(), 42)
+
+		url, err := repo.GetByCode(ctx, "clicked")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), url.ClickCount)
+
+		count, err := repo.GetClickCount(ctx, "clicked")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+
+		_, err = repo.GetClickCount(ctx, "notexist")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
 	t.Run("error - not found", func(t *testing.T) {
 		testDB.Cleanup(ctx)
 
@@ -242,3 +316,289 @@ func TestURLRepository_Delete(t *testing.T) {
 		assert.Equal(t, 1, count, "expected other URL to still exist")
 	})
 }
+
+func TestURLRepository_FindReusable(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+	hash := []byte("0123456789abcdef0123456789abcdef")
+	past := time.Now().Add(-time.Hour)
+
+	create := func(code string, u model.URL) {
+		u.ID, u.ShortCode, u.OriginalURL = uuid.New(), code, "https://example.com/page"
+		if u.CanonicalHash == nil {
+			u.CanonicalHash = hash
+		}
+		require.NoError(t, repo.Create(ctx, &u))
+	}
+
+	t.Run("finds a plain link with the same hash, owner and domain", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		create("plain1", model.URL{Owner: "alice"})
+
+		got, err := repo.FindReusable(ctx, hash, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, "plain1", got.ShortCode)
+	})
+
+	t.Run("skips links a caller did not ask for", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		create("expired", model.URL{ExpiresAt: &past})
+		create("locked", model.URL{PasswordHash: "$2a$10$hash"})
+		create("limited", model.URL{MaxClicks: 5})
+		create("tagged", model.URL{Tracking: &model.TrackingParams{Source: "x"}})
+		create("temp", model.URL{RedirectType: 302})
+		create("branded", model.URL{Domain: "go.brand.example"})
+		create("bobs", model.URL{Owner: "bob"})
+		create("other", model.URL{CanonicalHash: []byte("fedcba9876543210fedcba9876543210")})
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "custom", OriginalURL: "https://example.com/page"}))
+
+		_, err := repo.FindReusable(ctx, hash, "", "")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		got, err := repo.FindReusable(ctx, hash, "", "go.brand.example")
+		require.NoError(t, err)
+		assert.Equal(t, "branded", got.ShortCode)
+	})
+
+	t.Run("a new destination moves the hash, except on custom aliases", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		create("moved", model.URL{})
+		require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "custom", OriginalURL: "https://example.com/page"}))
+
+		newHash := []byte("fedcba9876543210fedcba9876543210")
+		for _, code := range []string{"moved", "custom"} {
+			dest := "https://example.com/other"
+			_, err := repo.Update(ctx, code, model.URLUpdate{OriginalURL: &dest, CanonicalHash: newHash})
+			require.NoError(t, err)
+		}
+
+		_, err := repo.FindReusable(ctx, hash, "", "")
+		assert.ErrorIs(t, err, ErrNotFound)
+		got, err := repo.FindReusable(ctx, newHash, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "moved", got.ShortCode)
+	})
+}
+
+func TestURLRepository_UpdateSchedule(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+	launch := time.Now().Add(time.Hour).Truncate(time.Second)
+	expires := launch.Add(time.Hour)
+
+	seed := func(t *testing.T) {
+		t.Helper()
+		testDB.Cleanup(ctx)
+		require.NoError(t, repo.Create(ctx, &model.URL{
+			ID: uuid.New(), ShortCode: "window", OriginalURL: "https://example.com/", NotBefore: &launch, ExpiresAt: &expires,
+		}))
+	}
+
+	t.Run("rejects bounds that cross the stored ones", func(t *testing.T) {
+		seed(t)
+
+		tooLate := expires.Add(time.Minute)
+		_, err := repo.Update(ctx, "window", model.URLUpdate{NotBefore: &tooLate})
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+		tooEarly := launch.Add(-time.Minute)
+		_, err = repo.Update(ctx, "window", model.URLUpdate{ExpiresAt: &tooEarly})
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+		got, err := repo.GetByCode(ctx, "window")
+		require.NoError(t, err)
+		assert.True(t, launch.Equal(*got.NotBefore), "a refused update changes nothing")
+	})
+
+	t.Run("cleared bounds are not checked", func(t *testing.T) {
+		seed(t)
+
+		later := expires.Add(time.Hour)
+		got, err := repo.Update(ctx, "window", model.URLUpdate{NotBefore: &later, ClearExpiry: true})
+		require.NoError(t, err)
+		assert.Nil(t, got.ExpiresAt)
+	})
+
+	t.Run("missing links are not found", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		later := expires.Add(time.Hour)
+		_, err := repo.Update(ctx, "missing", model.URLUpdate{NotBefore: &later})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestURLRepository_DeleteExpired(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+
+	seed := func(code string, expiresAt *time.Time) {
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO urls (id, short_code, original_url, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, uuid.New(), code, "https://example.com/"+code, time.Now(), expiresAt)
+		require.NoError(t, err)
+	}
+
+	t.Run("deletes only rows expired before the cutoff, oldest first", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		now := time.Now()
+		oldest, older, recent, future := now.Add(-3*time.Hour), now.Add(-2*time.Hour), now.Add(-time.Minute), now.Add(time.Hour)
+		seed("reap1", &oldest)
+		seed("reap2", &older)
+		seed("reap3", &recent)
+		seed("keep1", &future)
+		seed("keep2", nil)
+
+		refs, err := repo.DeleteExpired(ctx, now.Add(-time.Hour), 10)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []model.LinkRef{{ShortCode: "reap1"}, {ShortCode: "reap2"}}, refs)
+
+		var count int
+		testDB.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM urls").Scan(&count)
+		assert.Equal(t, 3, count, "recently expired and unexpired rows must remain")
+	})
+
+	t.Run("respects the batch limit", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		past := time.Now().Add(-time.Hour)
+		for _, code := range []string{"lim1", "lim2", "lim3"} {
+			seed(code, &past)
+		}
+
+		codes, err := repo.DeleteExpired(ctx, time.Now(), 2)
+		require.NoError(t, err)
+		assert.Len(t, codes, 2)
+
+		codes, err = repo.DeleteExpired(ctx, time.Now(), 2)
+		require.NoError(t, err)
+		assert.Len(t, codes, 1)
+
+		codes, err = repo.DeleteExpired(ctx, time.Now(), 2)
+		require.NoError(t, err)
+		assert.Empty(t, codes)
+	})
+}
+
+func TestURLRepository_List(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+
+	// seed inserts rows with distinct creation times and click counts so both
+	// sort orders are deterministic.
+	seed := func(t *testing.T) time.Time {
+		t.Helper()
+		testDB.Cleanup(ctx)
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		past := time.Now().Add(-time.Minute)
+		rows := []struct {
+			code      string
+			url       string
+			offset    time.Duration
+			clicks    int64
+			expiresAt *time.Time
+		}{
+			{"list01", "https://example.com/alpha", 0, 5, nil},
+			{"list02", "https://example.com/beta", time.Minute, 50, nil},
+			{"list03", "https://other.org/gamma", 2 * time.Minute, 1, &past},
+			{"list04", "https://example.com/100%_off", 3 * time.Minute, 20, nil},
+		}
+		for _, row := range rows {
+			_, err := testDB.Pool.Exec(ctx, `
+				INSERT INTO urls (id, short_code, original_url, created_at, expires_at, click_count)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, uuid.New(), row.code, row.url, base.Add(row.offset), row.expiresAt, row.clicks)
+			require.NoError(t, err)
+		}
+		return base
+	}
+
+	codes := func(urls []*model.URL) []string {
+		out := make([]string, len(urls))
+		for i, u := range urls {
+			out[i] = u.ShortCode
+		}
+		return out
+	}
+
+	t.Run("success - newest first by default", func(t *testing.T) {
+		seed(t)
+
+		urls, err := repo.List(ctx, model.URLFilter{SortBy: model.SortByCreatedAt, Descending: true, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list04", "list03", "list02", "list01"}, codes(urls))
+		assert.Equal(t, int64(20), urls[0].ClickCount)
+	})
+
+	t.Run("success - sort by click count ascending", func(t *testing.T) {
+		seed(t)
+
+		urls, err := repo.List(ctx, model.URLFilter{SortBy: model.SortByClickCount, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list03", "list01", "list04", "list02"}, codes(urls))
+	})
+
+	t.Run("success - keyset pagination resumes after cursor", func(t *testing.T) {
+		seed(t)
+
+		filter := model.URLFilter{SortBy: model.SortByClickCount, Descending: true, Limit: 2}
+		first, err := repo.List(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list02", "list04"}, codes(first))
+
+		last := first[len(first)-1]
+		filter.After = &model.URLCursor{CreatedAt: last.CreatedAt, ClickCount: last.ClickCount, ID: last.ID}
+		second, err := repo.List(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list01", "list03"}, codes(second))
+	})
+
+	t.Run("success - filters by status, query and creation range", func(t *testing.T) {
+		base := seed(t)
+
+		expired, err := repo.List(ctx, model.URLFilter{Status: model.StatusExpired, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list03"}, codes(expired))
+
+		active, err := repo.List(ctx, model.URLFilter{Status: model.StatusActive, Query: "EXAMPLE.com", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list01", "list02", "list04"}, codes(active))
+
+		// Wildcards in the query are matched literally
+		literal, err := repo.List(ctx, model.URLFilter{Query: "100%_", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list04"}, codes(literal))
+
+		after := base.Add(30 * time.Second)
+		before := base.Add(150 * time.Second)
+		ranged, err := repo.List(ctx, model.URLFilter{CreatedAfter: &after, CreatedBefore: &before, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list02", "list03"}, codes(ranged))
+	})
+
+	t.Run("success - scheduled links are neither active nor expired", func(t *testing.T) {
+		seed(t)
+		_, err := testDB.Pool.Exec(ctx, `UPDATE urls SET not_before = NOW() + INTERVAL '1 hour' WHERE short_code = 'list02'`)
+		require.NoError(t, err)
+
+		scheduled, err := repo.List(ctx, model.URLFilter{Status: model.StatusScheduled, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list02"}, codes(scheduled))
+
+		active, err := repo.List(ctx, model.URLFilter{Status: model.StatusActive, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list01", "list04"}, codes(active))
+	})
+
+	t.Run("success - filters by owner", func(t *testing.T) {
+		seed(t)
+		_, err := testDB.Pool.Exec(ctx, `UPDATE urls SET owner = 'alice' WHERE short_code IN ('list01', 'list04')`)
+		require.NoError(t, err)
+
+		owned, err := repo.List(ctx, model.URLFilter{Owner: "alice", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list01", "list04"}, codes(owned))
+	})
+}
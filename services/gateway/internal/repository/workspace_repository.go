@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrWorkspaceConflict = errors.New("workspace slug already exists")
+)
+
+// WorkspaceRepository handles database operations for workspaces.
+type WorkspaceRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *pgxpool.Pool) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// workspaceColumns is the select list read by scanWorkspace; queries alias
+// the workspaces table as w.
+const workspaceColumns = `w.id, w.slug, w.name, COALESCE(w.base_url, ''), w.alias_min_length,
+	w.alias_max_length, COALESCE(w.alias_pattern, ''), w.reserved_aliases, w.max_links,
+	COALESCE(w.fallback_url, ''), w.created_at`
+
+func scanWorkspace(row pgx.Row) (*model.Workspace, error) {
+	var w model.Workspace
+	if err := row.Scan(&w.ID, &w.Slug, &w.Name, &w.BaseURL,
+		&w.AliasMinLen, &w.AliasMaxLen, &w.AliasPattern, &w.ReservedAliases, &w.MaxLinks,
+		&w.FallbackURL, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Create stores a new workspace and fills in its ID and CreatedAt.
+// A duplicate slug returns ErrWorkspaceConflict.
+func (r *WorkspaceRepository) Create(ctx context.Context, ws *model.Workspace) error {
+	ctx, span := tracer.Start(ctx, "db.insert",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", "workspaces"),
+		),
+	)
+	defer span.End()
+
+	if ws.ReservedAliases == nil {
+		ws.ReservedAliases = []string{}
+	}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO workspaces (slug, name, base_url, alias_min_length, alias_max_length,
+		                        alias_pattern, reserved_aliases, max_links, fallback_url)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, $8, NULLIF($9, ''))
+		RETURNING id, created_at`,
+		ws.Slug, ws.Name, ws.BaseURL, ws.AliasMinLen, ws.AliasMaxLen,
+		ws.AliasPattern, ws.ReservedAliases, ws.MaxLinks, ws.FallbackURL,
+	).Scan(&ws.ID, &ws.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrWorkspaceConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByID returns the workspace with the given ID.
+func (r *WorkspaceRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Workspace, error) {
+	return r.getOne(ctx, `SELECT `+workspaceColumns+` FROM workspaces w WHERE w.id = $1`, id)
+}
+
+// GetByHost returns the workspace that registered host as a domain, compared
+// case-insensitively.
+func (r *WorkspaceRepository) GetByHost(ctx context.Context, host string) (*model.Workspace, error) {
+	return r.getOne(ctx, `SELECT `+workspaceColumns+`
+		FROM workspaces w JOIN domains d ON d.workspace_id = w.id
+		WHERE d.host = $1`, strings.ToLower(host))
+}
+
+// GetBySlug returns the workspace with the given slug.
+func (r *WorkspaceRepository) GetBySlug(ctx context.Context, slug string) (*model.Workspace, error) {
+	return r.getOne(ctx, `SELECT `+workspaceColumns+` FROM workspaces w WHERE w.slug = $1`, slug)
+}
+
+func (r *WorkspaceRepository) getOne(ctx context.Context, query string, arg any) (*model.Workspace, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "workspaces"),
+		),
+	)
+	defer span.End()
+
+	ws, err := scanWorkspace(r.db.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+	return ws, nil
+}
+
+// List returns all workspaces ordered by slug.
+func (r *WorkspaceRepository) List(ctx context.Context) ([]*model.Workspace, error) {
+	ctx, span := tracer.Start(ctx, "db.select",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.sql.table", "workspaces"),
+		),
+	)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, `SELECT `+workspaceColumns+` FROM workspaces w ORDER BY w.slug`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*model.Workspace
+	for rows.Next() {
+		ws, err := scanWorkspace(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/cache"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// createTestWorkspace stores a workspace and, unless host is empty,
+// registers host as its domain.
+func createTestWorkspace(t *testing.T, slug, host string) *model.Workspace {
+	t.Helper()
+	ctx := context.Background()
+	ws := &model.Workspace{Slug: slug, Name: slug, MaxLinks: 10}
+	require.NoError(t, NewWorkspaceRepository(testDB.Pool).Create(ctx, ws))
+	if host != "" {
+		require.NoError(t, NewDomainRepository(testDB.Pool).Create(ctx, &model.Domain{Host: host, WorkspaceID: ws.ID}))
+	}
+	return ws
+}
+
+func TestWorkspaceRepository(t *testing.T) {
+	repo := NewWorkspaceRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	ws := createTestWorkspace(t, "acme", "Go.Acme.Example")
+	assert.NotEqual(t, uuid.Nil, ws.ID)
+
+	t.Run("looks up by id, host and slug", func(t *testing.T) {
+		got, err := repo.GetByID(ctx, ws.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", got.Slug)
+		assert.Equal(t, int64(10), got.MaxLinks)
+
+		got, err = repo.GetByHost(ctx, "go.acme.EXAMPLE")
+		require.NoError(t, err)
+		assert.Equal(t, ws.ID, got.ID)
+
+		got, err = repo.GetBySlug(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, ws.ID, got.ID)
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		_, err := repo.GetByHost(ctx, "nowhere.example")
+		assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	})
+
+	t.Run("duplicate slug conflicts", func(t *testing.T) {
+		err := repo.Create(ctx, &model.Workspace{Slug: "acme", Name: "again"})
+		assert.ErrorIs(t, err, ErrWorkspaceConflict)
+	})
+
+	t.Run("lists the default workspace too", func(t *testing.T) {
+		all, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, model.DefaultWorkspaceID, all[1].ID)
+	})
+}
+
+func TestURLRepository_Workspaces(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	ws := createTestWorkspace(t, "acme", "")
+	wsCtx := tenant.WithWorkspace(ctx, ws)
+
+	require.NoError(t, repo.Create(ctx, &model.URL{ID: uuid.New(), ShortCode: "promo", OriginalURL: "https://default.example"}))
+	require.NoError(t, repo.Create(wsCtx, &model.URL{ID: uuid.New(), ShortCode: "promo", OriginalURL: "https://acme.example", WorkspaceID: ws.ID}),
+		"the same code may exist in another workspace")
+	err := repo.Create(wsCtx, &model.URL{ID: uuid.New(), ShortCode: "promo", OriginalURL: "https://acme.example", WorkspaceID: ws.ID})
+	assert.ErrorIs(t, err, ErrCodeConflict)
+
+	got, err := repo.GetByCode(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://default.example", got.OriginalURL)
+
+	got, err = repo.GetByCode(wsCtx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.example", got.OriginalURL)
+	assert.Equal(t, ws.ID, got.WorkspaceID)
+
+	count, err := repo.Count(wsCtx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	urls, err := repo.List(wsCtx, model.URLFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, ws.ID, urls[0].WorkspaceID)
+
+	require.NoError(t, repo.Delete(wsCtx, "promo"))
+	_, err = repo.GetByCode(ctx, "promo")
+	assert.NoError(t, err, "deleting in one workspace leaves the other untouched")
+}
+
+func TestURLRepository_WorkspaceQuota(t *testing.T) {
+	repo := NewURLRepository(testDB.Pool)
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+
+	ws := &model.Workspace{Slug: "small", Name: "small", MaxLinks: 2}
+	require.NoError(t, NewWorkspaceRepository(testDB.Pool).Create(ctx, ws))
+	wsCtx := tenant.WithWorkspace(ctx, ws)
+	link := func(code string) *model.URL {
+		return &model.URL{ID: uuid.New(), ShortCode: code, OriginalURL: "https://small.example/" + code, WorkspaceID: ws.ID}
+	}
+
+	require.NoError(t, repo.Create(wsCtx, link("one")))
+	results, err := repo.CreateBatch(wsCtx, []*model.URL{link("one"), link("two")})
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0], ErrCodeConflict, "skipped rows use no quota")
+	assert.NoError(t, results[1])
+
+	assert.ErrorIs(t, repo.Create(wsCtx, link("three")), ErrQuotaExceeded)
+	_, err = repo.CreateBatch(wsCtx, []*model.URL{link("four")})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	require.NoError(t, repo.Delete(wsCtx, "one"))
+	assert.NoError(t, repo.Create(wsCtx, link("five")), "deleting a link frees its slot")
+}
+
+func TestCachedURLRepository_WorkspaceKeys(t *testing.T) {
+	ctx := context.Background()
+	testDB.Cleanup(ctx)
+	testCache.Cleanup(ctx)
+
+	ws := createTestWorkspace(t, "acme", "")
+	wsCtx := tenant.WithWorkspace(ctx, ws)
+	repo := NewCachedURLRepository(NewURLRepository(testDB.Pool),
+		cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), time.Minute, newTestLogger())
+
+	require.NoError(t, repo.Create(wsCtx, &model.URL{ID: uuid.New(), ShortCode: "promo", OriginalURL: "https://acme.example", WorkspaceID: ws.ID}))
+
+	n, err := testCache.Client.Exists(ctx, "url:"+ws.ID.String()+":promo").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = repo.GetByCode(ctx, "promo")
+	assert.ErrorIs(t, err, ErrNotFound, "the default workspace must not see another workspace's entry")
+	got, err := repo.GetByCode(wsCtx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.example", got.OriginalURL)
+}
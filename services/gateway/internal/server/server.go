@@ -1,6 +1,7 @@
 package server
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -11,9 +12,12 @@ import (
 	"github.com/zhejian/url-shortener/gateway/internal/api"
 	"github.com/zhejian/url-shortener/gateway/internal/cache"
 	"github.com/zhejian/url-shortener/gateway/internal/config"
+	"github.com/zhejian/url-shortener/gateway/internal/geoip"
+	"github.com/zhejian/url-shortener/gateway/internal/keypool"
 	"github.com/zhejian/url-shortener/gateway/internal/middleware"
 	"github.com/zhejian/url-shortener/gateway/internal/observability"
 	"github.com/zhejian/url-shortener/gateway/internal/ratelimit"
+	"github.com/zhejian/url-shortener/gateway/internal/reaper"
 	"github.com/zhejian/url-shortener/gateway/internal/repository"
 	"github.com/zhejian/url-shortener/gateway/internal/service"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -21,7 +25,7 @@ import (
 
 // NewRouter initializes all dependencies and returns a configured Gin router.
 // Middleware is registered before routes so it applies to all requests.
-func NewRouter(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, rateLimiter *ratelimit.Client, obs *observability.Observability, pub *analytics.Publisher) *gin.Engine {
+func NewRouter(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, rateLimiter *ratelimit.Client, obs *observability.Observability, pub *analytics.Publisher, geo *geoip.Resolver, keys *keypool.Pool) *gin.Engine {
 	r := gin.Default()
 
 	// Metrics endpoint
@@ -36,30 +40,198 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider,
 	}
 
 	// Wire dependencies and register routes
-	baseRepo := repository.NewURLRepository(db)
+	urlRepo := newCachedURLRepository(cfg, db, cache, obs)
+	domainRepo := repository.NewDomainRepository(db)
+	urlService := service.NewURLService(urlRepo, obs.Logger, cfg.App.BaseURL, cfg.App.ShortCodeLen, cfg.App.ShortCodeRetries,
+		service.URLServiceOptions{
+			MaxBatchSize:          cfg.App.MaxBatchSize,
+			AliasPolicy:           newAliasPolicy(cfg.App, obs.Logger),
+			DefaultExpiry:         cfg.App.DefaultExpiry,
+			MaxExpiry:             cfg.App.MaxExpiry,
+			Stats:                 newCachedStatsRepository(cfg, db, cache, obs),
+			EnforceOwnership:      cfg.Auth.Enabled,
+			Domains:               domainRepo,
+			DefaultRedirectStatus: redirectStatus(cfg.App.RedirectStatus, obs.Logger),
+			Unlocks:               newUnlockSigner(cfg.App, obs.Logger),
+			Codes:                 newCodeStrategy(cfg.App, db, keys, obs.Logger),
+			MaxShortCodeLen:       cfg.App.ShortCodeMaxLen,
+			CollisionThreshold:    cfg.App.CollisionThreshold,
+			CollisionWindow:       cfg.App.CollisionWindow,
+			CodeLengths:           repository.NewCodeCounterRepository(db),
+		})
+	var rlCB api.CBStateProvider
+	if rateLimiter != nil {
+		rlCB = rateLimiter
+	}
+	handler := api.NewHandler(urlService, db, cache, obs.Logger, pub).
+		WithCBProviders(urlRepo, rlCB).
+		WithDomainService(service.NewDomainService(domainRepo, obs.Logger, cfg.Auth.Enabled))
+	if rateLimiter != nil {
+		handler.WithUnlockLimiter(rateLimiter)
+	}
+	if geo != nil {
+		handler.WithGeoIP(geo)
+	}
+	if pages := newErrorPages(cfg.App, obs.Logger); pages != nil {
+		handler.WithErrorPages(pages)
+	}
+	if cfg.Auth.Enabled {
+		handler.WithAPIMiddleware(middleware.Auth(repository.NewAPIKeyRepository(db), obs.Logger,
+			middleware.AuthOptions{AllowAnonymous: cfg.Auth.AllowAnonymous}))
+	}
+	// Tenant runs after Auth so an API key's workspace is known; API and
+	// redirect routes share one instance and therefore one lookup cache.
+	// Only redirects may be anonymous on a workspace's domain.
+	tenantMW := middleware.Tenant(repository.NewWorkspaceRepository(db), obs.Logger,
+		middleware.TenantOptions{CacheTTL: cfg.Workspace.CacheTTL})
+	handler.WithAPIMiddleware(tenantMW, middleware.RequireKeyOnDomain()).WithRedirectMiddleware(tenantMW)
+	handler.RegisterRoutes(r)
+
+	return r
+}
+
+// cacheBreakerSettings returns the Redis circuit breaker settings from config.
+func cacheBreakerSettings(cfg *config.Config) repository.CBSettings {
 	cacheCB := repository.DefaultCBSettings()
 	cacheCB.OperationTimeout = cfg.Cache.OperationTimeout
 	cacheCB.MinRequestsToTrip = cfg.Cache.CBMinRequests
 	cacheCB.FailureRateThreshold = cfg.Cache.CBFailureRate
 	cacheCB.ConsecutiveFailures = cfg.Cache.CBConsecutiveFailures
 	cacheCB.Timeout = cfg.Cache.CBTimeout
-	urlRepo := repository.NewCachedURLRepository(baseRepo, cache, cfg.Cache.TTL, obs.Logger,
-		repository.CachedURLRepositoryOptions{CacheCB: &cacheCB})
-	urlService := service.NewURLService(urlRepo, obs.Logger, cfg.App.BaseURL, cfg.App.ShortCodeLen, cfg.App.ShortCodeRetries)
-	var rlCB api.CBStateProvider
-	if rateLimiter != nil {
-		rlCB = rateLimiter
+	return cacheCB
+}
+
+// newCachedURLRepository builds the cached repository with circuit breaker settings from config.
+func newCachedURLRepository(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, obs *observability.Observability) *repository.CachedURLRepository {
+	baseRepo := repository.NewURLRepository(db)
+	cacheCB := cacheBreakerSettings(cfg)
+	return repository.NewCachedURLRepository(baseRepo, cache, cfg.Cache.TTL, obs.Logger,
+		repository.CachedURLRepositoryOptions{CacheCB: &cacheCB, ClickCountTTL: cfg.Cache.ClickCountTTL})
+}
+
+// newCachedStatsRepository builds the stats repository with its own cache circuit breaker.
+func newCachedStatsRepository(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, obs *observability.Observability) *repository.CachedStatsRepository {
+	cacheCB := cacheBreakerSettings(cfg)
+	return repository.NewCachedStatsRepository(repository.NewStatsRepository(db), cache, cfg.Cache.StatsTTL, obs.Logger,
+		repository.CachedStatsRepositoryOptions{CacheCB: &cacheCB})
+}
+
+// NewReaper wires the expiry reaper with its own repository instance so its
+// cache circuit breaker never trips on behalf of request traffic.
+func NewReaper(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, obs *observability.Observability) *reaper.Reaper {
+	return reaper.New(newCachedURLRepository(cfg, db, cache, obs), reaper.NewPGLocker(db), reaper.Config{
+		Interval:   cfg.Reaper.Interval,
+		BatchSize:  cfg.Reaper.BatchSize,
+		MaxBatches: cfg.Reaper.MaxBatches,
+		Grace:      cfg.Reaper.Grace,
+	}, obs.Logger)
+}
+
+// NewKeyPool wires the pre-generated short code pool. Codes are random
+// Base62, SHORT_CODE_LENGTH characters until URLService lengthens them, and
+// the same generator serves requests while the pool is empty.
+func NewKeyPool(cfg *config.Config, db *pgxpool.Pool, obs *observability.Observability) *keypool.Pool {
+	return keypool.New(repository.NewKeyPoolRepository(db), reaper.NewPGLockerKey(db, keypool.LockKey),
+		service.NewRandomStrategy(), keypool.Config{
+			Size:      cfg.App.ShortCodePoolSize,
+			LeaseSize: cfg.App.ShortCodePoolLease,
+			Interval:  cfg.App.ShortCodePoolInterval,
+			Length:    cfg.App.ShortCodeLen,
+		}, obs.Logger)
+}
+
+// redirectStatus validates REDIRECT_STATUS, falling back to 301 like other
+// invalid env values.
+func redirectStatus(status int, logger *slog.Logger) int {
+	if !service.ValidRedirectStatus(status) {
+		logger.Error("invalid REDIRECT_STATUS, using 301",
+			slog.Int("status", status))
+		return http.StatusMovedPermanently
 	}
-	handler := api.NewHandler(urlService, db, cache, obs.Logger, pub).WithCBProviders(urlRepo, rlCB)
-	handler.RegisterRoutes(r)
+	return status
+}
 
-	return r
+// newUnlockSigner builds the signer for unlock cookies. Without a configured
+// secret each replica signs with its own random key, which only works for a
+// single instance.
+func newUnlockSigner(cfg config.AppConfig, logger *slog.Logger) *service.UnlockSigner {
+	if cfg.UnlockSecret == "" {
+		logger.Warn("LINK_UNLOCK_SECRET not set, unlock cookies are valid on this instance only")
+	}
+	return service.NewUnlockSigner([]byte(cfg.UnlockSecret), cfg.UnlockTTL)
+}
+
+// newCodeStrategy picks how generated short codes are produced. An unknown
+// or misconfigured strategy is logged and falls back to hashing the URL.
+// The pool strategy uses keys, which the caller runs and closes.
+func newCodeStrategy(cfg config.AppConfig, db *pgxpool.Pool, keys *keypool.Pool, logger *slog.Logger) service.CodeStrategy {
+	switch cfg.ShortCodeStrategy {
+	case service.CodeStrategyHash:
+		return nil
+	case service.CodeStrategyRandom:
+		return service.NewRandomStrategy()
+	case service.CodeStrategyCounter:
+		if cfg.ShortCodeSecret == "" {
+			logger.Warn("SHORT_CODE_SECRET not set, counter codes are easy to enumerate")
+		}
+		codes, err := service.NewCounterStrategy(repository.NewCodeCounterRepository(db),
+			cfg.ShortCodeLen, int64(cfg.ShortCodeBlockSize), []byte(cfg.ShortCodeSecret))
+		if err != nil {
+			logger.Error("invalid counter strategy config, using hash",
+				slog.String("error", err.Error()))
+			return nil
+		}
+		return codes
+	case service.CodeStrategyPool:
+		if keys == nil {
+			logger.Error("short code pool not started, using hash")
+			return nil
+		}
+		return keys
+	default:
+		logger.Warn("unknown SHORT_CODE_STRATEGY, using hash",
+			slog.String("strategy", cfg.ShortCodeStrategy))
+		return nil
+	}
+}
+
+// newErrorPages loads the HTML error pages from ERROR_PAGES_DIR. A directory
+// that fails to load is logged and the built-in pages are served instead.
+func newErrorPages(cfg config.AppConfig, logger *slog.Logger) *api.ErrorPages {
+	pages, err := api.LoadErrorPages(cfg.ErrorPagesDir)
+	if err != nil {
+		logger.Error("failed to load error pages, using built-in pages",
+			slog.String("path", cfg.ErrorPagesDir),
+			slog.String("error", err.Error()))
+		return nil
+	}
+	return pages
+}
+
+// newAliasPolicy builds the custom alias policy from config.
+// Misconfiguration is logged and falls back to defaults rather than
+// preventing startup, matching how invalid env values are handled elsewhere.
+func newAliasPolicy(cfg config.AppConfig, logger *slog.Logger) *service.AliasPolicy {
+	policy, err := service.NewAliasPolicy(cfg.MinAliasLen, cfg.MaxAliasLen, cfg.AliasPattern, cfg.ReservedAliases)
+	if err != nil {
+		logger.Error("invalid alias policy config, using defaults",
+			slog.String("error", err.Error()))
+		policy = service.DefaultAliasPolicy()
+	}
+	if cfg.AliasBlocklist != "" {
+		if err := policy.LoadBlocklist(cfg.AliasBlocklist); err != nil {
+			logger.Error("failed to load alias blocklist",
+				slog.String("path", cfg.AliasBlocklist),
+				slog.String("error", err.Error()))
+		}
+	}
+	return policy
 }
 
 // NewServer initializes all dependencies and returns a configured HTTP server.
 // This includes the router plus HTTP server settings (timeouts, address, etc.).
-func NewServer(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, rateLimiter *ratelimit.Client, obs *observability.Observability, pub *analytics.Publisher) *http.Server {
-	router := NewRouter(cfg, db, cache, rateLimiter, obs, pub)
+func NewServer(cfg *config.Config, db *pgxpool.Pool, cache cache.ClientProvider, rateLimiter *ratelimit.Client, obs *observability.Observability, pub *analytics.Publisher, geo *geoip.Resolver, keys *keypool.Pool) *http.Server {
+	router := NewRouter(cfg, db, cache, rateLimiter, obs, pub, geo, keys)
 
 	return &http.Server{
 		Addr:         ":" + cfg.Server.Port,
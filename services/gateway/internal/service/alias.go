@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MaxAliasLen is the width of the urls.short_code column; longer aliases
+// could never be stored.
+const MaxAliasLen = 16
+
+// DefaultAliasPattern allows URL-safe characters that need no escaping in a path segment.
+const DefaultAliasPattern = `^[A-Za-z0-9_-]+$`
+
+// DefaultReservedAliases protects top-level routes served by the gateway
+// itself, plus a few names commonly claimed by future endpoints.
+var DefaultReservedAliases = []string{"api", "health", "metrics", "admin", "static", "assets", "docs"}
+
+// AliasError explains why a custom alias was rejected.
+// It matches ErrInvalidAlias with errors.Is so callers can keep mapping it to 400.
+type AliasError struct {
+	Alias  string
+	Reason string
+}
+
+func (e *AliasError) Error() string {
+	return fmt.Sprintf("%s: %q %s", ErrInvalidAlias, e.Alias, e.Reason)
+}
+
+func (e *AliasError) Unwrap() error {
+	return ErrInvalidAlias
+}
+
+// AliasPolicy decides whether a client-chosen custom alias may be used as a short code.
+// Checks run cheapest first: length, character set, reserved words, blocklist.
+type AliasPolicy struct {
+	minLen    int
+	maxLen    int
+	pattern   *regexp.Regexp
+	reserved  map[string]struct{} // lowercased, exact match
+	blocklist []string            // lowercased, substring match
+}
+
+// NewAliasPolicy builds a policy from explicit settings.
+// Reserved words are matched case-insensitively against the whole alias.
+func NewAliasPolicy(minLen, maxLen int, pattern string, reserved []string) (*AliasPolicy, error) {
+	if minLen < 1 || maxLen < minLen || maxLen > MaxAliasLen {
+		return nil, fmt.Errorf("invalid alias length bounds [%d, %d], at most %d", minLen, maxLen, MaxAliasLen)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compiling alias pattern: %w", err)
+	}
+
+	p := &AliasPolicy{
+		minLen:   minLen,
+		maxLen:   maxLen,
+		pattern:  re,
+		reserved: make(map[string]struct{}, len(reserved)),
+	}
+	for _, word := range reserved {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.reserved[word] = struct{}{}
+		}
+	}
+	return p, nil
+}
+
+// DefaultAliasPolicy returns the policy used when none is configured:
+// 3 to MaxAliasLen characters, DefaultAliasPattern and
+// DefaultReservedAliases.
+func DefaultAliasPolicy() *AliasPolicy {
+	p, _ := NewAliasPolicy(3, MaxAliasLen, DefaultAliasPattern, DefaultReservedAliases)
+	return p
+}
+
+// Extend returns a copy of p with overrides applied: non-zero length bounds
+// and a non-empty pattern replace p's, while reserved words are added to p's
+// so routes protected server-wide stay protected. The blocklist is shared.
+func (p *AliasPolicy) Extend(minLen, maxLen int, pattern string, reserved []string) (*AliasPolicy, error) {
+	if minLen == 0 {
+		minLen = p.minLen
+	}
+	if maxLen == 0 {
+		maxLen = p.maxLen
+	}
+	if pattern == "" {
+		pattern = p.pattern.String()
+	}
+	words := make([]string, 0, len(p.reserved)+len(reserved))
+	for word := range p.reserved {
+		words = append(words, word)
+	}
+	ext, err := NewAliasPolicy(minLen, maxLen, pattern, append(words, reserved...))
+	if err != nil {
+		return nil, err
+	}
+	ext.blocklist = p.blocklist
+	return ext, nil
+}
+
+// LoadBlocklist reads one blocked term per line from path, replacing any
+// previously loaded terms. Blank lines and lines starting with '#' are ignored.
+// Terms match case-insensitively anywhere inside an alias.
+func (p *AliasPolicy) LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	p.blocklist = terms
+	return nil
+}
+
+// Validate returns nil if alias is acceptable, otherwise an *AliasError.
+func (p *AliasPolicy) Validate(alias string) error {
+	if n := len(alias); n < p.minLen || n > p.maxLen {
+		return &AliasError{Alias: alias, Reason: fmt.Sprintf("must be between %d and %d characters", p.minLen, p.maxLen)}
+	}
+	if !p.pattern.MatchString(alias) {
+		return &AliasError{Alias: alias, Reason: "contains characters that are not allowed"}
+	}
+	lower := strings.ToLower(alias)
+	if _, ok := p.reserved[lower]; ok {
+		return &AliasError{Alias: alias, Reason: "is reserved"}
+	}
+	for _, term := range p.blocklist {
+		if strings.Contains(lower, term) {
+			return &AliasError{Alias: alias, Reason: "is not allowed"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasPolicy_Validate(t *testing.T) {
+	policy := DefaultAliasPolicy()
+
+	tests := []struct {
+		name   string
+		alias  string
+		reason string
+	}{
+		{"accepts letters digits hyphen underscore", "My_link-2024", ""},
+		{"rejects too short", "ab", "must be between 3 and 16 characters"},
+		{"rejects too long", "abcdefghijklmnopq", "must be between 3 and 16 characters"},
+		{"rejects slash", "a/b/c", "contains characters that are not allowed"},
+		{"rejects non-ascii", "café", "contains characters that are not allowed"},
+		{"rejects reserved word", "health", "is reserved"},
+		{"rejects reserved word case-insensitively", "Metrics", "is reserved"},
+		{"allows reserved word as substring", "api-docs", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.alias)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var aliasErr *AliasError
+			require.ErrorAs(t, err, &aliasErr)
+			assert.ErrorIs(t, err, ErrInvalidAlias)
+			assert.Equal(t, tt.reason, aliasErr.Reason)
+		})
+	}
+}
+
+func TestAliasPolicy_LoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n\nBadWord\n  spam \n"), 0o644))
+
+	policy := DefaultAliasPolicy()
+	require.NoError(t, policy.LoadBlocklist(path))
+
+	assert.ErrorIs(t, policy.Validate("my-badword-1"), ErrInvalidAlias)
+	assert.ErrorIs(t, policy.Validate("SPAMMY"), ErrInvalidAlias)
+	assert.NoError(t, policy.Validate("comment"))
+
+	assert.Error(t, policy.LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt")))
+}
+
+func TestNewAliasPolicy_InvalidConfig(t *testing.T) {
+	_, err := NewAliasPolicy(5, 3, DefaultAliasPattern, nil)
+	assert.Error(t, err)
+
+	_, err = NewAliasPolicy(3, 16, "[", nil)
+	assert.Error(t, err)
+
+	_, err = NewAliasPolicy(3, MaxAliasLen+1, DefaultAliasPattern, nil)
+	assert.Error(t, err, "aliases must fit the short_code column")
+}
+
+func TestAliasPolicy_Extend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("spam\n"), 0o644))
+	base := DefaultAliasPolicy()
+	require.NoError(t, base.LoadBlocklist(path))
+
+	policy, err := base.Extend(5, 0, `^[a-z]+$`, []string{"sale"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, policy.Validate("abcd"), ErrInvalidAlias, "min length overridden")
+	assert.NoError(t, policy.Validate("abcdefghijklmnop"), "max length inherited")
+	assert.ErrorIs(t, policy.Validate("Abcde"), ErrInvalidAlias, "pattern overridden")
+	assert.ErrorIs(t, policy.Validate("Sale"), ErrInvalidAlias, "workspace reserved word")
+	assert.ErrorIs(t, policy.Validate("admin"), ErrInvalidAlias, "server reserved words still apply")
+	assert.ErrorIs(t, policy.Validate("spammy"), ErrInvalidAlias, "blocklist shared")
+	assert.NoError(t, base.Validate("sale"), "base policy unchanged")
+
+	_, err = base.Extend(20, 0, "", nil)
+	assert.Error(t, err, "min above inherited max")
+
+	_, err = base.Extend(0, 32, "", nil)
+	assert.Error(t, err, "max above the short_code column")
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxGeneratedCodeLen is the longest generated code every strategy can
+// produce: hashes and counter values are 64-bit, and 62^10 < 2^64 < 62^11.
+const maxGeneratedCodeLen = 10
+
+// Defaults for adaptive code length when URLServiceOptions leaves them zero.
+const (
+	defaultCollisionThreshold = 0.1
+	defaultCollisionWindow    = 1000
+)
+
+// codeLengthLoadTimeout bounds reading the stored code length at startup.
+const codeLengthLoadTimeout = 5 * time.Second
+
+// CodeLengthStore persists the promoted code length so it survives
+// restarts and is shared by replicas as they start.
+// *repository.CodeCounterRepository satisfies it.
+type CodeLengthStore interface {
+	CodeLength(ctx context.Context) (int, error)
+	// RaiseCodeLength stores n unless a longer length is already stored.
+	RaiseCodeLength(ctx context.Context, n int) error
+}
+
+// codeLength picks the length of generated codes. It starts at the
+// configured length and grows by one, up to max, whenever more than
+// threshold of the insert attempts in a window collide. Lengths never
+// shrink: a code space crowded enough to promote stays crowded.
+//
+// Each replica measures collisions on its own. Promotions are written to
+// a CodeLengthStore when one is configured, and replicas start from the
+// stored length; other replicas already running catch up within a window.
+type codeLength struct {
+	max       int
+	threshold float64
+	window    int
+
+	mu         sync.Mutex
+	current    int
+	attempts   int
+	collisions int
+	rate       float64 // collision rate of the last full window
+
+	attemptsTotal metric.Int64Counter
+}
+
+func newCodeLength(base, maxLen int, threshold float64, window int) *codeLength {
+	if threshold <= 0 {
+		threshold = defaultCollisionThreshold
+	}
+	if window <= 0 {
+		window = defaultCollisionWindow
+	}
+	c := &codeLength{
+		max:       max(min(maxLen, maxGeneratedCodeLen), base),
+		threshold: threshold,
+		window:    window,
+		current:   base,
+	}
+
+	meter := otel.Meter("gateway/service")
+	c.attemptsTotal, _ = meter.Int64Counter("short_code_attempts_total",
+		metric.WithDescription("Inserts of generated short codes by result (created, collision)"),
+	)
+	_, _ = meter.Int64ObservableGauge("short_code_length",
+		metric.WithDescription("Length of newly generated short codes"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(c.get()))
+			return nil
+		}),
+	)
+	_, _ = meter.Float64ObservableGauge("short_code_collision_rate",
+		metric.WithDescription("Share of generated short codes that collided, over the last full window of attempts"),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			o.Observe(c.collisionRate())
+			return nil
+		}),
+	)
+	return c
+}
+
+// get returns the length new codes should have.
+func (c *codeLength) get() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// raise moves the length up to n, capped at max. Shorter lengths are
+// ignored.
+func (c *codeLength) raise(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n = min(n, c.max); n > c.current {
+		c.current = n
+		c.attempts, c.collisions = 0, 0
+	}
+}
+
+// collisionRate returns the collision rate of the last full window.
+func (c *codeLength) collisionRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rate
+}
+
+// record notes the outcome of inserting a generated code of length n. When
+// the attempt closes a window whose collision rate is above threshold, the
+// length is promoted and record returns the new length; otherwise it
+// returns 0. Attempts at an outdated length are counted in the metric only,
+// so requests in flight during a promotion cannot trigger another.
+func (c *codeLength) record(ctx context.Context, n int, collided bool) int {
+	result := "created"
+	if collided {
+		result = "collision"
+	}
+	c.attemptsTotal.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n != c.current {
+		return 0
+	}
+	c.attempts++
+	if collided {
+		c.collisions++
+	}
+	if c.attempts < c.window {
+		return 0
+	}
+	c.rate = float64(c.collisions) / float64(c.attempts)
+	c.attempts, c.collisions = 0, 0
+	if c.rate <= c.threshold || c.current >= c.max {
+		return 0
+	}
+	c.current++
+	return c.current
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestCodeLength(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("promotes once a window collides too often", func(t *testing.T) {
+		c := newCodeLength(6, 8, 0.5, 4)
+		for _, collided := range []bool{true, true, false} {
+			assert.Zero(t, c.record(ctx, 6, collided))
+		}
+		assert.Equal(t, 7, c.record(ctx, 6, true), "3 of 4 collided")
+		assert.Equal(t, 7, c.get())
+		assert.InDelta(t, 0.75, c.collisionRate(), 1e-9)
+	})
+
+	t.Run("stays put at or below the threshold", func(t *testing.T) {
+		c := newCodeLength(6, 8, 0.5, 4)
+		for _, collided := range []bool{true, false, true, false} {
+			assert.Zero(t, c.record(ctx, 6, collided))
+		}
+		assert.Equal(t, 6, c.get())
+		assert.InDelta(t, 0.5, c.collisionRate(), 1e-9)
+	})
+
+	t.Run("ignores attempts at an outdated length", func(t *testing.T) {
+		c := newCodeLength(6, 8, 0.5, 2)
+		c.record(ctx, 6, true)
+		require.Equal(t, 7, c.record(ctx, 6, true))
+		c.record(ctx, 6, true)
+		assert.Zero(t, c.record(ctx, 6, true), "in-flight short codes do not promote again")
+		assert.Equal(t, 7, c.get())
+	})
+
+	t.Run("never grows past the maximum", func(t *testing.T) {
+		c := newCodeLength(6, 7, 0.1, 1)
+		assert.Equal(t, 7, c.record(ctx, 6, true))
+		assert.Zero(t, c.record(ctx, 7, true))
+		assert.Equal(t, 7, c.get())
+
+		assert.Equal(t, 6, newCodeLength(6, 0, 0, 0).max, "unset maximum keeps the length fixed")
+		assert.Equal(t, maxGeneratedCodeLen, newCodeLength(6, 20, 0, 0).max)
+	})
+
+	t.Run("raise only moves up to the maximum", func(t *testing.T) {
+		c := newCodeLength(6, 8, 0.1, 10)
+		c.raise(5)
+		assert.Equal(t, 6, c.get())
+		c.raise(7)
+		assert.Equal(t, 7, c.get())
+		c.raise(12)
+		assert.Equal(t, 8, c.get())
+	})
+}
+
+// takenRepo rejects codes that are already stored, like the unique index.
+type takenRepo struct {
+	quotaRepo
+}
+
+func (r *takenRepo) Create(ctx context.Context, url *model.URL) error {
+	for _, l := range r.links {
+		if l.ShortCode == url.ShortCode {
+			return repository.ErrCodeConflict
+		}
+	}
+	return r.quotaRepo.Create(ctx, url)
+}
+
+// lengthStore is an in-memory CodeLengthStore.
+type lengthStore struct {
+	n      int
+	raised []int
+}
+
+func (s *lengthStore) CodeLength(context.Context) (int, error) { return s.n, nil }
+
+func (s *lengthStore) RaiseCodeLength(_ context.Context, n int) error {
+	s.raised = append(s.raised, n)
+	s.n = max(s.n, n)
+	return nil
+}
+
+// crowdedRepo rejects every code of one length as taken.
+type crowdedRepo struct {
+	quotaRepo
+	taken int
+}
+
+func (r *crowdedRepo) Create(ctx context.Context, url *model.URL) error {
+	if len(url.ShortCode) == r.taken {
+		return repository.ErrCodeConflict
+	}
+	return r.quotaRepo.Create(ctx, url)
+}
+
+func (r *crowdedRepo) CreateBatch(_ context.Context, urls []*model.URL) ([]error, error) {
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		if len(url.ShortCode) == r.taken {
+			errs[i] = repository.ErrCodeConflict
+			continue
+		}
+		r.links = append(r.links, url)
+	}
+	return errs, nil
+}
+
+func TestURLService_AdaptiveCodeLength(t *testing.T) {
+	repo := &crowdedRepo{taken: 6}
+	store := &lengthStore{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3,
+		URLServiceOptions{MaxShortCodeLen: 7, CollisionThreshold: 0.5, CollisionWindow: 2, CodeLengths: store})
+	ctx := context.Background()
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/crowded"})
+	require.NoError(t, err, "two collisions promote, the last retry gets a longer code")
+	assert.Equal(t, 7, s.length.get())
+	require.Len(t, repo.links, 1)
+	assert.Len(t, repo.links[0].ShortCode, 7)
+	assert.Equal(t, []int{7}, store.raised, "promotion is stored")
+
+	t.Run("batch uses the promoted length", func(t *testing.T) {
+		results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+			{URL: "https://example.com/1"}, {URL: "https://example.com/2"},
+		})
+		require.NoError(t, err)
+		for i, r := range results {
+			require.NoError(t, r.Err, i)
+			assert.Len(t, r.URL.ShortCode, 7, fmt.Sprint(i))
+		}
+	})
+}
+
+func TestURLService_CodeLengthIgnoresRepeatedURLs(t *testing.T) {
+	repo := &takenRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3,
+		URLServiceOptions{MaxShortCodeLen: 8, CollisionThreshold: 0.1, CollisionWindow: 1})
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/same"})
+		require.NoError(t, err)
+	}
+	assert.Len(t, repo.links, 3)
+	assert.Equal(t, 6, s.length.get(), "collisions with links for the same URL are not counted")
+}
+
+func TestURLService_StoredCodeLength(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(&quotaRepo{}, nil, 0, logger), logger, "http://short.example", 6, 3,
+		URLServiceOptions{MaxShortCodeLen: 8, CodeLengths: &lengthStore{n: 7}})
+
+	assert.Equal(t, 7, s.length.get(), "starts from the stored length")
+	resp, err := s.CreateShortURL(context.Background(), &model.CreateURLRequest{URL: "https://example.com/stored"})
+	require.NoError(t, err)
+	assert.Len(t, resp.ShortCode, 7)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodeStrategy produces the short codes of links created without a custom
+// alias. When a code is already taken URLService asks again with the next
+// attempt number, up to its retry limit. length is the number of characters
+// wanted, which URLService raises as the code space fills up.
+type CodeStrategy interface {
+	Next(ctx context.Context, longURL string, length, attempt int) (string, error)
+}
+
+// Strategy names accepted in SHORT_CODE_STRATEGY.
+const (
+	CodeStrategyHash    = "hash"    // SHA-256 of the URL; see ShortCodeGenerator
+	CodeStrategyRandom  = "random"  // see RandomStrategy
+	CodeStrategyCounter = "counter" // see CounterStrategy
+	CodeStrategyPool    = "pool"    // pre-generated; see keypool.Pool
+)
+
+// Next hashes longURL with the attempt number appended, so retries of the
+// same URL walk a fixed sequence of candidates.
+func (g *ShortCodeGenerator) Next(_ context.Context, longURL string, length, attempt int) (string, error) {
+	return g.generate(longURL+strconv.Itoa(attempt), length)
+}
+
+// RandomStrategy draws codes uniformly from crypto/rand. Unlike hashing,
+// the same URL gets unrelated codes on every attempt, and the chance of a
+// collision depends only on how full the code space is.
+type RandomStrategy struct{}
+
+// NewRandomStrategy creates a random code strategy.
+func NewRandomStrategy() *RandomStrategy {
+	return &RandomStrategy{}
+}
+
+// Next returns a random Base62 code of length characters.
+func (r *RandomStrategy) Next(_ context.Context, _ string, length, _ int) (string, error) {
+	code := make([]byte, 0, length)
+	buf := make([]byte, length+length/2)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrShortCodeGeneration, err)
+		}
+		for _, b := range buf {
+			// 248 = 4 * 62: dropping the bytes above keeps every
+			// character equally likely.
+			if b < 248 && len(code) < length {
+				code = append(code, base62Chars[b%62])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// CounterSource hands out disjoint blocks of a counter shared by every
+// replica. *repository.CodeCounterRepository implements it.
+type CounterSource interface {
+	NextBlock(ctx context.Context, size int64) (start int64, err error)
+}
+
+// maxCounterCodeLen is the longest code CounterStrategy supports: 62^10
+// still fits in a uint64.
+const maxCounterCodeLen = 10
+
+// CounterStrategy numbers links with a monotonic counter and scrambles the
+// number with a keyed permutation of the code space, so codes never collide
+// with each other yet do not reveal how many links exist or which comes
+// next. The counter is reserved from the source a block at a time; values
+// of a block left unused when the process exits are skipped.
+//
+// Each code length has its own permutation. Codes of different lengths
+// never collide, so the counter simply carries on when the length grows.
+type CounterStrategy struct {
+	source    CounterSource
+	blockSize int64
+	secret    []byte
+
+	mu        sync.Mutex
+	next, end int64
+	perms     map[int]*feistel
+}
+
+// NewCounterStrategy creates a strategy producing codes from counter blocks
+// of blockSize values. length is the code length expected at first and is
+// only validated here. secret keys the permutations; every replica must
+// use the same one.
+func NewCounterStrategy(source CounterSource, length int, blockSize int64, secret []byte) (*CounterStrategy, error) {
+	if length < 1 || length > maxCounterCodeLen {
+		return nil, fmt.Errorf("counter codes must be 1-%d characters, got %d", maxCounterCodeLen, length)
+	}
+	if blockSize < 1 {
+		return nil, fmt.Errorf("counter block size must be positive, got %d", blockSize)
+	}
+	return &CounterStrategy{
+		source:    source,
+		blockSize: blockSize,
+		secret:    secret,
+		perms:     map[int]*feistel{},
+	}, nil
+}
+
+// Next returns the length-character code of the next counter value. It
+// fails with ErrShortCodeGeneration once every code of that length is used.
+func (c *CounterStrategy) Next(ctx context.Context, _ string, length, _ int) (string, error) {
+	if length < 1 || length > maxCounterCodeLen {
+		return "", fmt.Errorf("%w: counter codes must be 1-%d characters, got %d",
+			ErrShortCodeGeneration, maxCounterCodeLen, length)
+	}
+	n, perm, err := c.take(ctx, length)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || uint64(n) >= perm.size {
+		return "", fmt.Errorf("%w: all %d-character codes are used", ErrShortCodeGeneration, length)
+	}
+	code := EncodeBase62(perm.apply(uint64(n)))
+	return strings.Repeat(string(base62Chars[0]), length-len(code)) + code, nil
+}
+
+// take returns the next counter value and the permutation for length,
+// reserving a new block when the current one is used up.
+func (c *CounterStrategy) take(ctx context.Context, length int) (int64, *feistel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	perm, ok := c.perms[length]
+	if !ok {
+		size := uint64(1)
+		for range length {
+			size *= 62
+		}
+		perm = newFeistel(size, c.secret)
+		c.perms[length] = perm
+	}
+	if c.next >= c.end {
+		start, err := c.source.NextBlock(ctx, c.blockSize)
+		if err != nil {
+			return 0, nil, fmt.Errorf("reserving short code block: %w", err)
+		}
+		c.next, c.end = start, start+c.blockSize
+	}
+	n := c.next
+	c.next++
+	return n, perm, nil
+}
+
+// feistel is a keyed permutation of [0, size): a four-round balanced
+// Feistel network over the smallest even number of bits that covers size,
+// cycle-walked until the result falls back inside the range.
+type feistel struct {
+	size     uint64
+	halfBits uint
+	keys     [4]uint64
+}
+
+func newFeistel(size uint64, secret []byte) *feistel {
+	n := uint(bits.Len64(size - 1))
+	n += n % 2
+	sum := sha256.Sum256(secret)
+	f := &feistel{size: size, halfBits: max(n/2, 1)}
+	for i := range f.keys {
+		f.keys[i] = binary.BigEndian.Uint64(sum[i*8:])
+	}
+	return f
+}
+
+// apply maps x, which must be below size, to its image.
+func (f *feistel) apply(x uint64) uint64 {
+	for {
+		x = f.encrypt(x)
+		if x < f.size {
+			return x
+		}
+	}
+}
+
+// encrypt runs x through every round of the network.
+func (f *feistel) encrypt(x uint64) uint64 {
+	mask := uint64(1)<<f.halfBits - 1
+	l, r := x>>f.halfBits, x&mask
+	for _, k := range f.keys {
+		l, r = r, l^(mix64(r^k)&mask)
+	}
+	return l<<f.halfBits | r
+}
+
+// mix64 is the SplitMix64 finaliser, used as the round function.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
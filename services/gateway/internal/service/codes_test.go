@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCounter hands out consecutive blocks and records each request.
+type fakeCounter struct {
+	next   int64
+	blocks int
+	err    error
+}
+
+func (f *fakeCounter) NextBlock(_ context.Context, size int64) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.blocks++
+	start := f.next
+	f.next += size
+	return start, nil
+}
+
+func TestShortCodeGenerator_Next(t *testing.T) {
+	g := NewShortCodeGenerator(6, 3, nil)
+	ctx := context.Background()
+
+	first, err := g.Next(ctx, "https://example.com", 6, 0)
+	require.NoError(t, err)
+	want, _ := g.Generate("https://example.com0")
+	assert.Equal(t, want, first)
+
+	again, _ := g.Next(ctx, "https://example.com", 6, 0)
+	retry, _ := g.Next(ctx, "https://example.com", 6, 1)
+	assert.Equal(t, first, again, "deterministic per attempt")
+	assert.NotEqual(t, first, retry)
+
+	longer, err := g.Next(ctx, "https://example.com", 8, 0)
+	require.NoError(t, err)
+	assert.Len(t, longer, 8)
+	assert.Equal(t, first, longer[:6])
+}
+
+func TestRandomStrategy(t *testing.T) {
+	r := NewRandomStrategy()
+	seen := map[string]bool{}
+	for range 200 {
+		code, err := r.Next(context.Background(), "https://example.com", 8, 0)
+		require.NoError(t, err)
+		require.Len(t, code, 8)
+		for _, c := range code {
+			assert.True(t, strings.ContainsRune(base62Chars, c), "unexpected character %q", c)
+		}
+		seen[code] = true
+	}
+	assert.Len(t, seen, 200, "same URL gets unrelated codes")
+}
+
+func TestFeistel_IsPermutation(t *testing.T) {
+	for _, size := range []uint64{1, 2, 62, 3844, 5000} {
+		f := newFeistel(size, []byte("secret"))
+		seen := make(map[uint64]bool, size)
+		for x := range size {
+			y := f.apply(x)
+			require.Less(t, y, size)
+			require.False(t, seen[y], "size %d: %d reached twice", size, y)
+			seen[y] = true
+		}
+	}
+
+	a, b := newFeistel(3844, []byte("one")), newFeistel(3844, []byte("two"))
+	same := 0
+	for x := range uint64(100) {
+		if a.apply(x) == b.apply(x) {
+			same++
+		}
+	}
+	assert.Less(t, same, 10, "the secret keys the permutation")
+}
+
+func TestCounterStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reserves blocks and never repeats", func(t *testing.T) {
+		src := &fakeCounter{}
+		c, err := NewCounterStrategy(src, 6, 10, []byte("secret"))
+		require.NoError(t, err)
+
+		seen := map[string]bool{}
+		for i := range 25 {
+			code, err := c.Next(ctx, "https://example.com", 6, 0)
+			require.NoError(t, err)
+			assert.Len(t, code, 6)
+			assert.False(t, seen[code], "code %d repeated", i)
+			seen[code] = true
+		}
+		assert.Equal(t, 3, src.blocks)
+	})
+
+	t.Run("codes are not sequential", func(t *testing.T) {
+		c, err := NewCounterStrategy(&fakeCounter{}, 6, 100, []byte("secret"))
+		require.NoError(t, err)
+		first, _ := c.Next(ctx, "", 6, 0)
+		second, _ := c.Next(ctx, "", 6, 0)
+		assert.NotEqual(t, "000000", first)
+		assert.NotEqual(t, first[:5], second[:5])
+	})
+
+	t.Run("carries on at a longer length", func(t *testing.T) {
+		c, err := NewCounterStrategy(&fakeCounter{next: 62*62 - 1}, 2, 10, nil)
+		require.NoError(t, err)
+		_, err = c.Next(ctx, "", 2, 0)
+		require.NoError(t, err)
+		code, err := c.Next(ctx, "", 3, 0)
+		require.NoError(t, err)
+		assert.Len(t, code, 3)
+		_, err = c.Next(ctx, "", 11, 0)
+		assert.ErrorIs(t, err, ErrShortCodeGeneration)
+	})
+
+	t.Run("fails once the code space is used", func(t *testing.T) {
+		c, err := NewCounterStrategy(&fakeCounter{next: 62*62 - 1}, 2, 10, nil)
+		require.NoError(t, err)
+		_, err = c.Next(ctx, "", 2, 0)
+		require.NoError(t, err)
+		_, err = c.Next(ctx, "", 2, 0)
+		assert.ErrorIs(t, err, ErrShortCodeGeneration)
+	})
+
+	t.Run("source errors are returned", func(t *testing.T) {
+		c, err := NewCounterStrategy(&fakeCounter{err: errors.New("db down")}, 6, 10, nil)
+		require.NoError(t, err)
+		_, err = c.Next(ctx, "", 6, 0)
+		assert.ErrorContains(t, err, "db down")
+	})
+
+	t.Run("rejects bad config", func(t *testing.T) {
+		_, err := NewCounterStrategy(&fakeCounter{}, 11, 10, nil)
+		assert.Error(t, err)
+		_, err = NewCounterStrategy(&fakeCounter{}, 6, 0, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// DomainLookup resolves registered branded domains.
+type DomainLookup interface {
+	GetByHost(ctx context.Context, host string) (*model.Domain, error)
+}
+
+// DomainStore persists branded domains.
+type DomainStore interface {
+	DomainLookup
+	Create(ctx context.Context, d *model.Domain) error
+	ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]*model.Domain, error)
+	Delete(ctx context.Context, workspaceID uuid.UUID, host string) error
+}
+
+// maxHostLen is the longest DNS name.
+const maxHostLen = 253
+
+// hostnamePattern matches a lower-case DNS name of at least two labels.
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// normalizeHost lower-cases host and drops a trailing dot, then checks that
+// what remains is a bare DNS name: no scheme, port or path.
+func normalizeHost(host string) (string, error) {
+	h := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if len(h) > maxHostLen || !hostnamePattern.MatchString(h) {
+		return "", fmt.Errorf("%w: %q is not a valid host name", ErrInvalidDomain, host)
+	}
+	return h, nil
+}
+
+// domainResult memoises one domainFor call within a batch.
+type domainResult struct {
+	domain string
+	err    error
+}
+
+// domainFor returns the domain a link created in ctx is served on: the
+// requested host, which must be registered to ctx's workspace, or else the
+// domain the request arrived on ("" when it arrived on none).
+func (s *URLService) domainFor(ctx context.Context, requested string) (string, error) {
+	if requested == "" {
+		return tenant.Domain(ctx), nil
+	}
+	host, err := normalizeHost(requested)
+	if err != nil {
+		return "", err
+	}
+	if host == tenant.Domain(ctx) {
+		return host, nil
+	}
+	if s.domains == nil {
+		return "", fmt.Errorf("%w: %q is not registered to this workspace", ErrInvalidDomain, host)
+	}
+
+	d, err := s.domains.GetByHost(ctx, host)
+	if errors.Is(err, repository.ErrDomainNotFound) || (err == nil && d.WorkspaceID != tenant.WorkspaceID(ctx)) {
+		return "", fmt.Errorf("%w: %q is not registered to this workspace", ErrInvalidDomain, host)
+	}
+	if err != nil {
+		return "", err
+	}
+	return d.Host, nil
+}
+
+// DomainService manages the branded domains of the caller's workspace.
+type DomainService struct {
+	repo         DomainStore
+	logger       *slog.Logger
+	requireAdmin bool
+}
+
+// DomainServiceInterface defines the contract for domain management.
+type DomainServiceInterface interface {
+	RegisterDomain(ctx context.Context, req *model.CreateDomainRequest) (*model.Domain, error)
+	ListDomains(ctx context.Context) ([]*model.Domain, error)
+	RemoveDomain(ctx context.Context, host string) error
+}
+
+// NewDomainService creates a new domain service. With requireAdmin set only
+// API keys with the admin scope may register or remove domains.
+func NewDomainService(repo DomainStore, logger *slog.Logger, requireAdmin bool) *DomainService {
+	return &DomainService{repo: repo, logger: logger, requireAdmin: requireAdmin}
+}
+
+// RegisterDomain binds req.Host to the workspace of ctx. Requests arriving
+// on the host then resolve to the workspace once the tenant cache expires.
+func (s *DomainService) RegisterDomain(ctx context.Context, req *model.CreateDomainRequest) (*model.Domain, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	host, err := normalizeHost(req.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &model.Domain{Host: host, WorkspaceID: tenant.WorkspaceID(ctx)}
+	if err := s.repo.Create(ctx, d); err != nil {
+		if errors.Is(err, repository.ErrDomainConflict) {
+			s.logger.WarnContext(ctx, "domain already registered",
+				slog.String("host", host))
+			return nil, ErrDomainExists
+		}
+		s.logger.ErrorContext(ctx, "failed to register domain",
+			slog.String("host", host),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "domain registered",
+		slog.String("host", host),
+		slog.String("workspace", d.WorkspaceID.String()))
+	return d, nil
+}
+
+// ListDomains returns the domains of ctx's workspace ordered by host.
+func (s *DomainService) ListDomains(ctx context.Context) ([]*model.Domain, error) {
+	domains, err := s.repo.ListByWorkspace(ctx, tenant.WorkspaceID(ctx))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list domains",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	return domains, nil
+}
+
+// RemoveDomain unbinds host from ctx's workspace. A domain that links were
+// created on cannot be removed until those links are deleted.
+func (s *DomainService) RemoveDomain(ctx context.Context, host string) error {
+	if err := s.authorize(ctx); err != nil {
+		return err
+	}
+
+	err := s.repo.Delete(ctx, tenant.WorkspaceID(ctx), strings.ToLower(host))
+	switch {
+	case err == nil:
+		s.logger.InfoContext(ctx, "domain removed",
+			slog.String("host", host))
+		return nil
+	case errors.Is(err, repository.ErrDomainNotFound):
+		return ErrDomainNotFound
+	case errors.Is(err, repository.ErrDomainInUse):
+		return ErrDomainInUse
+	default:
+		s.logger.ErrorContext(ctx, "failed to remove domain",
+			slog.String("host", host),
+			slog.String("error", err.Error()))
+		return err
+	}
+}
+
+// authorize checks that the caller in ctx may change the workspace's
+// domains. It is a no-op unless admin enforcement is enabled.
+func (s *DomainService) authorize(ctx context.Context) error {
+	if !s.requireAdmin {
+		return nil
+	}
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return ErrUnauthorized
+	}
+	if !id.Admin {
+		s.logger.WarnContext(ctx, "non-admin key tried to manage domains",
+			slog.String("key_id", id.KeyID.String()))
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Ensure DomainService implements DomainServiceInterface at compile time
+var _ DomainServiceInterface = (*DomainService)(nil)
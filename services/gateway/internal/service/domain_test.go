@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// fakeDomainStore keeps domains in memory, keyed by host.
+type fakeDomainStore struct {
+	domains map[string]*model.Domain
+	inUse   map[string]bool
+}
+
+func newFakeDomainStore(domains ...*model.Domain) *fakeDomainStore {
+	f := &fakeDomainStore{domains: make(map[string]*model.Domain), inUse: make(map[string]bool)}
+	for _, d := range domains {
+		f.domains[d.Host] = d
+	}
+	return f
+}
+
+func (f *fakeDomainStore) GetByHost(_ context.Context, host string) (*model.Domain, error) {
+	if d, ok := f.domains[host]; ok {
+		return d, nil
+	}
+	return nil, repository.ErrDomainNotFound
+}
+
+func (f *fakeDomainStore) Create(_ context.Context, d *model.Domain) error {
+	if _, ok := f.domains[d.Host]; ok {
+		return repository.ErrDomainConflict
+	}
+	d.ID = uuid.New()
+	f.domains[d.Host] = d
+	return nil
+}
+
+func (f *fakeDomainStore) ListByWorkspace(_ context.Context, workspaceID uuid.UUID) ([]*model.Domain, error) {
+	var out []*model.Domain
+	for _, d := range f.domains {
+		if d.WorkspaceID == workspaceID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDomainStore) Delete(_ context.Context, workspaceID uuid.UUID, host string) error {
+	d, ok := f.domains[host]
+	if !ok || d.WorkspaceID != workspaceID {
+		return repository.ErrDomainNotFound
+	}
+	if f.inUse[host] {
+		return repository.ErrDomainInUse
+	}
+	delete(f.domains, host)
+	return nil
+}
+
+// domainRepo serves links from memory by short code, ignoring workspaces.
+type domainRepo struct {
+	quotaRepo
+}
+
+func (r *domainRepo) GetByCode(_ context.Context, code string) (*model.URL, error) {
+	for _, u := range r.links {
+		if u.ShortCode == code {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *domainRepo) GetClickCount(context.Context, string) (int64, error) { return 0, nil }
+
+func TestNormalizeHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"go.brand-a.com":        "go.brand-a.com",
+		" Brand-B.LINK. ":       "brand-b.link",
+		"xn--bcher-kva.example": "xn--bcher-kva.example",
+	} {
+		got, err := normalizeHost(host)
+		require.NoError(t, err, host)
+		assert.Equal(t, want, got)
+	}
+	for _, host := range []string{
+		"", "localhost", "https://go.brand-a.com", "go.brand-a.com:8443", "go.brand-a.com/x",
+		"-bad.example", "bad-.example", "under_score.example", strings.Repeat("a.", 127) + "com",
+	} {
+		_, err := normalizeHost(host)
+		assert.ErrorIs(t, err, ErrInvalidDomain, host)
+	}
+}
+
+func TestURLService_Domains(t *testing.T) {
+	acme := &model.Workspace{ID: uuid.New(), Slug: "acme", BaseURL: "https://acme.example"}
+	other := &model.Workspace{ID: uuid.New(), Slug: "other"}
+	domains := newFakeDomainStore(
+		&model.Domain{Host: "go.brand-a.com", WorkspaceID: acme.ID},
+		&model.Domain{Host: "brand-b.link", WorkspaceID: acme.ID},
+		&model.Domain{Host: "other.link", WorkspaceID: other.ID},
+	)
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3,
+		URLServiceOptions{Domains: domains})
+
+	apiCtx := tenant.WithWorkspace(context.Background(), acme)
+	onBrandA := tenant.WithDomain(apiCtx, "go.brand-a.com")
+
+	t.Run("requested domain sets the short URL", func(t *testing.T) {
+		resp, err := s.CreateShortURL(apiCtx, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "sale", Domain: "Brand-B.link"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://brand-b.link/sale", resp.ShortURL)
+		assert.Equal(t, "brand-b.link", repo.links[len(repo.links)-1].Domain)
+	})
+
+	t.Run("request domain is the default", func(t *testing.T) {
+		resp, err := s.CreateShortURL(onBrandA, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "spring"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://go.brand-a.com/spring", resp.ShortURL)
+	})
+
+	t.Run("no domain falls back to the base URL", func(t *testing.T) {
+		resp, err := s.CreateShortURL(apiCtx, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "plain"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://acme.example/plain", resp.ShortURL)
+	})
+
+	t.Run("domain of another workspace is rejected", func(t *testing.T) {
+		_, err := s.CreateShortURL(apiCtx, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "xx1", Domain: "other.link"})
+		assert.ErrorIs(t, err, ErrInvalidDomain)
+		_, err = s.CreateShortURL(apiCtx, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "xx2", Domain: "unknown.link"})
+		assert.ErrorIs(t, err, ErrInvalidDomain)
+	})
+
+	t.Run("batch items resolve their own domains", func(t *testing.T) {
+		results, err := s.CreateShortURLBatch(apiCtx, []model.CreateURLRequest{
+			{URL: "https://a.example", CustomAlias: "bat1", Domain: "go.brand-a.com"},
+			{URL: "https://a.example", CustomAlias: "bat2", Domain: "other.link"},
+			{URL: "https://a.example", CustomAlias: "bat3"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://go.brand-a.com/bat1", results[0].URL.ShortURL)
+		assert.ErrorIs(t, results[1].Err, ErrInvalidDomain)
+		assert.Equal(t, "https://acme.example/bat3", results[2].URL.ShortURL)
+	})
+
+	t.Run("redirect resolves host and code together", func(t *testing.T) {
+		_, err := s.Redirect(tenant.WithDomain(apiCtx, "brand-b.link"), &model.RedirectRequest{Code: "sale"})
+		assert.NoError(t, err)
+		_, err = s.Redirect(onBrandA, &model.RedirectRequest{Code: "sale"})
+		assert.ErrorIs(t, err, ErrURLNotFound, "a link on brand-b.link is not served on go.brand-a.com")
+		_, err = s.Redirect(apiCtx, &model.RedirectRequest{Code: "sale"})
+		assert.ErrorIs(t, err, ErrURLNotFound)
+
+		_, err = s.Redirect(onBrandA, &model.RedirectRequest{Code: "plain"})
+		assert.NoError(t, err, "links without a domain are served on every host")
+
+		got, err := s.GetURL(apiCtx, "sale")
+		require.NoError(t, err, "the API is not host-bound")
+		assert.Equal(t, "brand-b.link", got.Domain)
+		assert.Equal(t, "https://brand-b.link/sale", got.ShortURL)
+	})
+}
+
+func TestDomainService(t *testing.T) {
+	acme := &model.Workspace{ID: uuid.New(), Slug: "acme"}
+	store := newFakeDomainStore(&model.Domain{Host: "taken.link", WorkspaceID: uuid.New()})
+	s := NewDomainService(store, slog.New(slog.NewTextHandler(io.Discard, nil)), true)
+
+	ctx := tenant.WithWorkspace(context.Background(), acme)
+	admin := auth.WithIdentity(ctx, &auth.Identity{Owner: "ops", Admin: true, WorkspaceID: acme.ID})
+	member := auth.WithIdentity(ctx, &auth.Identity{Owner: "dev", WorkspaceID: acme.ID})
+
+	_, err := s.RegisterDomain(ctx, &model.CreateDomainRequest{Host: "go.brand-a.com"})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = s.RegisterDomain(member, &model.CreateDomainRequest{Host: "go.brand-a.com"})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	d, err := s.RegisterDomain(admin, &model.CreateDomainRequest{Host: "GO.Brand-A.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "go.brand-a.com", d.Host)
+	assert.Equal(t, acme.ID, d.WorkspaceID)
+
+	_, err = s.RegisterDomain(admin, &model.CreateDomainRequest{Host: "taken.link"})
+	assert.ErrorIs(t, err, ErrDomainExists)
+	_, err = s.RegisterDomain(admin, &model.CreateDomainRequest{Host: "https://x.link/"})
+	assert.ErrorIs(t, err, ErrInvalidDomain)
+
+	list, err := s.ListDomains(member)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	assert.ErrorIs(t, s.RemoveDomain(member, "go.brand-a.com"), ErrForbidden)
+	assert.ErrorIs(t, s.RemoveDomain(admin, "taken.link"), ErrDomainNotFound, "another workspace's domain")
+	store.inUse["go.brand-a.com"] = true
+	assert.ErrorIs(t, s.RemoveDomain(admin, "go.brand-a.com"), ErrDomainInUse)
+	store.inUse["go.brand-a.com"] = false
+	assert.NoError(t, s.RemoveDomain(admin, "Go.Brand-A.com"))
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// UnavailableError reports a visit to a link that exists but cannot be
+// followed: it is not active yet, has expired or has used up its
+// max_clicks. It matches its Reason (ErrURLNotActive, ErrURLExpired or
+// ErrClickLimitReached) with errors.Is. FallbackURL, when set, is where the
+// visitor should be sent instead.
+type UnavailableError struct {
+	Reason      error
+	ActiveAt    time.Time // activation time, for ErrURLNotActive
+	FallbackURL string
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Reason == ErrURLNotActive {
+		return fmt.Sprintf("%s: active from %s", e.Reason, e.ActiveAt.Format(time.RFC3339))
+	}
+	return e.Reason.Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Reason
+}
+
+// unavailable builds the error for a visit to url that cannot be followed
+// for reason. The link's own fallback_url takes precedence over that of
+// the workspace the request resolved to.
+func unavailable(ctx context.Context, url *model.URL, reason error) *UnavailableError {
+	e := &UnavailableError{Reason: reason, FallbackURL: url.FallbackURL}
+	if url.NotBefore != nil {
+		e.ActiveAt = *url.NotBefore
+	}
+	if e.FallbackURL == "" {
+		if ws, ok := tenant.FromContext(ctx); ok {
+			e.FallbackURL = ws.FallbackURL
+		}
+	}
+	return e
+}
+
+// validateFallbackURL checks an optional fallback destination.
+func validateFallbackURL(raw string) error {
+	if raw != "" && validateURL(raw) != nil {
+		return fmt.Errorf("%w: fallback_url", ErrInvalidURL)
+	}
+	return nil
+}
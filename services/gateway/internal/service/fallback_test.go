@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+func TestURLService_Fallbacks(t *testing.T) {
+	repo := &usesRepo{uses: map[string]int64{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ws := &model.Workspace{ID: uuid.New(), Slug: "acme", FallbackURL: "https://acme.example/expired"}
+	ctx := tenant.WithWorkspace(context.Background(), ws)
+
+	fallbackOf := func(t *testing.T, err error) string {
+		t.Helper()
+		var unavailable *UnavailableError
+		require.True(t, errors.As(err, &unavailable), "got %v", err)
+		return unavailable.FallbackURL
+	}
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/sale", CustomAlias: "sale"})
+	require.NoError(t, err)
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/promo", CustomAlias: "promo",
+		FallbackURL: "https://example.com/promo-over"})
+	require.NoError(t, err)
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/once", CustomAlias: "once", MaxClicks: 1})
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Minute)
+	for _, link := range repo.links[:2] {
+		link.ExpiresAt = &expired
+	}
+
+	t.Run("expired link uses the workspace fallback", func(t *testing.T) {
+		_, err := s.Redirect(ctx, &model.RedirectRequest{Code: "sale"})
+		assert.ErrorIs(t, err, ErrURLExpired)
+		assert.Equal(t, "https://acme.example/expired", fallbackOf(t, err))
+	})
+
+	t.Run("link fallback takes precedence", func(t *testing.T) {
+		_, err := s.Redirect(ctx, &model.RedirectRequest{Code: "promo"})
+		assert.ErrorIs(t, err, ErrURLExpired)
+		assert.Equal(t, "https://example.com/promo-over", fallbackOf(t, err))
+	})
+
+	t.Run("exhausted link uses the workspace fallback", func(t *testing.T) {
+		_, err := s.Redirect(ctx, &model.RedirectRequest{Code: "once"})
+		require.NoError(t, err)
+		_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "once"})
+		assert.ErrorIs(t, err, ErrClickLimitReached)
+		assert.Equal(t, "https://acme.example/expired", fallbackOf(t, err))
+	})
+
+	t.Run("owners get the plain error", func(t *testing.T) {
+		_, err := s.GetURL(ctx, "sale")
+		assert.Equal(t, ErrURLExpired, err)
+	})
+
+	t.Run("workspace without a fallback", func(t *testing.T) {
+		_, err := s.Redirect(tenant.WithWorkspace(ctx, &model.Workspace{ID: ws.ID}), &model.RedirectRequest{Code: "sale"})
+		assert.ErrorIs(t, err, ErrURLExpired)
+		assert.Empty(t, fallbackOf(t, err))
+	})
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestAuthorize(t *testing.T) {
+	s := &URLService{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), enforceOwnership: true}
+	owned := &model.URL{ShortCode: "abc", Owner: "alice"}
+	as := func(id *auth.Identity) context.Context {
+		return auth.WithIdentity(context.Background(), id)
+	}
+
+	assert.ErrorIs(t, s.authorize(context.Background(), owned), ErrUnauthorized)
+	assert.NoError(t, s.authorize(as(&auth.Identity{Owner: "alice"}), owned))
+	assert.ErrorIs(t, s.authorize(as(&auth.Identity{Owner: "bob"}), owned), ErrForbidden)
+	assert.NoError(t, s.authorize(as(&auth.Identity{Owner: "ops", Admin: true}), owned))
+	assert.ErrorIs(t, s.authorize(as(&auth.Identity{Owner: "alice"}), &model.URL{ShortCode: "anon"}), ErrForbidden)
+
+	s.enforceOwnership = false
+	assert.NoError(t, s.authorize(context.Background(), owned), "no checks when enforcement is off")
+}
+
+func TestListOwner(t *testing.T) {
+	s := &URLService{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), enforceOwnership: true}
+	as := func(id *auth.Identity) context.Context {
+		return auth.WithIdentity(context.Background(), id)
+	}
+
+	_, err := s.listOwner(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	owner, err := s.listOwner(as(&auth.Identity{Owner: "alice"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", owner)
+	owner, err = s.listOwner(as(&auth.Identity{Owner: "ops", Admin: true}))
+	assert.NoError(t, err)
+	assert.Equal(t, "", owner, "admins list every owner")
+	_, err = s.listOwner(as(&auth.Identity{}))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	s.enforceOwnership = false
+	owner, err = s.listOwner(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "", owner)
+}
+
+func TestOwnerFromContext(t *testing.T) {
+	assert.Equal(t, "", ownerFromContext(context.Background()))
+	assert.Equal(t, "alice", ownerFromContext(auth.WithIdentity(context.Background(), &auth.Identity{Owner: "alice"})))
+}
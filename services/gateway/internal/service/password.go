@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordLen is the longest password bcrypt can hash; it rejects
+// anything longer rather than silently ignoring the excess.
+const maxPasswordLen = 72
+
+// defaultUnlockTTL is how long a correct password is remembered when no
+// UnlockSigner is configured.
+const defaultUnlockTTL = time.Hour
+
+// hashPassword checks a link password and returns its bcrypt hash.
+func hashPassword(password string) (string, error) {
+	if password == "" || len(password) > maxPasswordLen {
+		return "", fmt.Errorf("%w: must be 1 to %d bytes", ErrInvalidPassword, maxPasswordLen)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// UnlockSigner issues and checks the tokens a visitor receives after entering
+// a link's password. A token names one link, carries its own expiry and is
+// bound to the password hash, so changing or removing the password revokes
+// every token issued for the old one.
+type UnlockSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewUnlockSigner creates a signer whose tokens last ttl (0 = one hour).
+// With an empty secret a random one is generated, so tokens do not survive
+// a restart and are not accepted by other replicas.
+func NewUnlockSigner(secret []byte, ttl time.Duration) *UnlockSigner {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	if ttl <= 0 {
+		ttl = defaultUnlockTTL
+	}
+	return &UnlockSigner{secret: secret, ttl: ttl}
+}
+
+// Sign returns a token unlocking url until now plus the signer's TTL.
+func (s *UnlockSigner) Sign(url *model.URL, now time.Time) *model.UnlockGrant {
+	expiresAt := now.Add(s.ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return &model.UnlockGrant{
+		Token:     exp + "." + base64.RawURLEncoding.EncodeToString(s.mac(url, exp)),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// Verify reports whether token was signed for url's current password and
+// has not expired at now.
+func (s *UnlockSigner) Verify(url *model.URL, token string, now time.Time) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, s.mac(url, exp))
+}
+
+func (s *UnlockSigner) mac(url *model.URL, exp string) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(m, "%s\x00%s\x00%s\x00%s", url.WorkspaceID, url.ShortCode, url.PasswordHash, exp)
+	return m.Sum(nil)
+}
+
+// UnlockURL checks a visitor's password for a protected link and grants a
+// token that lets later visits through without asking again. Like Redirect
+// it resolves the code on the request's host. A wrong password returns
+// ErrWrongPassword; callers are expected to throttle attempts.
+func (s *URLService) UnlockURL(ctx context.Context, req *model.UnlockRequest) (*model.UnlockGrant, error) {
+	url, err := s.getAndValidateURL(ctx, req.Code, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.PasswordHash != "" {
+		err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(req.Password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.logger.WarnContext(ctx, "wrong link password",
+				slog.String("code", req.Code))
+			return nil, ErrWrongPassword
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to check link password",
+				slog.String("code", req.Code),
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	s.logger.InfoContext(ctx, "link unlocked",
+		slog.String("code", req.Code))
+	return s.unlocks.Sign(url, time.Now()), nil
+}
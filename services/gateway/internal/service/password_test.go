@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("s3cret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$"), "bcrypt hash")
+
+	for _, pw := range []string{"", strings.Repeat("x", maxPasswordLen+1)} {
+		_, err := hashPassword(pw)
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	}
+}
+
+func TestUnlockSigner(t *testing.T) {
+	now := time.Now()
+	link := &model.URL{WorkspaceID: uuid.New(), ShortCode: "docs", PasswordHash: "$2a$10$hash"}
+	s := NewUnlockSigner([]byte("secret"), time.Hour)
+
+	grant := s.Sign(link, now)
+	assert.WithinDuration(t, now.Add(time.Hour), grant.ExpiresAt, time.Second)
+	assert.True(t, s.Verify(link, grant.Token, now))
+	assert.True(t, s.Verify(link, grant.Token, now.Add(59*time.Minute)))
+
+	assert.False(t, s.Verify(link, grant.Token, now.Add(time.Hour+time.Second)), "expired")
+	assert.False(t, s.Verify(&model.URL{WorkspaceID: link.WorkspaceID, ShortCode: "other", PasswordHash: link.PasswordHash}, grant.Token, now),
+		"token names one link")
+	assert.False(t, s.Verify(&model.URL{WorkspaceID: uuid.New(), ShortCode: "docs", PasswordHash: link.PasswordHash}, grant.Token, now),
+		"same code in another workspace")
+	assert.False(t, s.Verify(&model.URL{WorkspaceID: link.WorkspaceID, ShortCode: "docs", PasswordHash: "$2a$10$new"}, grant.Token, now),
+		"changing the password revokes tokens")
+	assert.False(t, NewUnlockSigner([]byte("other"), time.Hour).Verify(link, grant.Token, now), "other key")
+
+	exp, _, _ := strings.Cut(grant.Token, ".")
+	for _, token := range []string{"", "garbage", exp, exp + ".", "9999999999." + strings.SplitN(grant.Token, ".", 2)[1]} {
+		assert.False(t, s.Verify(link, token, now), token)
+	}
+}
+
+func TestURLService_PasswordProtectedLinks(t *testing.T) {
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://intranet.example/doc", CustomAlias: "doc", Password: "hunter2"})
+	require.NoError(t, err)
+	assert.NotContains(t, repo.links[0].PasswordHash, "hunter2")
+
+	got, err := s.GetURL(ctx, "doc")
+	require.NoError(t, err)
+	assert.True(t, got.Protected)
+
+	_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "doc"})
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "doc", Unlock: "1.forged"})
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	_, err = s.UnlockURL(ctx, &model.UnlockRequest{Code: "doc", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	_, err = s.UnlockURL(ctx, &model.UnlockRequest{Code: "missing", Password: "hunter2"})
+	assert.ErrorIs(t, err, ErrURLNotFound)
+
+	grant, err := s.UnlockURL(ctx, &model.UnlockRequest{Code: "doc", Password: "hunter2"})
+	require.NoError(t, err)
+	target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "doc", Unlock: grant.Token})
+	require.NoError(t, err)
+	assert.Equal(t, "https://intranet.example/doc", target.URL)
+	assert.True(t, target.NoStore)
+
+	t.Run("invalid password rejected on create", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://a.example", CustomAlias: "long", Password: strings.Repeat("x", 73)})
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	})
+
+	t.Run("batch hashes each distinct password once", func(t *testing.T) {
+		results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+			{URL: "https://a.example/1", CustomAlias: "bp1", Password: "shared"},
+			{URL: "https://a.example/2", CustomAlias: "bp2", Password: "shared"},
+			{URL: "https://a.example/3", CustomAlias: "bp3"},
+		})
+		require.NoError(t, err)
+		for _, r := range results {
+			require.NoError(t, r.Err)
+		}
+		n := len(repo.links)
+		assert.NotEmpty(t, repo.links[n-3].PasswordHash)
+		assert.Equal(t, repo.links[n-3].PasswordHash, repo.links[n-2].PasswordHash)
+		assert.Empty(t, repo.links[n-1].PasswordHash)
+	})
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// defaultRedirectStatus is used when neither the link nor the server
+// configuration chooses one.
+const defaultRedirectStatus = http.StatusMovedPermanently
+
+// ValidRedirectStatus reports whether status is a redirect a link may use.
+// 301 and 308 are permanent and cached by browsers, so repeat visits skip
+// the gateway and are not counted; 302 and 307 reach it every time. 307 and
+// 308 also preserve the request method and body.
+func ValidRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// validateRedirectType checks a requested per-link status; 0 selects the
+// server default.
+func validateRedirectType(status int) error {
+	if status != 0 && !ValidRedirectStatus(status) {
+		return fmt.Errorf("%w: %d is not one of 301, 302, 307, 308", ErrInvalidRedirect, status)
+	}
+	return nil
+}
+
+// redirectStatus returns the status url is redirected with.
+func (s *URLService) redirectStatus(url *model.URL) int {
+	if url.RedirectType != 0 {
+		return url.RedirectType
+	}
+	return s.defaultRedirect
+}
+
+// redirectTarget builds the response to a visit of url. The first of the
+// link's rules the visitor matches replaces its destination; visitors no
+// rule matches are split between the link's variants, if it has any. The
+// link's tracking parameters are added to whichever destination is chosen,
+// and with ForwardQuery set the visitor's query string is merged in after
+// them, so a visitor cannot override the tags. Redirects of
+// password-protected and click-limited links must not be cached, whatever
+// their status: a cached redirect would bypass the check. Nor may those
+// picked by country, which no cache can key on, or by variant, which would
+// pin the browser to one variant and hide its later visits from the split's
+// click counts.
+func (s *URLService) redirectTarget(url *model.URL, req *model.RedirectRequest) *model.RedirectTarget {
+	dest := url.OriginalURL
+	var rule *model.RedirectRule
+	if len(url.Rules) > 0 {
+		if rule = matchRule(url.Rules, visitorFrom(req)); rule != nil {
+			dest = rule.URL
+		}
+	}
+	var variant string
+	if rule == nil && len(url.Variants) > 0 {
+		v := assignVariant(url, req.Variant)
+		dest, variant = v.URL, v.Name
+	}
+	dest = applyTracking(dest, url.Tracking)
+	if url.ForwardQuery {
+		dest = mergeQuery(dest, req.Query)
+	}
+	var tracking *model.TrackingParams
+	if !url.Tracking.IsZero() {
+		tracking = url.Tracking
+	}
+	return &model.RedirectTarget{
+		URL:      dest,
+		Status:   s.redirectStatus(url),
+		NoStore:  url.PasswordHash != "" || url.MaxClicks > 0 || rulesUseCountry(url.Rules) || len(url.Variants) > 0,
+		Vary:     rulesVary(url.Rules),
+		Variant:  variant,
+		Sticky:   variant != "" && url.StickyVariants,
+		Tracking: tracking,
+	}
+}
+
+// mergeQuery appends the visitor's query parameters to dest. Parameters the
+// destination already sets win, so a visitor cannot override values the link
+// owner fixed (campaign tags, affiliate IDs); every value of a parameter the
+// destination does not set is passed on. The destination's own query string
+// and fragment are kept byte for byte. A destination that does not parse is
+// returned unchanged.
+func mergeQuery(dest string, incoming url.Values) string {
+	if len(incoming) == 0 {
+		return dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return dest
+	}
+
+	own := u.Query()
+	extra := url.Values{}
+	for key, values := range incoming {
+		if _, ok := own[key]; !ok {
+			extra[key] = values
+		}
+	}
+	if len(extra) == 0 {
+		return dest
+	}
+
+	if u.RawQuery == "" {
+		u.RawQuery = extra.Encode()
+	} else {
+		u.RawQuery += "&" + extra.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		dest     string
+		incoming string
+		want     string
+	}{
+		{"no incoming query", "https://example.com/p?a=1", "", "https://example.com/p?a=1"},
+		{"destination without query", "https://example.com/p", "utm_source=x", "https://example.com/p?utm_source=x"},
+		{"appended after destination query", "https://example.com/p?a=1", "b=2", "https://example.com/p?a=1&b=2"},
+		{"destination wins on conflict", "https://example.com/p?a=1", "a=evil&b=2", "https://example.com/p?a=1&b=2"},
+		{"all conflicting", "https://example.com/p?a=1", "a=2", "https://example.com/p?a=1"},
+		{"repeated values kept", "https://example.com/p", "t=1&t=2", "https://example.com/p?t=1&t=2"},
+		{"fragment preserved", "https://example.com/p?a=1#top", "b=2", "https://example.com/p?a=1&b=2#top"},
+		{"destination encoding untouched", "https://example.com/p?q=a+b%2Fc", "b=x y", "https://example.com/p?q=a+b%2Fc&b=x+y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			incoming, err := url.ParseQuery(tt.incoming)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, mergeQuery(tt.dest, incoming))
+		})
+	}
+}
+
+func TestValidateRedirectType(t *testing.T) {
+	for _, status := range []int{0, 301, 302, 307, 308} {
+		assert.NoError(t, validateRedirectType(status), status)
+	}
+	for _, status := range []int{200, 300, 303, 304, 404, -1} {
+		assert.ErrorIs(t, validateRedirectType(status), ErrInvalidRedirect, status)
+	}
+}
+
+func TestURLService_RedirectTarget(t *testing.T) {
+	link := &model.URL{OriginalURL: "https://example.com/p?a=1"}
+	req := &model.RedirectRequest{Code: "abc", Query: url.Values{"b": {"2"}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	s := NewURLService(nil, logger, "http://localhost", 6, 3)
+	target := s.redirectTarget(link, req)
+	assert.Equal(t, http.StatusMovedPermanently, target.Status, "301 without configuration")
+	assert.Equal(t, "https://example.com/p?a=1", target.URL, "query not forwarded unless enabled")
+
+	s = NewURLService(nil, logger, "http://localhost", 6, 3, URLServiceOptions{DefaultRedirectStatus: http.StatusFound})
+	assert.Equal(t, http.StatusFound, s.redirectTarget(link, req).Status, "server default applies")
+
+	s = NewURLService(nil, logger, "http://localhost", 6, 3, URLServiceOptions{DefaultRedirectStatus: 303})
+	assert.Equal(t, http.StatusMovedPermanently, s.redirectTarget(link, req).Status, "invalid default ignored")
+
+	link.RedirectType = http.StatusTemporaryRedirect
+	link.ForwardQuery = true
+	target = s.redirectTarget(link, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, target.Status, "per-link status wins")
+	assert.Equal(t, "https://example.com/p?a=1&b=2", target.URL)
+}
+
+// usesRepo counts link uses the way URLRepository.ConsumeUse does.
+type usesRepo struct {
+	domainRepo
+	uses map[string]int64
+}
+
+func (r *usesRepo) ConsumeUse(ctx context.Context, code string) (int64, error) {
+	url, err := r.GetByCode(ctx, code)
+	if err != nil || r.uses[code] >= url.MaxClicks {
+		return 0, repository.ErrUsesExhausted
+	}
+	r.uses[code]++
+	return r.uses[code], nil
+}
+
+func (r *usesRepo) GetUses(_ context.Context, code string) (int64, error) {
+	return r.uses[code], nil
+}
+
+func TestURLService_ClickLimitedLinks(t *testing.T) {
+	repo := &usesRepo{uses: map[string]int64{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/invite", CustomAlias: "invite", MaxClicks: 2})
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "invite")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.MaxClicks)
+	require.NotNil(t, got.RemainingClicks)
+	assert.Equal(t, int64(2), *got.RemainingClicks)
+
+	target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "invite"})
+	require.NoError(t, err)
+	assert.True(t, target.NoStore, "limited redirects must not be cached")
+	_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "invite"})
+	require.NoError(t, err)
+	_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "invite"})
+	assert.ErrorIs(t, err, ErrClickLimitReached)
+
+	got, err = s.GetURL(ctx, "invite")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), *got.RemainingClicks)
+
+	t.Run("unlimited links report no remaining count", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", CustomAlias: "open"})
+		require.NoError(t, err)
+		got, err := s.GetURL(ctx, "open")
+		require.NoError(t, err)
+		assert.Nil(t, got.RemainingClicks)
+		_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "open"})
+		require.NoError(t, err)
+		assert.Zero(t, repo.uses["open"], "no use consumed")
+	})
+
+	t.Run("password prompt consumes nothing", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", CustomAlias: "once", MaxClicks: 1, Password: "pw"})
+		require.NoError(t, err)
+		_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "once"})
+		assert.ErrorIs(t, err, ErrPasswordRequired)
+		assert.Zero(t, repo.uses["once"])
+	})
+
+	t.Run("negative limit rejected", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", MaxClicks: -1})
+		assert.ErrorIs(t, err, ErrInvalidClickLimit)
+
+		results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{{URL: "https://example.com/", MaxClicks: -1}})
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrInvalidClickLimit)
+	})
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// reusable reports whether req may be answered with an existing link: it
+// opted in and asks for nothing a shared link could fail to honour.
+func reusable(req *model.CreateURLRequest) bool {
+	return req.ReuseExisting && req.CustomAlias == "" &&
+		req.ExpiresIn == 0 && req.ExpiresAt == nil && req.ExpiresAfter == "" &&
+		req.RedirectType == 0 && !req.ForwardQuery && req.Password == "" && req.MaxClicks == 0 &&
+		req.NotBefore == nil && req.FallbackURL == "" && len(req.Rules) == 0 && len(req.Variants) == 0 &&
+		req.Tracking.IsZero()
+}
+
+// canonicalHash returns the SHA-256 of rawURL's canonical form, or nil when
+// it does not parse.
+func canonicalHash(rawURL string) []byte {
+	c, err := Canonicalize(rawURL)
+	if err != nil {
+		return nil
+	}
+	h := sha256.Sum256([]byte(c))
+	return h[:]
+}
+
+// findReusable returns the caller's existing link to rawURL on domain that a
+// reuse_existing request may be given, or nil when there is none.
+func (s *URLService) findReusable(ctx context.Context, rawURL, domain string) (*model.URL, error) {
+	hash := canonicalHash(rawURL)
+	if hash == nil {
+		return nil, nil
+	}
+	url, err := s.repo.FindReusable(ctx, hash, ownerFromContext(ctx), domain)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return url, err
+}
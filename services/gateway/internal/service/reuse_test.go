@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// reuseRepo finds reusable links in memory by canonical hash, owner and
+// domain, ignoring expiry and options.
+type reuseRepo struct {
+	domainRepo
+}
+
+func (r *reuseRepo) FindReusable(_ context.Context, hash []byte, owner, domain string) (*model.URL, error) {
+	for _, u := range r.links {
+		if u.CanonicalHash != nil && bytes.Equal(u.CanonicalHash, hash) && u.Owner == owner && u.Domain == domain {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestReusable(t *testing.T) {
+	assert.True(t, reusable(&model.CreateURLRequest{URL: "https://example.com", ReuseExisting: true, Domain: "go.example"}))
+	assert.False(t, reusable(&model.CreateURLRequest{URL: "https://example.com"}), "opt-in")
+	for name, req := range map[string]model.CreateURLRequest{
+		"custom alias": {CustomAlias: "mine"},
+		"expiry":       {ExpiresIn: 7},
+		"password":     {Password: "pw"},
+		"click limit":  {MaxClicks: 1},
+		"tracking":     {Tracking: &model.TrackingParams{Source: "x"}},
+		"rules":        {Rules: []model.RedirectRule{{OS: []string{"ios"}, URL: "https://example.com/ios"}}},
+	} {
+		req.URL, req.ReuseExisting = "https://example.com", true
+		assert.False(t, reusable(&req), name)
+	}
+}
+
+func TestURLService_ReuseExisting(t *testing.T) {
+	repo := &reuseRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	first, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://Example.com/docs/"})
+	require.NoError(t, err)
+	assert.False(t, first.Reused)
+	assert.Equal(t, canonicalHash("https://example.com/docs"), repo.links[0].CanonicalHash)
+
+	again, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/docs#intro", ReuseExisting: true})
+	require.NoError(t, err)
+	assert.True(t, again.Reused)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+	assert.Len(t, repo.links, 1)
+
+	fresh, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/docs"})
+	require.NoError(t, err)
+	assert.False(t, fresh.Reused, "without reuse_existing a new link is created")
+	assert.NotEqual(t, first.ShortCode, fresh.ShortCode)
+
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/alias", CustomAlias: "alias"})
+	require.NoError(t, err)
+	assert.Nil(t, repo.links[len(repo.links)-1].CanonicalHash, "custom aliases are never reused")
+
+	t.Run("batch", func(t *testing.T) {
+		results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+			{URL: "https://example.com/docs", ReuseExisting: true},
+			{URL: "https://example.com/new", ReuseExisting: true},
+		})
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+		assert.True(t, results[0].URL.Reused)
+		assert.False(t, results[1].URL.Reused)
+		assert.Equal(t, canonicalHash("https://example.com/new"), repo.links[len(repo.links)-1].CanonicalHash)
+	})
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// maxRedirectRules bounds the rules of one link; they are tried on every
+// visit.
+const maxRedirectRules = 20
+
+// Values accepted in RedirectRule.Browser and RedirectRule.OS.
+var (
+	ruleBrowsers = []string{"chrome", "edge", "firefox", "safari", "opera", "samsung", "bot", "other"}
+	ruleOSes     = []string{"ios", "android", "windows", "macos", "linux", "chromeos", "other"}
+)
+
+// countryCode matches an ISO 3166-1 alpha-2 code.
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// languageTag loosely matches a BCP 47 language tag such as "en" or "pt-BR".
+var languageTag = regexp.MustCompile(`^[a-z]{2,8}(-[a-z0-9]{1,8})*$`)
+
+// normalizeRules checks a link's redirect rules and case-folds their
+// conditions so they can be matched without folding on every visit.
+func normalizeRules(rules []model.RedirectRule) error {
+	if len(rules) > maxRedirectRules {
+		return fmt.Errorf("%w: at most %d rules", ErrInvalidRules, maxRedirectRules)
+	}
+	for i := range rules {
+		r := &rules[i]
+		if validateURL(r.URL) != nil {
+			return fmt.Errorf("%w: rule %d: invalid url", ErrInvalidRules, i)
+		}
+		if len(r.Browser) == 0 && len(r.OS) == 0 && len(r.Language) == 0 && len(r.Country) == 0 {
+			return fmt.Errorf("%w: rule %d has no conditions", ErrInvalidRules, i)
+		}
+		for j, v := range r.Browser {
+			if r.Browser[j] = strings.ToLower(v); !slices.Contains(ruleBrowsers, r.Browser[j]) {
+				return fmt.Errorf("%w: rule %d: unknown browser %q", ErrInvalidRules, i, v)
+			}
+		}
+		for j, v := range r.OS {
+			if r.OS[j] = strings.ToLower(v); !slices.Contains(ruleOSes, r.OS[j]) {
+				return fmt.Errorf("%w: rule %d: unknown os %q", ErrInvalidRules, i, v)
+			}
+		}
+		for j, v := range r.Language {
+			if r.Language[j] = strings.ToLower(v); !languageTag.MatchString(r.Language[j]) {
+				return fmt.Errorf("%w: rule %d: invalid language %q", ErrInvalidRules, i, v)
+			}
+		}
+		for j, v := range r.Country {
+			if r.Country[j] = strings.ToUpper(v); !countryCode.MatchString(r.Country[j]) {
+				return fmt.Errorf("%w: rule %d: invalid country %q", ErrInvalidRules, i, v)
+			}
+		}
+	}
+	return nil
+}
+
+// matchRule returns the first rule v satisfies, or nil.
+func matchRule(rules []model.RedirectRule, v model.Visitor) *model.RedirectRule {
+	for i := range rules {
+		r := &rules[i]
+		if (len(r.Browser) == 0 || slices.Contains(r.Browser, v.Browser)) &&
+			(len(r.OS) == 0 || slices.Contains(r.OS, v.OS)) &&
+			(len(r.Language) == 0 || slices.ContainsFunc(r.Language, func(tag string) bool {
+				return v.Language == tag || strings.HasPrefix(v.Language, tag+"-")
+			})) &&
+			(len(r.Country) == 0 || slices.Contains(r.Country, v.Country)) {
+			return r
+		}
+	}
+	return nil
+}
+
+// rulesVary lists the request headers rules select on, for the Vary header
+// of redirects that evaluated them. Country is not a header; see
+// rulesUseCountry.
+func rulesVary(rules []model.RedirectRule) string {
+	var ua, lang bool
+	for _, r := range rules {
+		ua = ua || len(r.Browser) > 0 || len(r.OS) > 0
+		lang = lang || len(r.Language) > 0
+	}
+	switch {
+	case ua && lang:
+		return "User-Agent, Accept-Language"
+	case ua:
+		return "User-Agent"
+	case lang:
+		return "Accept-Language"
+	}
+	return ""
+}
+
+// rulesUseCountry reports whether any rule selects on the visitor's
+// country. Caches cannot key on the client address, so such redirects must
+// not be stored.
+func rulesUseCountry(rules []model.RedirectRule) bool {
+	return slices.ContainsFunc(rules, func(r model.RedirectRule) bool { return len(r.Country) > 0 })
+}
+
+// visitorFrom classifies the visitor of req for rule matching.
+func visitorFrom(req *model.RedirectRequest) model.Visitor {
+	browser, os := parseUserAgent(req.UserAgent)
+	return model.Visitor{Browser: browser, OS: os, Language: preferredLanguage(req.AcceptLanguage), Country: req.Country}
+}
+
+// parseUserAgent reduces a User-Agent header to its browser family and OS.
+// Checks run from most to least specific: iOS UAs also claim Mac OS X,
+// Android ones Linux, and nearly every browser claims to be Safari.
+func parseUserAgent(ua string) (browser, os string) {
+	ua = strings.ToLower(ua)
+	has := func(subs ...string) bool {
+		return slices.ContainsFunc(subs, func(s string) bool { return strings.Contains(ua, s) })
+	}
+
+	switch {
+	case has("iphone", "ipad", "ipod"):
+		os = "ios"
+	case has("android"):
+		os = "android"
+	case has("cros"):
+		os = "chromeos"
+	case has("windows"):
+		os = "windows"
+	case has("macintosh", "mac os x"):
+		os = "macos"
+	case has("linux"):
+		os = "linux"
+	default:
+		os = "other"
+	}
+
+	switch {
+	case has("bot", "crawler", "spider", "slurp", "facebookexternalhit"):
+		browser = "bot"
+	case has("edg/", "edge/", "edga/", "edgios/"):
+		browser = "edge"
+	case has("opr/", "opera"):
+		browser = "opera"
+	case has("samsungbrowser"):
+		browser = "samsung"
+	case has("firefox", "fxios"):
+		browser = "firefox"
+	case has("chrome", "crios", "chromium"):
+		browser = "chrome"
+	case has("safari"):
+		browser = "safari"
+	default:
+		browser = "other"
+	}
+	return browser, os
+}
+
+// preferredLanguage returns the Accept-Language tag with the highest
+// quality, the first one listed on ties, lower-cased. The wildcard and
+// tags refused with q=0 are ignored.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua      string
+		browser string
+		os      string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", "safari", "ios"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/123.0.6312.52 Mobile/15E148 Safari/604.1", "chrome", "ios"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36", "chrome", "android"},
+		{"Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36", "samsung", "android"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.2420.65", "edge", "windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:124.0) Gecko/20100101 Firefox/124.0", "firefox", "macos"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 OPR/109.0.0.0", "opera", "linux"},
+		{"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36", "chrome", "chromeos"},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "bot", "other"},
+		{"curl/8.5.0", "other", "other"},
+		{"", "other", "other"},
+	}
+	for _, tt := range tests {
+		browser, os := parseUserAgent(tt.ua)
+		assert.Equal(t, tt.browser, browser, tt.ua)
+		assert.Equal(t, tt.os, os, tt.ua)
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "de-at", preferredLanguage("de-AT,de;q=0.9,en;q=0.5"))
+	assert.Equal(t, "en", preferredLanguage("fr;q=0.4, en;q=0.8"))
+	assert.Equal(t, "fr", preferredLanguage("fr, en"), "first listed wins ties")
+	assert.Equal(t, "en", preferredLanguage("*, de;q=0, en;q=0.1"))
+	assert.Equal(t, "", preferredLanguage(""))
+	assert.Equal(t, "", preferredLanguage("de;q=bogus"))
+}
+
+func TestNormalizeRules(t *testing.T) {
+	rules := []model.RedirectRule{{OS: []string{"iOS"}, Language: []string{"pt-BR"}, URL: "https://example.com/"}}
+	require.NoError(t, normalizeRules(rules))
+	assert.Equal(t, []string{"ios"}, rules[0].OS)
+	assert.Equal(t, []string{"pt-br"}, rules[0].Language)
+
+	for name, r := range map[string]model.RedirectRule{
+		"no conditions":    {URL: "https://example.com/"},
+		"invalid url":      {OS: []string{"ios"}, URL: "not a url"},
+		"unknown os":       {OS: []string{"symbian"}, URL: "https://example.com/"},
+		"unknown browser":  {Browser: []string{"netscape"}, URL: "https://example.com/"},
+		"invalid language": {Language: []string{"en_US"}, URL: "https://example.com/"},
+		"invalid country":  {Country: []string{"USA"}, URL: "https://example.com/"},
+	} {
+		assert.ErrorIs(t, normalizeRules([]model.RedirectRule{r}), ErrInvalidRules, name)
+	}
+	assert.ErrorIs(t, normalizeRules(make([]model.RedirectRule, maxRedirectRules+1)), ErrInvalidRules)
+}
+
+func TestURLService_RedirectRules(t *testing.T) {
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36"
+		desktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"
+	)
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{
+		URL:         "https://example.com/app",
+		CustomAlias: "app",
+		Rules: []model.RedirectRule{
+			{OS: []string{"iOS"}, URL: "https://apps.apple.com/app/id1"},
+			{OS: []string{"android"}, Language: []string{"de"}, URL: "https://play.google.com/store/apps/details?id=x&hl=de"},
+			{OS: []string{"android"}, URL: "https://play.google.com/store/apps/details?id=x"},
+			{Language: []string{"fr"}, URL: "https://example.com/fr/app"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, ua, lang, want string
+	}{
+		{"iOS", iPhone, "de-DE", "https://apps.apple.com/app/id1"},
+		{"German Android", android, "de-AT,en;q=0.5", "https://play.google.com/store/apps/details?id=x&hl=de"},
+		{"other Android", android, "en-GB,de;q=0.9", "https://play.google.com/store/apps/details?id=x"},
+		{"French desktop", desktop, "fr-CA", "https://example.com/fr/app"},
+		{"no match", desktop, "en-US", "https://example.com/app"},
+		{"no headers", "", "", "https://example.com/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "app", UserAgent: tt.ua, AcceptLanguage: tt.lang})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, target.URL)
+			assert.Equal(t, "User-Agent, Accept-Language", target.Vary)
+		})
+	}
+
+	t.Run("invalid rules rejected", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/",
+			Rules: []model.RedirectRule{{OS: []string{"beos"}, URL: "https://example.com/be"}}})
+		assert.ErrorIs(t, err, ErrInvalidRules)
+	})
+
+	t.Run("country rules", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{
+			URL:         "https://example.com/store",
+			CustomAlias: "store",
+			Rules: []model.RedirectRule{
+				{Country: []string{"gb", "IE"}, URL: "https://example.co.uk/store"},
+				{Country: []string{"DE"}, OS: []string{"ios"}, URL: "https://apps.apple.com/de/app/id1"},
+			},
+		})
+		require.NoError(t, err)
+
+		target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "store", Country: "GB"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.co.uk/store", target.URL)
+		assert.True(t, target.NoStore, "caches cannot key on the client's country")
+
+		target, err = s.Redirect(ctx, &model.RedirectRequest{Code: "store", Country: "DE", UserAgent: iPhone})
+		require.NoError(t, err)
+		assert.Equal(t, "https://apps.apple.com/de/app/id1", target.URL)
+
+		target, err = s.Redirect(ctx, &model.RedirectRequest{Code: "store", Country: "DE", UserAgent: desktop})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/store", target.URL)
+
+		target, err = s.Redirect(ctx, &model.RedirectRequest{Code: "store"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/store", target.URL, "unknown country matches no country rule")
+	})
+
+	t.Run("links without rules do not vary", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/plain", CustomAlias: "plain"})
+		require.NoError(t, err)
+		target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "plain", UserAgent: iPhone})
+		require.NoError(t, err)
+		assert.Empty(t, target.Vary)
+	})
+}
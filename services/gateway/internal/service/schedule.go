@@ -0,0 +1,15 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// validateSchedule checks that a link activates before it expires.
+// Activation times in the past are accepted and take effect immediately.
+func validateSchedule(notBefore, expiresAt *time.Time) error {
+	if notBefore != nil && expiresAt != nil && !notBefore.Before(*expiresAt) {
+		return fmt.Errorf("%w: not_before must be before the expiry", ErrInvalidExpiry)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// windowRepo refuses every update as the stored schedule check would.
+type windowRepo struct {
+	domainRepo
+}
+
+func (r *windowRepo) Update(context.Context, string, model.URLUpdate) (*model.URL, error) {
+	return nil, repository.ErrInvalidSchedule
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Now()
+	past, later, latest := now.Add(-time.Hour), now.Add(time.Hour), now.Add(2*time.Hour)
+
+	assert.NoError(t, validateSchedule(nil, nil))
+	assert.NoError(t, validateSchedule(&later, nil))
+	assert.NoError(t, validateSchedule(&past, &later), "past activation is immediate")
+	assert.NoError(t, validateSchedule(&later, &latest))
+	assert.ErrorIs(t, validateSchedule(&latest, &later), ErrInvalidExpiry)
+	assert.ErrorIs(t, validateSchedule(&later, &later), ErrInvalidExpiry)
+}
+
+func TestURLService_ScheduledLinks(t *testing.T) {
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+	launch := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{
+		URL:         "https://example.com/launch",
+		CustomAlias: "launch",
+		NotBefore:   &launch,
+		FallbackURL: "https://example.com/coming-soon",
+	})
+	require.NoError(t, err)
+
+	_, err = s.Redirect(ctx, &model.RedirectRequest{Code: "launch"})
+	require.ErrorIs(t, err, ErrURLNotActive)
+	var notActive *UnavailableError
+	require.True(t, errors.As(err, &notActive))
+	assert.Equal(t, launch, notActive.ActiveAt)
+	assert.Equal(t, "https://example.com/coming-soon", notActive.FallbackURL)
+
+	_, err = s.UnlockURL(ctx, &model.UnlockRequest{Code: "launch"})
+	assert.ErrorIs(t, err, ErrURLNotActive)
+
+	got, err := s.GetURL(ctx, "launch")
+	require.NoError(t, err, "owners can still read scheduled links")
+	assert.Equal(t, launch.Format(time.RFC3339), got.NotBefore)
+	assert.Equal(t, "https://example.com/coming-soon", got.FallbackURL)
+
+	launched := time.Now().Add(-time.Second)
+	repo.links[0].NotBefore = &launched
+	target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "launch"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/launch", target.URL)
+
+	t.Run("activation must precede expiry", func(t *testing.T) {
+		expires := launch.Add(-time.Minute)
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", NotBefore: &launch, ExpiresAt: &expires})
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+	})
+
+	t.Run("updates keep activation before the stored expiry", func(t *testing.T) {
+		s := NewURLService(repository.NewCachedURLRepository(&windowRepo{}, nil, 0, logger), logger, "http://short.example", 6, 3)
+		tooLate := time.Now().Add(time.Hour)
+		_, err := s.UpdateURL(ctx, "window", &model.UpdateURLRequest{NotBefore: &tooLate})
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+	})
+
+	t.Run("invalid fallback rejected", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", FallbackURL: "not a url"})
+		assert.ErrorIs(t, err, ErrInvalidURL)
+
+		results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{{URL: "https://example.com/", FallbackURL: "not a url"}})
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrInvalidURL)
+	})
+}
@@ -71,16 +71,20 @@ func HashURL(s string) uint64 {
 // detection and retry logic (checking the repository) should be
 // implemented externally or added here in the future.
 func (g *ShortCodeGenerator) Generate(longURL string) (string, error) {
+	return g.generate(longURL, g.codeLength)
+}
+
+func (g *ShortCodeGenerator) generate(longURL string, length int) (string, error) {
 	c, err := Canonicalize(longURL)
 	if err != nil {
 		return "", ErrInvalidURL
 	}
 	h := HashURL(c)
 	s := EncodeBase62(h)
-	if len(s) < g.codeLength {
+	if len(s) < length {
 		return "", ErrShortCodeGeneration
 	}
-	return s[:g.codeLength], nil
+	return s[:length], nil
 }
 
 // EncodeBase62 encodes a number to Base62 string
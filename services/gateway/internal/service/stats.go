@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+// Stats query bounds.
+const (
+	defaultStatsTop = 10
+	maxStatsTop     = 100
+	// maxStatsBuckets caps histogram size: a day of minutes, two months of
+	// hours, or about four years of days.
+	maxStatsBuckets = 1440
+)
+
+// statsBuckets maps each bucket name to its width and the range used when the
+// client gives no "from".
+var statsBuckets = map[string]struct{ width, defaultSpan time.Duration }{
+	model.StatsBucketMinute: {time.Minute, time.Hour},
+	model.StatsBucketHour:   {time.Hour, 24 * time.Hour},
+	model.StatsBucketDay:    {24 * time.Hour, 30 * 24 * time.Hour},
+}
+
+// GetStats returns click statistics for a short URL. Expired links still have
+// stats; only unknown codes yield ErrURLNotFound. With ownership enforcement
+// only the owner or an admin may read them.
+func (s *URLService) GetStats(ctx context.Context, code string, req *model.StatsRequest) (*model.ClickStats, error) {
+	if s.stats == nil {
+		return nil, ErrStatsUnavailable
+	}
+
+	filter, err := buildStatsFilter(code, req, time.Now())
+	if err != nil {
+		s.logger.WarnContext(ctx, "invalid stats query",
+			slog.String("code", code),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	url, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorize(ctx, url); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.stats.GetStats(ctx, filter)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to compute stats",
+			slog.String("code", code),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Copy before filling gaps: the repository may hand out a shared value
+	// (singleflight) that other callers are reading.
+	out := *stats
+	out.Series = fillSeries(stats.Series, filter)
+	return &out, nil
+}
+
+// buildStatsFilter validates req and aligns the range to whole buckets in UTC.
+// Alignment also makes repeated dashboard requests map to the same cache key.
+func buildStatsFilter(code string, req *model.StatsRequest, now time.Time) (model.StatsFilter, error) {
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = model.StatsBucketHour
+	}
+	spec, ok := statsBuckets[bucket]
+	if !ok {
+		return model.StatsFilter{}, fmt.Errorf("%w: bucket must be one of minute, hour, day", ErrInvalidStatsQuery)
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = now
+	}
+	// Round "to" up so the bucket containing it is included.
+	to = to.UTC()
+	if t := to.Truncate(spec.width); !t.Equal(to) {
+		to = t.Add(spec.width)
+	}
+
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-spec.defaultSpan)
+	}
+	from = from.UTC().Truncate(spec.width)
+
+	if !from.Before(to) {
+		return model.StatsFilter{}, fmt.Errorf("%w: from must be before to", ErrInvalidStatsQuery)
+	}
+	if n := int(to.Sub(from) / spec.width); n > maxStatsBuckets {
+		return model.StatsFilter{}, fmt.Errorf("%w: range spans %d %s buckets, maximum is %d", ErrInvalidStatsQuery, n, bucket, maxStatsBuckets)
+	}
+
+	top := req.Top
+	switch {
+	case top < 0:
+		return model.StatsFilter{}, fmt.Errorf("%w: top must not be negative", ErrInvalidStatsQuery)
+	case top == 0:
+		top = defaultStatsTop
+	case top > maxStatsTop:
+		top = maxStatsTop
+	}
+
+	return model.StatsFilter{
+		ShortCode: code,
+		From:      from,
+		To:        to,
+		Bucket:    bucket,
+		TopN:      top,
+	}, nil
+}
+
+// fillSeries returns one point per bucket in [From, To), with zero clicks for
+// buckets the repository did not return, so charts need no gap handling.
+func fillSeries(series []model.StatsBucket, filter model.StatsFilter) []model.StatsBucket {
+	width := statsBuckets[filter.Bucket].width
+	counts := make(map[int64]int64, len(series))
+	for _, b := range series {
+		counts[b.Start.Unix()] = b.Clicks
+	}
+
+	out := make([]model.StatsBucket, 0, int(filter.To.Sub(filter.From)/width))
+	for t := filter.From; t.Before(filter.To); t = t.Add(width) {
+		out = append(out, model.StatsBucket{Start: t, Clicks: counts[t.Unix()]})
+	}
+	return out
+}
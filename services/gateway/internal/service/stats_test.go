@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestBuildStatsFilter(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 37, 12, 0, time.UTC)
+
+	t.Run("defaults to the last day in hourly buckets", func(t *testing.T) {
+		f, err := buildStatsFilter("abc", &model.StatsRequest{}, now)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatsBucketHour, f.Bucket)
+		assert.Equal(t, time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC), f.To, "to rounds up to include the current bucket")
+		assert.Equal(t, time.Date(2026, 3, 9, 15, 0, 0, 0, time.UTC), f.From)
+		assert.Equal(t, defaultStatsTop, f.TopN)
+	})
+
+	t.Run("aligns explicit ranges to UTC day boundaries", func(t *testing.T) {
+		loc := time.FixedZone("UTC+8", 8*3600)
+		f, err := buildStatsFilter("abc", &model.StatsRequest{
+			Bucket: model.StatsBucketDay,
+			From:   time.Date(2026, 3, 1, 6, 0, 0, 0, loc),  // 2026-02-28T22:00Z
+			To:     time.Date(2026, 3, 3, 12, 0, 0, 0, loc), // 2026-03-03T04:00Z
+			Top:    500,
+		}, now)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), f.From)
+		assert.Equal(t, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), f.To)
+		assert.Equal(t, maxStatsTop, f.TopN)
+	})
+
+	t.Run("rejects invalid queries", func(t *testing.T) {
+		for name, req := range map[string]*model.StatsRequest{
+			"unknown bucket":  {Bucket: "week"},
+			"from after to":   {From: now, To: now.Add(-2 * time.Hour)},
+			"too many points": {Bucket: model.StatsBucketMinute, From: now.Add(-48 * time.Hour)},
+			"negative top":    {Top: -1},
+		} {
+			_, err := buildStatsFilter("abc", req, now)
+			assert.ErrorIs(t, err, ErrInvalidStatsQuery, name)
+		}
+	})
+}
+
+func TestFillSeries(t *testing.T) {
+	from := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	filter := model.StatsFilter{From: from, To: from.Add(4 * time.Hour), Bucket: model.StatsBucketHour}
+
+	series := fillSeries([]model.StatsBucket{
+		{Start: from.Add(time.Hour), Clicks: 3},
+		{Start: from.Add(3 * time.Hour), Clicks: 1},
+	}, filter)
+
+	require.Len(t, series, 4)
+	assert.Equal(t, []int64{0, 3, 0, 1}, []int64{series[0].Clicks, series[1].Clicks, series[2].Clicks, series[3].Clicks})
+	assert.Equal(t, from.Add(2*time.Hour), series[2].Start)
+}
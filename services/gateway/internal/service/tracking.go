@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// Bounds on a link's tracking parameters; they are added to every redirect.
+const (
+	maxTrackingExtra    = 20
+	maxTrackingKeyLen   = 64
+	maxTrackingValueLen = 512
+)
+
+// Query parameters set by the structured TrackingParams fields.
+const (
+	utmSource   = "utm_source"
+	utmMedium   = "utm_medium"
+	utmCampaign = "utm_campaign"
+)
+
+// validateTracking checks a link's tracking parameters. Extra may not set
+// the UTM tags that have their own fields, so each tag has one source.
+func validateTracking(t *model.TrackingParams) error {
+	if t == nil {
+		return nil
+	}
+	for key, v := range map[string]string{utmSource: t.Source, utmMedium: t.Medium, utmCampaign: t.Campaign} {
+		if len(v) > maxTrackingValueLen {
+			return fmt.Errorf("%w: %s exceeds %d bytes", ErrInvalidTracking, key, maxTrackingValueLen)
+		}
+	}
+	if len(t.Extra) > maxTrackingExtra {
+		return fmt.Errorf("%w: at most %d extra parameters", ErrInvalidTracking, maxTrackingExtra)
+	}
+	for key, v := range t.Extra {
+		switch {
+		case key == "" || len(key) > maxTrackingKeyLen:
+			return fmt.Errorf("%w: parameter names must be 1-%d bytes", ErrInvalidTracking, maxTrackingKeyLen)
+		case key == utmSource || key == utmMedium || key == utmCampaign:
+			return fmt.Errorf("%w: set %s with its own field, not in extra", ErrInvalidTracking, key)
+		case len(v) > maxTrackingValueLen:
+			return fmt.Errorf("%w: %s exceeds %d bytes", ErrInvalidTracking, key, maxTrackingValueLen)
+		}
+	}
+	return nil
+}
+
+// trackingValues returns the query parameters t adds.
+func trackingValues(t *model.TrackingParams) url.Values {
+	values := make(url.Values, len(t.Extra)+3)
+	for key, v := range t.Extra {
+		values.Set(key, v)
+	}
+	for key, v := range map[string]string{utmSource: t.Source, utmMedium: t.Medium, utmCampaign: t.Campaign} {
+		if v != "" {
+			values.Set(key, v)
+		}
+	}
+	return values
+}
+
+// applyTracking adds t's parameters to dest, replacing any of the same name
+// the destination already sets: the tags are where the link owner manages
+// the campaign. The destination's other parameters are kept byte for byte.
+// A destination that does not parse is returned unchanged.
+func applyTracking(dest string, t *model.TrackingParams) string {
+	if t.IsZero() {
+		return dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return dest
+	}
+
+	tags := trackingValues(t)
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && tags.Has(name) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	u.RawQuery = strings.Join(append(kept, tags.Encode()), "&")
+	return u.String()
+}
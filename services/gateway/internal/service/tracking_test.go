@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestApplyTracking(t *testing.T) {
+	spring := &model.TrackingParams{Source: "newsletter", Medium: "email", Campaign: "spring sale"}
+	tests := []struct {
+		name     string
+		dest     string
+		tracking *model.TrackingParams
+		want     string
+	}{
+		{"no tracking", "https://example.com/p?a=1", nil, "https://example.com/p?a=1"},
+		{"empty tracking", "https://example.com/p?a=1", &model.TrackingParams{}, "https://example.com/p?a=1"},
+		{"destination without query", "https://example.com/p", spring,
+			"https://example.com/p?utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter"},
+		{"appended after destination query", "https://example.com/p?q=a+b%2Fc", &model.TrackingParams{Source: "x"},
+			"https://example.com/p?q=a+b%2Fc&utm_source=x"},
+		{"tags replace destination values", "https://example.com/p?utm_source=old&a=1&utm_source=older", &model.TrackingParams{Source: "new"},
+			"https://example.com/p?a=1&utm_source=new"},
+		{"extra parameters encoded", "https://example.com/p", &model.TrackingParams{Extra: map[string]string{"ref": "a&b=c"}},
+			"https://example.com/p?ref=a%26b%3Dc"},
+		{"fragment preserved", "https://example.com/p#top", &model.TrackingParams{Medium: "social"},
+			"https://example.com/p?utm_medium=social#top"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, applyTracking(tt.dest, tt.tracking))
+		})
+	}
+}
+
+func TestValidateTracking(t *testing.T) {
+	require.NoError(t, validateTracking(nil))
+	require.NoError(t, validateTracking(&model.TrackingParams{Source: "a", Extra: map[string]string{"utm_content": "b"}}))
+
+	extra := map[string]string{}
+	for i := range maxTrackingExtra + 1 {
+		extra[strings.Repeat("k", i+1)] = "v"
+	}
+	for name, tp := range map[string]*model.TrackingParams{
+		"utm tag in extra": {Extra: map[string]string{"utm_source": "x"}},
+		"empty key":        {Extra: map[string]string{"": "x"}},
+		"long key":         {Extra: map[string]string{strings.Repeat("k", maxTrackingKeyLen+1): "x"}},
+		"long value":       {Campaign: strings.Repeat("v", maxTrackingValueLen+1)},
+		"too many extra":   {Extra: extra},
+	} {
+		assert.ErrorIs(t, validateTracking(tp), ErrInvalidTracking, name)
+	}
+}
+
+func TestURLService_RedirectTracking(t *testing.T) {
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	tracking := &model.TrackingParams{Source: "twitter", Medium: "social", Campaign: "launch"}
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{
+		URL:          "https://example.com/launch?lang=en",
+		CustomAlias:  "launch",
+		ForwardQuery: true,
+		Tracking:     tracking,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/launch?lang=en", repo.links[0].OriginalURL, "tags are stored apart")
+
+	target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "launch", Query: url.Values{"utm_source": {"spoofed"}, "x": {"1"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/launch?lang=en&utm_campaign=launch&utm_medium=social&utm_source=twitter&x=1", target.URL,
+		"visitors cannot override the tags")
+	assert.Equal(t, tracking, target.Tracking)
+
+	got, err := s.GetURL(ctx, "launch")
+	require.NoError(t, err)
+	assert.Equal(t, tracking, got.Tracking)
+
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/",
+		Tracking: &model.TrackingParams{Extra: map[string]string{"utm_campaign": "x"}}})
+	assert.ErrorIs(t, err, ErrInvalidTracking)
+}
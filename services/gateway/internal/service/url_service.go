@@ -2,23 +2,57 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
-	"strconv"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
 	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
 )
 
 var (
 	ErrInvalidURL          = errors.New("invalid URL format")
 	ErrURLNotFound         = errors.New("URL not found")
 	ErrURLExpired          = errors.New("URL has expired")
+	ErrURLNotActive        = errors.New("URL is not active yet")
 	ErrCodeExists          = errors.New("custom alias already exists")
 	ErrInvalidAlias        = errors.New("invalid custom alias format")
 	ErrShortCodeGeneration = errors.New("failed to generate short URL")
+	ErrInvalidListQuery    = errors.New("invalid list query")
+	ErrInvalidUpdate       = errors.New("invalid URL update")
+	ErrInvalidBatch        = errors.New("invalid batch request")
+	ErrInvalidExpiry       = errors.New("invalid expiry")
+	ErrInvalidStatsQuery   = errors.New("invalid stats query")
+	ErrStatsUnavailable    = errors.New("click statistics are not available")
+	ErrUnauthorized        = errors.New("authentication required")
+	ErrForbidden           = errors.New("not allowed to manage this URL")
+	ErrQuotaExceeded       = errors.New("workspace link quota exceeded")
+	ErrInvalidDomain       = errors.New("invalid domain")
+	ErrDomainNotFound      = errors.New("domain not found")
+	ErrDomainExists        = errors.New("domain already registered")
+	ErrDomainInUse         = errors.New("domain still has links")
+	ErrInvalidRedirect     = errors.New("invalid redirect type")
+	ErrInvalidPassword     = errors.New("invalid link password")
+	ErrPasswordRequired    = errors.New("link is password protected")
+	ErrWrongPassword       = errors.New("incorrect link password")
+	ErrInvalidClickLimit   = errors.New("invalid click limit")
+	ErrClickLimitReached   = errors.New("URL has reached its click limit")
+	ErrInvalidRules        = errors.New("invalid redirect rules")
+	ErrInvalidVariants     = errors.New("invalid link variants")
+	ErrInvalidTracking     = errors.New("invalid tracking parameters")
+)
+
+// Page size bounds for ListURLs.
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
 )
 
 // URLService handles business logic for URL operations
@@ -28,14 +62,88 @@ type URLService struct {
 	baseURL          string
 	shortCodeLen     int
 	shortCodeRetries int
+	maxBatchSize     int
+	aliasPolicy      *AliasPolicy
+	defaultExpiry    time.Duration
+	maxExpiry        time.Duration
+	stats            repository.StatsRepositoryInterface
+	enforceOwnership bool
+	domains          DomainLookup
+	defaultRedirect  int
+	unlocks          *UnlockSigner
+	codes            CodeStrategy
+	length           *codeLength
+	codeLengths      CodeLengthStore
+	hashCodes        bool // codes are hashed from the URL, see recordCodeAttempt
+
+	// workspacePolicies caches compiled per-workspace alias policies
+	// (uuid.UUID -> workspacePolicy).
+	workspacePolicies sync.Map
+}
+
+// URLServiceOptions holds optional configuration.
+type URLServiceOptions struct {
+	MaxBatchSize int          // maximum items per CreateShortURLBatch call (0 = defaultMaxBatchSize)
+	AliasPolicy  *AliasPolicy // custom alias rules (nil = DefaultAliasPolicy)
+
+	// DefaultExpiry is applied when a create request sets no expiry (0 = never expires).
+	DefaultExpiry time.Duration
+	// MaxExpiry caps how far in the future any link may expire (0 = unlimited).
+	// With a cap set, links can no longer be permanent: requests without an
+	// expiry fall back to MaxExpiry when DefaultExpiry is unset.
+	MaxExpiry time.Duration
+	// Stats serves GetStats (nil = GetStats returns ErrStatsUnavailable).
+	Stats repository.StatsRepositoryInterface
+	// EnforceOwnership restricts GetURL, ListURLs, UpdateURL, DeleteURL and
+	// GetStats to the auth.Identity that owns the link, or one with admin
+	// scope.
+	EnforceOwnership bool
+	// Domains validates the domain of create requests (nil = only links on
+	// the request's own domain can be created).
+	Domains DomainLookup
+	// DefaultRedirectStatus is the status of links that set no redirect_type
+	// (0 = 301). Values other than 301, 302, 307 and 308 are ignored.
+	DefaultRedirectStatus int
+	// Unlocks signs the tokens that let visitors past a link's password
+	// prompt (nil = random key, tokens valid for an hour).
+	Unlocks *UnlockSigner
+	// Codes generates the short codes of links without a custom alias
+	// (nil = hash of the URL, ShortCodeGenerator).
+	Codes CodeStrategy
+	// MaxShortCodeLen is the length generated codes may grow to when more
+	// than CollisionThreshold (0 = 10%) of the inserts in a window of
+	// CollisionWindow (0 = 1000) attempts collide. Values up to the
+	// configured length keep it fixed; the longest possible is 10.
+	MaxShortCodeLen    int
+	CollisionThreshold float64
+	CollisionWindow    int
+	// CodeLengths keeps the promoted length across restarts (nil = every
+	// start begins at the configured length).
+	CodeLengths CodeLengthStore
+}
+
+// BatchItemResult is the outcome of one CreateShortURLBatch item:
+// exactly one of URL or Err is set.
+type BatchItemResult struct {
+	URL *model.CreateURLResponse
+	Err error
 }
 
+// defaultMaxBatchSize keeps a batch INSERT well under Postgres' 65535
+// bind-parameter limit (18 parameters per row).
+const defaultMaxBatchSize = 1000
+
 // URLServiceInterface defines the contract for URL shortening operations
 type URLServiceInterface interface {
 	CreateShortURL(ctx context.Context, req *model.CreateURLRequest) (*model.CreateURLResponse, error)
+	CreateShortURLBatch(ctx context.Context, reqs []model.CreateURLRequest) ([]BatchItemResult, error)
 	GetURL(ctx context.Context, code string) (*model.URLResponse, error)
 	DeleteURL(ctx context.Context, code string) error
-	Redirect(ctx context.Context, code string) (string, error)
+	UpdateURL(ctx context.Context, code string, req *model.UpdateURLRequest) (*model.URLResponse, error)
+	Redirect(ctx context.Context, req *model.RedirectRequest) (*model.RedirectTarget, error)
+	UnlockURL(ctx context.Context, req *model.UnlockRequest) (*model.UnlockGrant, error)
+	ListURLs(ctx context.Context, req *model.ListURLsRequest) (*model.ListURLsResponse, error)
+	GetStats(ctx context.Context, code string, req *model.StatsRequest) (*model.ClickStats, error)
 }
 
 // NewURLService creates a new URL service
@@ -44,44 +152,208 @@ func NewURLService(repo *repository.CachedURLRepository,
 	baseURL string,
 	shortCodeLen int,
 	shortCodeRetries int,
+	opts ...URLServiceOptions,
 ) *URLService {
-	return &URLService{
+	s := &URLService{
 		repo:             repo,
 		logger:           logger,
 		baseURL:          baseURL,
 		shortCodeLen:     shortCodeLen,
 		shortCodeRetries: shortCodeRetries,
+		maxBatchSize:     defaultMaxBatchSize,
+		aliasPolicy:      DefaultAliasPolicy(),
+		defaultRedirect:  defaultRedirectStatus,
+	}
+	if len(opts) > 0 {
+		if opts[0].MaxBatchSize > 0 {
+			s.maxBatchSize = opts[0].MaxBatchSize
+		}
+		if opts[0].AliasPolicy != nil {
+			s.aliasPolicy = opts[0].AliasPolicy
+		}
+		s.stats = opts[0].Stats
+		s.enforceOwnership = opts[0].EnforceOwnership
+		s.domains = opts[0].Domains
+		s.unlocks = opts[0].Unlocks
+		s.codes = opts[0].Codes
+		s.length = newCodeLength(s.shortCodeLen, opts[0].MaxShortCodeLen, opts[0].CollisionThreshold, opts[0].CollisionWindow)
+		s.codeLengths = opts[0].CodeLengths
+		if ValidRedirectStatus(opts[0].DefaultRedirectStatus) {
+			s.defaultRedirect = opts[0].DefaultRedirectStatus
+		}
+		s.defaultExpiry = max(opts[0].DefaultExpiry, 0)
+		s.maxExpiry = max(opts[0].MaxExpiry, 0)
+		if s.maxExpiry > 0 && s.defaultExpiry > s.maxExpiry {
+			s.defaultExpiry = s.maxExpiry
+		}
+	}
+	if s.unlocks == nil {
+		s.unlocks = NewUnlockSigner(nil, 0)
 	}
+	if s.codes == nil {
+		s.codes = NewShortCodeGenerator(s.shortCodeLen, s.shortCodeRetries, s.repo)
+	}
+	_, s.hashCodes = s.codes.(*ShortCodeGenerator)
+	if s.length == nil {
+		s.length = newCodeLength(s.shortCodeLen, 0, 0, 0)
+	}
+	if s.codeLengths != nil {
+		s.loadCodeLength()
+	}
+	return s
 }
 
-// CreateShortURL creates a new shortened URL
+// loadCodeLength starts from the length stored by earlier promotions. A
+// store that cannot be read is logged and the configured length is used.
+func (s *URLService) loadCodeLength() {
+	ctx, cancel := context.WithTimeout(context.Background(), codeLengthLoadTimeout)
+	defer cancel()
+	n, err := s.codeLengths.CodeLength(ctx)
+	if err != nil {
+		s.logger.Error("failed to load stored short code length, using configured length",
+			slog.String("error", err.Error()))
+		return
+	}
+	s.length.raise(n)
+}
+
+// CreateShortURL creates a new shortened URL in the workspace of ctx.
+// Workspaces with a link quota reject it with ErrQuotaExceeded once full.
+// The link is served on req.Domain, which must be registered to the
+// workspace, or else on the domain the request arrived on. With
+// req.ReuseExisting the caller's existing link to the same canonical URL is
+// returned instead, marked Reused, if there is one.
 func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLRequest) (*model.CreateURLResponse, error) {
 	// Log incoming request
 	s.logger.InfoContext(ctx, "creating short URL",
 		slog.String("url", req.URL),
 		slog.String("custom_alias", req.CustomAlias),
-		slog.Int("expires_in_days", req.ExpiresIn))
+		slog.Int("expires_in_days", req.ExpiresIn),
+		slog.String("expires_after", req.ExpiresAfter),
+		slog.String("domain", req.Domain))
 
 	var shortCode string
 	var err error
 
-	var expiresAt *time.Time
-	if req.ExpiresIn > 0 {
-		t := time.Now().AddDate(0, 0, req.ExpiresIn)
-		expiresAt = &t
+	if err := validateRedirectType(req.RedirectType); err != nil {
+		s.logger.WarnContext(ctx, "invalid redirect type requested",
+			slog.Int("redirect_type", req.RedirectType))
+		return nil, err
+	}
+
+	if req.MaxClicks < 0 {
+		s.logger.WarnContext(ctx, "invalid click limit requested",
+			slog.Int64("max_clicks", req.MaxClicks))
+		return nil, fmt.Errorf("%w: max_clicks must not be negative", ErrInvalidClickLimit)
+	}
+
+	var passwordHash string
+	if req.Password != "" {
+		if passwordHash, err = hashPassword(req.Password); err != nil {
+			s.logger.WarnContext(ctx, "link password rejected",
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	expiresAt, err := s.resolveExpiry(req)
+	if err == nil {
+		err = validateSchedule(req.NotBefore, expiresAt)
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "invalid expiry requested",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	if err := validateFallbackURL(req.FallbackURL); err != nil {
+		s.logger.WarnContext(ctx, "invalid fallback URL",
+			slog.String("fallback_url", req.FallbackURL))
+		return nil, err
+	}
+	if err := normalizeRules(req.Rules); err != nil {
+		s.logger.WarnContext(ctx, "invalid redirect rules",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	if err := validateVariants(req.Variants); err != nil {
+		s.logger.WarnContext(ctx, "invalid link variants",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	if err := validateTracking(req.Tracking); err != nil {
+		s.logger.WarnContext(ctx, "invalid tracking parameters",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	domain, err := s.domainFor(ctx, req.Domain)
+	if err != nil {
+		s.logger.WarnContext(ctx, "domain rejected",
+			slog.String("domain", req.Domain),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if reusable(req) {
+		existing, err := s.findReusable(ctx, req.URL, domain)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to look up reusable link",
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+		if existing != nil {
+			s.logger.InfoContext(ctx, "reusing existing short URL",
+				slog.String("short_code", existing.ShortCode),
+				slog.String("url", req.URL))
+			resp := s.toCreateURLResponse(ctx, existing.ShortCode, existing.Domain, existing.ExpiresAt)
+			resp.Reused = true
+			return resp, nil
+		}
+	}
+
+	remaining, err := s.remainingQuota(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check workspace quota",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	if remaining == 0 {
+		s.logger.WarnContext(ctx, "workspace link quota exceeded",
+			slog.String("workspace", tenant.WorkspaceID(ctx).String()))
+		return nil, ErrQuotaExceeded
 	}
 
 	if req.CustomAlias != "" {
 		s.logger.InfoContext(ctx, "using custom alias",
 			slog.String("alias", req.CustomAlias))
 
+		if err := s.aliasPolicyFor(ctx).Validate(req.CustomAlias); err != nil {
+			s.logger.WarnContext(ctx, "custom alias rejected",
+				slog.String("alias", req.CustomAlias),
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+
 		url := &model.URL{
-			ID:          uuid.New(),
-			ShortCode:   req.CustomAlias,
-			OriginalURL: req.URL,
-			CreatedAt:   time.Now(),
-			ExpiresAt:   expiresAt,
-			ClickCount:  0,
+			ID:             uuid.New(),
+			ShortCode:      req.CustomAlias,
+			OriginalURL:    req.URL,
+			CreatedAt:      time.Now(),
+			ExpiresAt:      expiresAt,
+			ClickCount:     0,
+			Owner:          ownerFromContext(ctx),
+			WorkspaceID:    tenant.WorkspaceID(ctx),
+			Domain:         domain,
+			RedirectType:   req.RedirectType,
+			ForwardQuery:   req.ForwardQuery,
+			PasswordHash:   passwordHash,
+			MaxClicks:      req.MaxClicks,
+			NotBefore:      req.NotBefore,
+			FallbackURL:    req.FallbackURL,
+			Rules:          req.Rules,
+			Variants:       req.Variants,
+			StickyVariants: req.StickyVariants && len(req.Variants) > 0,
+			Tracking:       req.Tracking,
 		}
 		if err := s.repo.Create(ctx, url); err != nil {
 			if errors.Is(err, repository.ErrCodeConflict) {
@@ -89,6 +361,9 @@ func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLReq
 					slog.String("alias", req.CustomAlias))
 				return nil, ErrCodeExists
 			}
+			if errors.Is(err, repository.ErrQuotaExceeded) {
+				return nil, ErrQuotaExceeded
+			}
 			s.logger.ErrorContext(ctx, "failed to create URL with custom alias",
 				slog.String("error", err.Error()),
 				slog.String("alias", req.CustomAlias))
@@ -99,10 +374,9 @@ func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLReq
 		s.logger.InfoContext(ctx, "generating short code",
 			slog.Int("max_retries", s.shortCodeRetries))
 
-		g := NewShortCodeGenerator(s.shortCodeLen, s.shortCodeRetries, s.repo)
 		created := false
 		for attemp := 0; attemp < s.shortCodeRetries; attemp++ {
-			candidate, genErr := g.Generate(req.URL + strconv.Itoa(attemp))
+			candidate, genErr := s.codes.Next(ctx, req.URL, s.length.get(), attemp)
 			if genErr != nil {
 				s.logger.ErrorContext(ctx, "short code generation failed",
 					slog.String("error", genErr.Error()),
@@ -111,14 +385,32 @@ func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLReq
 			}
 
 			url := &model.URL{
-				ID:          uuid.New(),
-				ShortCode:   candidate,
-				OriginalURL: req.URL,
-				CreatedAt:   time.Now(),
-				ExpiresAt:   expiresAt,
-				ClickCount:  0,
+				ID:             uuid.New(),
+				ShortCode:      candidate,
+				OriginalURL:    req.URL,
+				CreatedAt:      time.Now(),
+				ExpiresAt:      expiresAt,
+				ClickCount:     0,
+				Owner:          ownerFromContext(ctx),
+				WorkspaceID:    tenant.WorkspaceID(ctx),
+				Domain:         domain,
+				RedirectType:   req.RedirectType,
+				ForwardQuery:   req.ForwardQuery,
+				PasswordHash:   passwordHash,
+				MaxClicks:      req.MaxClicks,
+				NotBefore:      req.NotBefore,
+				FallbackURL:    req.FallbackURL,
+				Rules:          req.Rules,
+				Variants:       req.Variants,
+				StickyVariants: req.StickyVariants && len(req.Variants) > 0,
+				Tracking:       req.Tracking,
+				CanonicalHash:  canonicalHash(req.URL),
+			}
+			err = s.repo.Create(ctx, url)
+			if err == nil || errors.Is(err, repository.ErrCodeConflict) {
+				s.recordCodeAttempt(ctx, url, err != nil)
 			}
-			if err = s.repo.Create(ctx, url); err != nil {
+			if err != nil {
 				if errors.Is(err, repository.ErrCodeConflict) {
 					s.logger.WarnContext(ctx, "short code collision detected, retrying",
 						slog.String("code", candidate),
@@ -126,6 +418,9 @@ func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLReq
 						slog.Int("max_retries", s.shortCodeRetries))
 					continue
 				}
+				if errors.Is(err, repository.ErrQuotaExceeded) {
+					return nil, ErrQuotaExceeded
+				}
 				s.logger.ErrorContext(ctx, "failed to create URL",
 					slog.String("error", err.Error()),
 					slog.String("code", candidate),
@@ -143,22 +438,240 @@ func (s *URLService) CreateShortURL(ctx context.Context, req *model.CreateURLReq
 		}
 	}
 
-	// Format expiry for response
-	var expiresAtStr string
-	if expiresAt != nil {
-		expiresAtStr = expiresAt.Format(time.RFC3339)
-	}
-
 	// Log success
 	s.logger.InfoContext(ctx, "short URL created",
 		slog.String("short_code", shortCode),
 		slog.String("url", req.URL))
 
-	return &model.CreateURLResponse{
-		ShortCode: shortCode,
-		ShortURL:  s.baseURL + "/" + shortCode,
-		ExpiresAt: expiresAtStr,
-	}, nil
+	return s.toCreateURLResponse(ctx, shortCode, domain, expiresAt), nil
+}
+
+// CreateShortURLBatch creates many short URLs at once and reports a result per item.
+// Invalid items never fail the whole batch: each one carries its own error.
+//
+// Each round inserts every pending item in one statement. Items whose
+// generated code collided are re-generated with the next attempt suffix and
+// retried in the following round, up to shortCodeRetries rounds; custom
+// aliases that collide fail immediately with ErrCodeExists. In a workspace
+// with a link quota, valid items beyond the remaining quota fail with
+// ErrQuotaExceeded. Items with ReuseExisting are looked up one by one, as
+// in CreateShortURL, and do not count against the quota when reused.
+func (s *URLService) CreateShortURLBatch(ctx context.Context, reqs []model.CreateURLRequest) ([]BatchItemResult, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: no items", ErrInvalidBatch)
+	}
+	if len(reqs) > s.maxBatchSize {
+		return nil, fmt.Errorf("%w: %d items exceeds maximum of %d", ErrInvalidBatch, len(reqs), s.maxBatchSize)
+	}
+
+	s.logger.InfoContext(ctx, "creating short URL batch",
+		slog.Int("items", len(reqs)))
+
+	remaining, err := s.remainingQuota(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check workspace quota",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	errs := make([]error, len(reqs))
+	urls := make([]*model.URL, len(reqs))
+	reused := make([]*model.URL, len(reqs))
+	owner := ownerFromContext(ctx)
+	workspaceID := tenant.WorkspaceID(ctx)
+	aliasPolicy := s.aliasPolicyFor(ctx)
+	domains := make(map[string]domainResult) // requested domain -> resolution, looked up once per batch
+	passwords := make(map[string]string)     // password -> bcrypt hash, hashed once per batch
+	var pending []int
+
+	for i := range reqs {
+		req := &reqs[i]
+		if err := validateURL(req.URL); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := validateRedirectType(req.RedirectType); err != nil {
+			errs[i] = err
+			continue
+		}
+		expiresAt, err := s.resolveExpiry(req)
+		if err == nil {
+			err = validateSchedule(req.NotBefore, expiresAt)
+		}
+		if err == nil {
+			err = validateFallbackURL(req.FallbackURL)
+		}
+		if err == nil {
+			err = normalizeRules(req.Rules)
+		}
+		if err == nil {
+			err = validateVariants(req.Variants)
+		}
+		if err == nil {
+			err = validateTracking(req.Tracking)
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		d, ok := domains[req.Domain]
+		if !ok {
+			d.domain, d.err = s.domainFor(ctx, req.Domain)
+			domains[req.Domain] = d
+		}
+		if d.err != nil {
+			errs[i] = d.err
+			continue
+		}
+		if reusable(req) {
+			existing, err := s.findReusable(ctx, req.URL, d.domain)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			if existing != nil {
+				reused[i] = existing
+				continue
+			}
+		}
+		if req.MaxClicks < 0 {
+			errs[i] = fmt.Errorf("%w: max_clicks must not be negative", ErrInvalidClickLimit)
+			continue
+		}
+		passwordHash, ok := passwords[req.Password]
+		if !ok && req.Password != "" {
+			if passwordHash, err = hashPassword(req.Password); err != nil {
+				errs[i] = err
+				continue
+			}
+			passwords[req.Password] = passwordHash
+		}
+		code := req.CustomAlias
+		var hash []byte
+		if code != "" {
+			if err := aliasPolicy.Validate(code); err != nil {
+				errs[i] = err
+				continue
+			}
+		} else {
+			var err error
+			if code, err = s.codes.Next(ctx, req.URL, s.length.get(), 0); err != nil {
+				errs[i] = err
+				continue
+			}
+			hash = canonicalHash(req.URL)
+		}
+		urls[i] = &model.URL{
+			ID:             uuid.New(),
+			ShortCode:      code,
+			OriginalURL:    req.URL,
+			CreatedAt:      time.Now(),
+			ExpiresAt:      expiresAt,
+			Owner:          owner,
+			WorkspaceID:    workspaceID,
+			Domain:         d.domain,
+			RedirectType:   req.RedirectType,
+			ForwardQuery:   req.ForwardQuery,
+			PasswordHash:   passwordHash,
+			MaxClicks:      req.MaxClicks,
+			NotBefore:      req.NotBefore,
+			FallbackURL:    req.FallbackURL,
+			Rules:          req.Rules,
+			Variants:       req.Variants,
+			StickyVariants: req.StickyVariants && len(req.Variants) > 0,
+			Tracking:       req.Tracking,
+			CanonicalHash:  hash,
+		}
+		pending = append(pending, i)
+	}
+
+	if remaining >= 0 && int64(len(pending)) > remaining {
+		for _, i := range pending[remaining:] {
+			errs[i] = ErrQuotaExceeded
+		}
+		pending = pending[:remaining]
+	}
+
+	for attempt := 0; attempt < s.shortCodeRetries && len(pending) > 0; attempt++ {
+		batch := make([]*model.URL, len(pending))
+		for j, i := range pending {
+			batch[j] = urls[i]
+		}
+
+		results, err := s.repo.CreateBatch(ctx, batch)
+		if errors.Is(err, repository.ErrQuotaExceeded) {
+			err = ErrQuotaExceeded
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "batch insert failed",
+				slog.String("error", err.Error()),
+				slog.Int("items", len(pending)),
+				slog.Int("attempt", attempt+1))
+			for _, i := range pending {
+				errs[i] = err
+			}
+			pending = nil
+			break
+		}
+
+		var retry []int
+		for j, i := range pending {
+			switch {
+			case results[j] == nil:
+				if reqs[i].CustomAlias == "" {
+					s.recordCodeAttempt(ctx, urls[i], false)
+				}
+			case !errors.Is(results[j], repository.ErrCodeConflict):
+				errs[i] = results[j]
+			case reqs[i].CustomAlias != "":
+				errs[i] = ErrCodeExists
+			default:
+				s.recordCodeAttempt(ctx, urls[i], true)
+				candidate, genErr := s.codes.Next(ctx, reqs[i].URL, s.length.get(), attempt+1)
+				if genErr != nil {
+					errs[i] = genErr
+					continue
+				}
+				urls[i].ID = uuid.New()
+				urls[i].ShortCode = candidate
+				retry = append(retry, i)
+			}
+		}
+		if len(retry) > 0 {
+			s.logger.WarnContext(ctx, "short code collisions in batch, retrying",
+				slog.Int("collisions", len(retry)),
+				slog.Int("attempt", attempt+1),
+				slog.Int("max_retries", s.shortCodeRetries))
+		}
+		pending = retry
+	}
+	for _, i := range pending {
+		errs[i] = ErrShortCodeGeneration
+	}
+
+	results := make([]BatchItemResult, len(reqs))
+	created, reusedCount := 0, 0
+	for i := range reqs {
+		if errs[i] != nil {
+			results[i].Err = errs[i]
+			continue
+		}
+		if u := reused[i]; u != nil {
+			results[i].URL = s.toCreateURLResponse(ctx, u.ShortCode, u.Domain, u.ExpiresAt)
+			results[i].URL.Reused = true
+			reusedCount++
+			continue
+		}
+		results[i].URL = s.toCreateURLResponse(ctx, urls[i].ShortCode, urls[i].Domain, urls[i].ExpiresAt)
+		created++
+	}
+
+	s.logger.InfoContext(ctx, "short URL batch created",
+		slog.Int("created", created),
+		slog.Int("reused", reusedCount),
+		slog.Int("failed", len(reqs)-created-reusedCount))
+
+	return results, nil
 }
 
 // GetURL retrieves URL metadata by short code
@@ -166,54 +679,161 @@ func (s *URLService) GetURL(ctx context.Context, code string) (*model.URLRespons
 	s.logger.DebugContext(ctx, "fetching URL metadata",
 		slog.String("code", code))
 
-	url, err := s.getAndValidateURL(ctx, code)
+	url, err := s.getAndValidateURL(ctx, code, false)
 	if err != nil {
 		s.logger.WarnContext(ctx, "URL not found or invalid",
 			slog.String("code", code),
 			slog.String("error", err.Error()))
 		return nil, err
 	}
+	if err := s.authorize(ctx, url); err != nil {
+		return nil, err
+	}
 
-	var expiresAtStr string
-	if url.ExpiresAt != nil {
-		expiresAtStr = url.ExpiresAt.Format(time.RFC3339)
+	// The cached URL entry may be up to the cache TTL old; refresh the
+	// counter from its short-lived cache so reported clicks stay current.
+	count, err := s.repo.GetClickCount(ctx, code)
+	switch {
+	case err == nil:
+		url.ClickCount = count
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, ErrURLNotFound
+	default:
+		s.logger.WarnContext(ctx, "failed to refresh click count, serving cached value",
+			slog.String("code", code),
+			slog.String("error", err.Error()))
+	}
+
+	if url.MaxClicks > 0 {
+		uses, err := s.repo.GetUses(ctx, code)
+		switch {
+		case err == nil:
+			url.Uses = uses
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrURLNotFound
+		default:
+			s.logger.WarnContext(ctx, "failed to refresh link uses, serving cached value",
+				slog.String("code", code),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	resp := s.toURLResponse(ctx, url)
+	return &resp, nil
+}
+
+// ListURLs returns one page of URLs matching the request filters, limited
+// to the caller's own links when ownership is enforced.
+// Pages are cursor-based: pass the returned NextCursor to fetch the next page.
+func (s *URLService) ListURLs(ctx context.Context, req *model.ListURLsRequest) (*model.ListURLsResponse, error) {
+	filter, err := buildURLFilter(req)
+	if err != nil {
+		s.logger.WarnContext(ctx, "invalid list query",
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	if filter.Owner, err = s.listOwner(ctx); err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	urls, err := s.repo.List(ctx, filter)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list URLs",
+			slog.String("error", err.Error()))
+		return nil, err
 	}
 
-	return &model.URLResponse{
-		ShortCode:   url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		ShortURL:    s.baseURL + "/" + url.ShortCode,
-		CreatedAt:   url.CreatedAt.Format(time.RFC3339),
-		ExpiresAt:   expiresAtStr,
-		ClickCount:  url.ClickCount,
-	}, nil
+	resp := &model.ListURLsResponse{URLs: make([]model.URLResponse, 0, min(len(urls), limit))}
+	for i, url := range urls {
+		if i == limit {
+			last := urls[limit-1]
+			resp.NextCursor = encodeCursor(model.URLCursor{
+				CreatedAt:  last.CreatedAt,
+				ClickCount: last.ClickCount,
+				ID:         last.ID,
+			})
+			break
+		}
+		resp.URLs = append(resp.URLs, s.toURLResponse(ctx, url))
+	}
+	return resp, nil
 }
 
-// Redirect retrieves the original URL for redirection
-func (s *URLService) Redirect(ctx context.Context, code string) (string, error) {
+// Redirect resolves a visit to a short link into the destination and status
+// to redirect with. A link created on a branded domain is only found on that
+// domain, so the request resolves the (host, code) pair rather than the code
+// alone. The first of the link's rules that matches the visitor's
+// User-Agent, Accept-Language and country overrides the destination. Links with
+// forward_query set pass req.Query on to the destination.
+// Password-protected links return ErrPasswordRequired unless req.Unlock holds
+// a valid token from UnlockURL. Each redirect of a click-limited link uses
+// up one of its max_clicks; once none remain ErrClickLimitReached is
+// returned. Visits stopped at the password prompt use nothing. Expired,
+// exhausted and not yet active links return an *UnavailableError carrying
+// the link's or workspace's fallback URL.
+func (s *URLService) Redirect(ctx context.Context, req *model.RedirectRequest) (*model.RedirectTarget, error) {
 	s.logger.InfoContext(ctx, "redirecting",
-		slog.String("code", code))
+		slog.String("code", req.Code))
 
-	url, err := s.getAndValidateURL(ctx, code)
+	url, err := s.getAndValidateURL(ctx, req.Code, true)
 	if err != nil {
 		s.logger.WarnContext(ctx, "redirect failed, URL not found or invalid",
-			slog.String("code", code),
+			slog.String("code", req.Code),
 			slog.String("error", err.Error()))
-		return "", err
+		return nil, err
+	}
+
+	if url.PasswordHash != "" && !s.unlocks.Verify(url, req.Unlock, time.Now()) {
+		s.logger.InfoContext(ctx, "redirect needs password",
+			slog.String("code", req.Code))
+		return nil, ErrPasswordRequired
 	}
 
+	if url.MaxClicks > 0 {
+		uses, err := s.repo.ConsumeUse(ctx, req.Code)
+		if err != nil {
+			if errors.Is(err, repository.ErrUsesExhausted) {
+				s.logger.InfoContext(ctx, "redirect refused, click limit reached",
+					slog.String("code", req.Code),
+					slog.Int64("max_clicks", url.MaxClicks))
+				return nil, unavailable(ctx, url, ErrClickLimitReached)
+			}
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrURLNotFound
+			}
+			s.logger.ErrorContext(ctx, "failed to consume link use",
+				slog.String("code", req.Code),
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+		s.logger.DebugContext(ctx, "link use consumed",
+			slog.String("code", req.Code),
+			slog.Int64("uses", uses),
+			slog.Int64("max_clicks", url.MaxClicks))
+	}
+
+	target := s.redirectTarget(url, req)
 	s.logger.InfoContext(ctx, "redirect successful",
-		slog.String("code", code),
-		slog.String("target_url", url.OriginalURL))
+		slog.String("code", req.Code),
+		slog.String("target_url", target.URL),
+		slog.Int("status", target.Status))
 
-	return url.OriginalURL, nil
+	return target, nil
 }
 
-// DeleteURL removes a shortened URL
+// DeleteURL removes a shortened URL.
+// With ownership enforcement only the owner or an admin may delete it.
 func (s *URLService) DeleteURL(ctx context.Context, code string) error {
 	s.logger.InfoContext(ctx, "deleting URL",
 		slog.String("code", code))
 
+	if err := s.authorizeCode(ctx, code); err != nil {
+		return err
+	}
+
 	if err := s.repo.Delete(ctx, code); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			s.logger.WarnContext(ctx, "URL not found for deletion",
@@ -232,13 +852,351 @@ func (s *URLService) DeleteURL(ctx context.Context, code string) error {
 	return nil
 }
 
-// Helper methods such as short-code generation, URL validation and
-// alias validation can be added here. The current service uses the
-// `ShortCodeGenerator` for producing codes and relies on repository
-// uniqueness checks to detect collisions.
+// UpdateURL changes the destination, expiry, schedule or redirect behaviour of an
+// existing short URL.
+// The short code itself never changes, so links already shared keep working.
+// Expired links may be updated, which is how they are revived.
+// With ownership enforcement only the owner or an admin may update it.
+func (s *URLService) UpdateURL(ctx context.Context, code string, req *model.UpdateURLRequest) (*model.URLResponse, error) {
+	s.logger.InfoContext(ctx, "updating URL",
+		slog.String("code", code))
+
+	if req.URL == nil && req.ExpiresAt == nil && !req.ClearExpiry &&
+		req.RedirectType == nil && req.ForwardQuery == nil && req.Password == nil &&
+		req.NotBefore == nil && !req.ClearNotBefore && req.FallbackURL == nil && req.Rules == nil &&
+		req.Variants == nil && req.StickyVariants == nil && req.Tracking == nil {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidUpdate)
+	}
+	if req.ExpiresAt != nil && req.ClearExpiry {
+		return nil, fmt.Errorf("%w: expires_at and clear_expiry are mutually exclusive", ErrInvalidUpdate)
+	}
+	if req.NotBefore != nil && req.ClearNotBefore {
+		return nil, fmt.Errorf("%w: not_before and clear_not_before are mutually exclusive", ErrInvalidUpdate)
+	}
+	if req.NotBefore != nil && req.ExpiresAt != nil && !req.NotBefore.Before(*req.ExpiresAt) {
+		return nil, fmt.Errorf("%w: not_before must be before expires_at", ErrInvalidUpdate)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidUpdate)
+	}
+	if s.maxExpiry > 0 {
+		if req.ClearExpiry {
+			return nil, fmt.Errorf("%w: links must expire within %s", ErrInvalidUpdate, s.maxExpiry)
+		}
+		if req.ExpiresAt != nil && req.ExpiresAt.After(time.Now().Add(s.maxExpiry)) {
+			return nil, fmt.Errorf("%w: expires_at exceeds maximum of %s", ErrInvalidUpdate, s.maxExpiry)
+		}
+	}
+	var hash []byte
+	if req.URL != nil {
+		if hash = canonicalHash(*req.URL); hash == nil {
+			return nil, ErrInvalidURL
+		}
+	}
+	if req.FallbackURL != nil {
+		if err := validateFallbackURL(*req.FallbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if req.Rules != nil {
+		if err := normalizeRules(*req.Rules); err != nil {
+			return nil, err
+		}
+	}
+	if req.Variants != nil {
+		if err := validateVariants(*req.Variants); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateTracking(req.Tracking); err != nil {
+		return nil, err
+	}
+	if req.RedirectType != nil {
+		if err := validateRedirectType(*req.RedirectType); err != nil {
+			return nil, err
+		}
+	}
+	var passwordHash *string
+	if req.Password != nil {
+		hash := ""
+		if *req.Password != "" {
+			var err error
+			if hash, err = hashPassword(*req.Password); err != nil {
+				return nil, err
+			}
+		}
+		passwordHash = &hash
+	}
+
+	if err := s.authorizeCode(ctx, code); err != nil {
+		return nil, err
+	}
 
-// getAndValidateURL is a helper that fetches URL and checks expiration
-func (s *URLService) getAndValidateURL(ctx context.Context, code string) (*model.URL, error) {
+	url, err := s.repo.Update(ctx, code, model.URLUpdate{
+		OriginalURL:    req.URL,
+		ExpiresAt:      req.ExpiresAt,
+		ClearExpiry:    req.ClearExpiry,
+		RedirectType:   req.RedirectType,
+		ForwardQuery:   req.ForwardQuery,
+		PasswordHash:   passwordHash,
+		NotBefore:      req.NotBefore,
+		ClearNotBefore: req.ClearNotBefore,
+		FallbackURL:    req.FallbackURL,
+		Rules:          req.Rules,
+		Variants:       req.Variants,
+		StickyVariants: req.StickyVariants,
+		Tracking:       req.Tracking,
+		CanonicalHash:  hash,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.logger.WarnContext(ctx, "URL not found for update",
+				slog.String("code", code))
+			return nil, ErrURLNotFound
+		}
+		if errors.Is(err, repository.ErrInvalidSchedule) {
+			return nil, fmt.Errorf("%w: not_before must be before expires_at", ErrInvalidUpdate)
+		}
+		s.logger.ErrorContext(ctx, "failed to update URL",
+			slog.String("code", code),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "URL updated successfully",
+		slog.String("code", code),
+		slog.String("target_url", url.OriginalURL))
+
+	resp := s.toURLResponse(ctx, url)
+	return &resp, nil
+}
+
+// recordCodeAttempt feeds the outcome of inserting url with a generated
+// code to the adaptive code length, and logs and stores the length when it
+// promotes. Hashed candidates depend only on the URL and the attempt, so a
+// URL shortened again collides with its own earlier links however empty the
+// code space is; such collisions are not counted.
+func (s *URLService) recordCodeAttempt(ctx context.Context, url *model.URL, collided bool) {
+	if collided && s.hashCodes {
+		if taken, err := s.repo.GetByCode(ctx, url.ShortCode); err == nil && taken.OriginalURL == url.OriginalURL {
+			return
+		}
+	}
+	n := s.length.record(ctx, len(url.ShortCode), collided)
+	if n == 0 {
+		return
+	}
+	s.logger.WarnContext(ctx, "short code space crowded, lengthening new codes",
+		slog.Int("length", n),
+		slog.Float64("collision_rate", s.length.collisionRate()))
+	if s.codeLengths != nil {
+		if err := s.codeLengths.RaiseCodeLength(ctx, n); err != nil {
+			s.logger.ErrorContext(ctx, "failed to store short code length",
+				slog.String("error", err.Error()),
+				slog.Int("length", n))
+		}
+	}
+}
+
+// toCreateURLResponse builds the response for a newly created short URL.
+func (s *URLService) toCreateURLResponse(ctx context.Context, shortCode, domain string, expiresAt *time.Time) *model.CreateURLResponse {
+	var expiresAtStr string
+	if expiresAt != nil {
+		expiresAtStr = expiresAt.Format(time.RFC3339)
+	}
+
+	return &model.CreateURLResponse{
+		ShortCode: shortCode,
+		ShortURL:  s.shortURL(ctx, domain, shortCode),
+		ExpiresAt: expiresAtStr,
+	}
+}
+
+// resolveExpiry returns the absolute expiry for req, or nil for a permanent link.
+// Clients choose one of expires_in (days), expires_at (RFC3339) or
+// expires_after (duration string); otherwise the configured default applies.
+// Any expiry beyond maxExpiry is rejected rather than silently shortened.
+func (s *URLService) resolveExpiry(req *model.CreateURLRequest) (*time.Time, error) {
+	now := time.Now()
+	var expiresAt time.Time
+	set := 0
+
+	if req.ExpiresIn < 0 {
+		return nil, fmt.Errorf("%w: expires_in must not be negative", ErrInvalidExpiry)
+	}
+	if req.ExpiresIn > 0 {
+		set++
+		expiresAt = now.AddDate(0, 0, req.ExpiresIn)
+	}
+	if req.ExpiresAt != nil {
+		set++
+		if !req.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	if req.ExpiresAfter != "" {
+		set++
+		d, err := time.ParseDuration(req.ExpiresAfter)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: expires_after must be a positive duration such as \"36h\"", ErrInvalidExpiry)
+		}
+		expiresAt = now.Add(d)
+	}
+
+	switch {
+	case set > 1:
+		return nil, fmt.Errorf("%w: only one of expires_in, expires_at and expires_after may be set", ErrInvalidExpiry)
+	case set == 0 && s.defaultExpiry > 0:
+		expiresAt = now.Add(s.defaultExpiry)
+	case set == 0 && s.maxExpiry > 0:
+		expiresAt = now.Add(s.maxExpiry)
+	case set == 0:
+		return nil, nil
+	}
+
+	if s.maxExpiry > 0 && expiresAt.After(now.Add(s.maxExpiry)) {
+		return nil, fmt.Errorf("%w: expiry exceeds maximum of %s", ErrInvalidExpiry, s.maxExpiry)
+	}
+	return &expiresAt, nil
+}
+
+// validateURL checks that raw is an absolute URL with a scheme and host.
+// Single-item requests get the same check from Gin's binding:"url" tag; batch
+// items are validated here so one bad entry does not reject the whole batch.
+func validateURL(raw string) error {
+	u, err := url.ParseRequestURI(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// toURLResponse converts a stored URL into its API representation.
+func (s *URLService) toURLResponse(ctx context.Context, url *model.URL) model.URLResponse {
+	var expiresAtStr string
+	if url.ExpiresAt != nil {
+		expiresAtStr = url.ExpiresAt.Format(time.RFC3339)
+	}
+
+	var notBeforeStr string
+	if url.NotBefore != nil {
+		notBeforeStr = url.NotBefore.Format(time.RFC3339)
+	}
+
+	var remaining *int64
+	if url.MaxClicks > 0 {
+		n := max(url.MaxClicks-url.Uses, 0)
+		remaining = &n
+	}
+
+	return model.URLResponse{
+		ShortCode:       url.ShortCode,
+		OriginalURL:     url.OriginalURL,
+		ShortURL:        s.shortURL(ctx, url.Domain, url.ShortCode),
+		CreatedAt:       url.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:       expiresAtStr,
+		NotBefore:       notBeforeStr,
+		ClickCount:      url.ClickCount,
+		Owner:           url.Owner,
+		Domain:          url.Domain,
+		RedirectType:    s.redirectStatus(url),
+		ForwardQuery:    url.ForwardQuery,
+		Protected:       url.PasswordHash != "",
+		MaxClicks:       url.MaxClicks,
+		FallbackURL:     url.FallbackURL,
+		Rules:           url.Rules,
+		Variants:        url.Variants,
+		StickyVariants:  url.StickyVariants && len(url.Variants) > 0,
+		Tracking:        url.Tracking,
+		RemainingClicks: remaining,
+	}
+}
+
+// buildURLFilter validates list query parameters and applies defaults.
+func buildURLFilter(req *model.ListURLsRequest) (model.URLFilter, error) {
+	filter := model.URLFilter{
+		Status:     req.Status,
+		Query:      req.Query,
+		SortBy:     req.SortBy,
+		Descending: true,
+		Limit:      req.Limit,
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = model.SortByCreatedAt
+	case model.SortByCreatedAt, model.SortByClickCount:
+	default:
+		return filter, fmt.Errorf("%w: unsupported sort %q", ErrInvalidListQuery, req.SortBy)
+	}
+
+	switch req.Order {
+	case "", "desc":
+	case "asc":
+		filter.Descending = false
+	default:
+		return filter, fmt.Errorf("%w: unsupported order %q", ErrInvalidListQuery, req.Order)
+	}
+
+	switch filter.Status {
+	case "", model.StatusActive, model.StatusScheduled, model.StatusExpired:
+	default:
+		return filter, fmt.Errorf("%w: unsupported status %q", ErrInvalidListQuery, req.Status)
+	}
+
+	switch {
+	case filter.Limit < 0:
+		return filter, fmt.Errorf("%w: negative limit", ErrInvalidListQuery)
+	case filter.Limit == 0:
+		filter.Limit = defaultListLimit
+	case filter.Limit > maxListLimit:
+		filter.Limit = maxListLimit
+	}
+
+	if !req.CreatedAfter.IsZero() {
+		filter.CreatedAfter = &req.CreatedAfter
+	}
+	if !req.CreatedBefore.IsZero() {
+		filter.CreatedBefore = &req.CreatedBefore
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return filter, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+// encodeCursor serialises a keyset position as an opaque URL-safe token.
+func encodeCursor(c model.URLCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor is the inverse of encodeCursor.
+func decodeCursor(token string) (*model.URLCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var c model.URLCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// getAndValidateURL is a helper that fetches URL and checks expiration.
+// visit is set when resolving a link for a visitor rather than its owner:
+// a link on a branded domain other than the one the request arrived on is
+// then not found, whatever its expiry, and one that has expired or whose
+// not_before has not passed returns an *UnavailableError naming where to
+// send the visitor instead.
+func (s *URLService) getAndValidateURL(ctx context.Context, code string, visit bool) (*model.URL, error) {
 	// 1. Fetch URL from repository
 	url, err := s.repo.GetByCode(ctx, code)
 	if err != nil {
@@ -247,12 +1205,23 @@ func (s *URLService) getAndValidateURL(ctx context.Context, code string) (*model
 		}
 		return nil, err
 	}
+	if visit && url.Domain != "" && url.Domain != tenant.Domain(ctx) {
+		return nil, ErrURLNotFound
+	}
 
 	// 2. Check if URL has expired
 	if url.ExpiresAt != nil && url.ExpiresAt.Before(time.Now()) {
+		if visit {
+			return nil, unavailable(ctx, url, ErrURLExpired)
+		}
 		return nil, ErrURLExpired
 	}
 
+	// 3. Check that a visited link has been activated
+	if visit && url.NotBefore != nil && time.Now().Before(*url.NotBefore) {
+		return nil, unavailable(ctx, url, ErrURLNotActive)
+	}
+
 	return url, nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/auth"
 	"github.com/zhejian/url-shortener/gateway/internal/cache"
 	"github.com/zhejian/url-shortener/gateway/internal/config"
 	"github.com/zhejian/url-shortener/gateway/internal/model"
@@ -137,6 +138,78 @@ func TestURLService_CreateShortURL(t *testing.T) {
 		assert.Error(t, err, "Expected error for duplicate alias, got nil")
 	})
 
+	t.Run("accepts absolute and duration expiries", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		at := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		resp, err := service.CreateShortURL(ctx, &model.CreateURLRequest{
+			URL:       "https://example.com/at",
+			ExpiresAt: &at,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, at.Format(time.RFC3339), resp.ExpiresAt)
+
+		resp, err = service.CreateShortURL(ctx, &model.CreateURLRequest{
+			URL:          "https://example.com/after",
+			ExpiresAfter: "36h",
+		})
+		require.NoError(t, err)
+		expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(36*time.Hour), expiresAt, time.Minute)
+	})
+
+	t.Run("rejects invalid expiries", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+		for name, req := range map[string]*model.CreateURLRequest{
+			"past expires_at":     {URL: "https://example.com", ExpiresAt: &past},
+			"bad duration":        {URL: "https://example.com", ExpiresAfter: "soon"},
+			"negative duration":   {URL: "https://example.com", ExpiresAfter: "-1h"},
+			"negative expires_in": {URL: "https://example.com", ExpiresIn: -1},
+			"more than one":       {URL: "https://example.com", ExpiresIn: 1, ExpiresAt: &future},
+		} {
+			_, err := service.CreateShortURL(ctx, req)
+			assert.ErrorIs(t, err, ErrInvalidExpiry, name)
+		}
+	})
+
+	t.Run("applies default and maximum expiry", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		limited := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries,
+			URLServiceOptions{DefaultExpiry: 24 * time.Hour, MaxExpiry: 7 * 24 * time.Hour})
+
+		resp, err := limited.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/default"})
+		require.NoError(t, err)
+		expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, time.Minute)
+
+		_, err = limited.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/long", ExpiresIn: 30})
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+		_, err = limited.UpdateURL(ctx, resp.ShortCode, &model.UpdateURLRequest{ClearExpiry: true})
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+	})
+
+	t.Run("rejects aliases that violate the policy", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		for _, alias := range []string{"ab", "has space", "health", "API", "way-too-long-alias-x"} {
+			_, err := service.CreateShortURL(ctx, &model.CreateURLRequest{
+				URL:         "https://example.com",
+				CustomAlias: alias,
+			})
+			assert.ErrorIs(t, err, ErrInvalidAlias, "alias %q", alias)
+		}
+
+		var count int
+		err := testDB.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM urls").Scan(&count)
+		require.NoError(t, err)
+		assert.Zero(t, count, "rejected aliases must not be persisted")
+	})
+
 	t.Run("retries on collision and succeeds", func(t *testing.T) {
 		testDB.Cleanup(ctx)
 
@@ -168,6 +241,66 @@ func TestURLService_CreateShortURL(t *testing.T) {
 	})
 }
 
+func TestURLService_CreateShortURLBatch(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewURLRepository(testDB.Pool)
+	repo := repository.NewCachedURLRepository(db, nil, 0, testObs.Logger)
+	service := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries,
+		URLServiceOptions{MaxBatchSize: 5})
+
+	t.Run("reports per-item results in request order", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := service.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/first", CustomAlias: "batch-taken"})
+		require.NoError(t, err)
+
+		results, err := service.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b", CustomAlias: "batch-taken"},
+			{URL: "not a url"},
+			{URL: "https://example.com/c", CustomAlias: "batch-new", ExpiresIn: 7},
+			{URL: "https://example.com/d", CustomAlias: "metrics"},
+		})
+		require.NoError(t, err, "Expected no error, got %v", err)
+		require.Len(t, results, 5)
+
+		require.NoError(t, results[0].Err)
+		assert.Equal(t, testCfg.App.BaseURL+"/"+results[0].URL.ShortCode, results[0].URL.ShortURL)
+		assert.ErrorIs(t, results[1].Err, ErrCodeExists)
+		assert.ErrorIs(t, results[2].Err, ErrInvalidURL)
+		require.NoError(t, results[3].Err)
+		assert.Equal(t, "batch-new", results[3].URL.ShortCode)
+		assert.NotEmpty(t, results[3].URL.ExpiresAt)
+		assert.ErrorIs(t, results[4].Err, ErrInvalidAlias)
+
+		target, err := service.Redirect(ctx, &model.RedirectRequest{Code: results[0].URL.ShortCode})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/a", target.URL)
+	})
+
+	t.Run("retries generated-code collisions within the batch", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		// Identical URLs hash to the same first candidate
+		results, err := service.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+			{URL: "https://example.com/same"},
+			{URL: "https://example.com/same"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+		assert.NotEqual(t, results[0].URL.ShortCode, results[1].URL.ShortCode)
+	})
+
+	t.Run("rejects empty and oversized batches", func(t *testing.T) {
+		_, err := service.CreateShortURLBatch(ctx, nil)
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+
+		_, err = service.CreateShortURLBatch(ctx, make([]model.CreateURLRequest, 6))
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+	})
+}
+
 func TestURLService_GetURL(t *testing.T) {
 	ctx := context.Background()
 	db := repository.NewURLRepository(testDB.Pool)
@@ -226,6 +359,136 @@ func TestURLService_GetURL(t *testing.T) {
 		assert.Error(t, err, "Expected error for expired URL, got nil")
 		assert.Equal(t, ErrURLExpired, err, "Expected ErrURLExpired, got %v", err)
 	})
+
+	t.Run("refuses links the caller does not own", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		owned := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries,
+			URLServiceOptions{EnforceOwnership: true})
+		alice := auth.WithIdentity(ctx, &auth.Identity{Owner: "alice"})
+		_, err := owned.CreateShortURL(alice, &model.CreateURLRequest{URL: "https://example.com/mine", CustomAlias: "alices"})
+		require.NoError(t, err)
+
+		_, err = owned.GetURL(alice, "alices")
+		assert.NoError(t, err)
+		_, err = owned.GetURL(auth.WithIdentity(ctx, &auth.Identity{Owner: "bob"}), "alices")
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestURLService_ListURLs(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewURLRepository(testDB.Pool)
+	repo := repository.NewCachedURLRepository(db, nil, 0, testObs.Logger)
+	service := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries)
+
+	t.Run("pages through all URLs with the returned cursor", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		for _, alias := range []string{"page-a", "page-b", "page-c"} {
+			_, err := service.CreateShortURL(ctx, &model.CreateURLRequest{
+				URL:         "https://example.com/" + alias,
+				CustomAlias: alias,
+			})
+			require.NoError(t, err, "Failed to create URL: %v", err)
+		}
+
+		first, err := service.ListURLs(ctx, &model.ListURLsRequest{Limit: 2})
+		require.NoError(t, err, "Expected no error, got %v", err)
+		assert.Len(t, first.URLs, 2, "Expected a full first page")
+		assert.NotEmpty(t, first.NextCursor, "Expected a cursor for the next page")
+		assert.Equal(t, testCfg.App.BaseURL+"/"+first.URLs[0].ShortCode, first.URLs[0].ShortURL)
+
+		second, err := service.ListURLs(ctx, &model.ListURLsRequest{Limit: 2, Cursor: first.NextCursor})
+		require.NoError(t, err, "Expected no error, got %v", err)
+		assert.Len(t, second.URLs, 1, "Expected the remaining URL on the second page")
+		assert.Empty(t, second.NextCursor, "Expected no cursor after the last page")
+
+		seen := map[string]bool{}
+		for _, u := range append(first.URLs, second.URLs...) {
+			seen[u.ShortCode] = true
+		}
+		assert.Len(t, seen, 3, "Expected every URL exactly once across pages")
+	})
+
+	t.Run("rejects invalid sort, status and cursor", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := service.ListURLs(ctx, &model.ListURLsRequest{SortBy: "random"})
+		assert.ErrorIs(t, err, ErrInvalidListQuery)
+
+		_, err = service.ListURLs(ctx, &model.ListURLsRequest{Status: "deleted"})
+		assert.ErrorIs(t, err, ErrInvalidListQuery)
+
+		_, err = service.ListURLs(ctx, &model.ListURLsRequest{Cursor: "not-a-cursor!"})
+		assert.ErrorIs(t, err, ErrInvalidListQuery)
+	})
+}
+
+func TestURLService_UpdateURL(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewURLRepository(testDB.Pool)
+	repo := repository.NewCachedURLRepository(db, nil, 0, testObs.Logger)
+	service := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries)
+
+	t.Run("changes destination while keeping the short code", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := service.CreateShortURL(ctx, &model.CreateURLRequest{
+			URL:         "https://example.com/typo",
+			CustomAlias: "update-test",
+		})
+		require.NoError(t, err, "Failed to create URL: %v", err)
+
+		newURL := "https://example.com/fixed"
+		resp, err := service.UpdateURL(ctx, "update-test", &model.UpdateURLRequest{URL: &newURL})
+		require.NoError(t, err, "Expected no error, got %v", err)
+		assert.Equal(t, "update-test", resp.ShortCode)
+		assert.Equal(t, newURL, resp.OriginalURL)
+
+		target, err := service.Redirect(ctx, &model.RedirectRequest{Code: "update-test"})
+		require.NoError(t, err)
+		assert.Equal(t, newURL, target.URL, "Expected redirect to follow the new destination")
+	})
+
+	t.Run("revives an expired URL with a new expiry", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO urls (id, short_code, original_url, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, "00000000-0000-0000-0000-000000000002", "revive-test", "https://example.com/revive", time.Now().Add(-48*time.Hour), time.Now().Add(-time.Hour))
+		require.NoError(t, err, "Failed to insert expired URL: %v", err)
+
+		expiresAt := time.Now().Add(24 * time.Hour)
+		resp, err := service.UpdateURL(ctx, "revive-test", &model.UpdateURLRequest{ExpiresAt: &expiresAt})
+		require.NoError(t, err, "Expected no error, got %v", err)
+		assert.NotEmpty(t, resp.ExpiresAt)
+
+		_, err = service.Redirect(ctx, &model.RedirectRequest{Code: "revive-test"})
+		assert.NoError(t, err, "Expected revived URL to redirect")
+	})
+
+	t.Run("rejects empty, past-expiry and conflicting updates", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := service.UpdateURL(ctx, "any", &model.UpdateURLRequest{})
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+
+		past := time.Now().Add(-time.Hour)
+		_, err = service.UpdateURL(ctx, "any", &model.UpdateURLRequest{ExpiresAt: &past})
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+
+		future := time.Now().Add(time.Hour)
+		_, err = service.UpdateURL(ctx, "any", &model.UpdateURLRequest{ExpiresAt: &future, ClearExpiry: true})
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+	})
+
+	t.Run("returns error for non-existent URL", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+
+		_, err := service.UpdateURL(ctx, "nonexistent", &model.UpdateURLRequest{ClearExpiry: true})
+		assert.Equal(t, ErrURLNotFound, err, "Expected ErrURLNotFound, got %v", err)
+	})
 }
 
 func TestURLService_Redirect(t *testing.T) {
@@ -247,15 +510,15 @@ func TestURLService_Redirect(t *testing.T) {
 		require.NoError(t, err, "Failed to create URL: %v", err)
 
 		// Get redirect URL
-		originalURL, err := service.Redirect(ctx, createResp.ShortCode)
+		target, err := service.Redirect(ctx, &model.RedirectRequest{Code: createResp.ShortCode})
 		require.NoError(t, err, "Expected no error, got %v", err)
-		assert.Equal(t, "https://example.com/redirect-target", originalURL, "Expected redirect to 'https://example.com/redirect-target', got %s", originalURL)
+		assert.Equal(t, "https://example.com/redirect-target", target.URL, "Expected redirect to 'https://example.com/redirect-target', got %s", target.URL)
 	})
 
 	t.Run("returns error for non-existent short code", func(t *testing.T) {
 		testDB.Cleanup(ctx)
 
-		_, err := service.Redirect(ctx, "nonexistent")
+		_, err := service.Redirect(ctx, &model.RedirectRequest{Code: "nonexistent"})
 		assert.Error(t, err, "Expected error for non-existent short code, got nil")
 		assert.Equal(t, ErrURLNotFound, err, "Expected ErrURLNotFound, got %v", err)
 	})
@@ -274,7 +537,7 @@ func TestURLService_Redirect(t *testing.T) {
 		}
 
 		// Try to redirect
-		_, err = service.Redirect(ctx, "expired-redirect")
+		_, err = service.Redirect(ctx, &model.RedirectRequest{Code: "expired-redirect"})
 		assert.Error(t, err, "Expected error for expired URL, got nil")
 		assert.Equal(t, ErrURLExpired, err, "Expected ErrURLExpired, got %v", err)
 	})
@@ -370,9 +633,9 @@ func TestURLService_Integration_FullWorkflow(t *testing.T) {
 		assert.Equal(t, createReq.URL, urlResp.OriginalURL, "Original URL mismatch: expected %s, got %s", createReq.URL, urlResp.OriginalURL)
 
 		// 3. Redirect to original URL
-		redirectURL, err := service.Redirect(ctx, createResp.ShortCode)
+		target, err := service.Redirect(ctx, &model.RedirectRequest{Code: createResp.ShortCode})
 		require.NoError(t, err, "Failed to redirect: %v", err)
-		assert.Equal(t, createReq.URL, redirectURL, "Redirect URL mismatch: expected %s, got %s", createReq.URL, redirectURL)
+		assert.Equal(t, createReq.URL, target.URL, "Redirect URL mismatch: expected %s, got %s", createReq.URL, target.URL)
 
 		// 4. Delete the URL
 		err = service.DeleteURL(ctx, createResp.ShortCode)
@@ -462,9 +725,9 @@ func TestURLService_Integration_FullWorkflow(t *testing.T) {
 
 		// Verify we can also redirect using generated short codes
 		for shortCode, expectedURL := range shortCodeMap {
-			redirectURL, err := service.Redirect(ctx, shortCode)
+			target, err := service.Redirect(ctx, &model.RedirectRequest{Code: shortCode})
 			require.NoError(t, err, "Failed to redirect for short code %s: %v", shortCode, err)
-			assert.Equal(t, expectedURL, redirectURL, "Redirect mismatch for %s: expected %s, got %s", shortCode, expectedURL, redirectURL)
+			assert.Equal(t, expectedURL, target.URL, "Redirect mismatch for %s: expected %s, got %s", shortCode, expectedURL, target.URL)
 		}
 
 		// Verify all 5 URLs were created
@@ -505,6 +768,31 @@ func TestURLService_WithCache(t *testing.T) {
 		assert.Equal(t, int64(1), exists, "Expected URL to be cached after first read")
 	})
 
+	t.Run("click count is not pinned by the URL cache TTL", func(t *testing.T) {
+		testDB.Cleanup(ctx)
+		testCache.Cleanup(ctx)
+
+		dbRepo := repository.NewURLRepository(testDB.Pool)
+		repo := repository.NewCachedURLRepository(dbRepo, cache.NewHashRing(map[string]*redis.Client{"node": testCache.Client}, 1), cacheTTL, testObs.Logger,
+			repository.CachedURLRepositoryOptions{ClickCountTTL: 100 * time.Millisecond})
+		service := NewURLService(repo, testObs.Logger, testCfg.App.BaseURL, testCfg.App.ShortCodeLen, testCfg.App.ShortCodeRetries)
+
+		_, err := service.CreateShortURL(ctx, &model.CreateURLRequest{
+			URL:         "https://example.com/clicks",
+			CustomAlias: "click-sync",
+		})
+		require.NoError(t, err)
+
+		// Simulate the analytics worker applying a flushed batch
+		_, err = testDB.Pool.Exec(ctx, "UPDATE urls SET click_count = click_count + 5 WHERE short_code = 'click-sync'")
+		require.NoError(t, err)
+		time.Sleep(150 * time.Millisecond)
+
+		resp, err := service.GetURL(ctx, "click-sync")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), resp.ClickCount)
+	})
+
 	t.Run("serves from cache on subsequent reads", func(t *testing.T) {
 		testDB.Cleanup(ctx)
 		testCache.Cleanup(ctx)
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+// Bounds on a link's A/B variants.
+const (
+	maxVariants      = 10
+	maxVariantWeight = 10000
+)
+
+// variantName matches names that are safe in a cookie value and readable in
+// click stats.
+var variantName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// validateVariants checks a link's A/B variants: at least two, uniquely
+// named, each with a valid URL and a positive weight.
+func validateVariants(variants []model.Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return fmt.Errorf("%w: a split needs between 2 and %d variants", ErrInvalidVariants, maxVariants)
+	}
+	seen := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		if !variantName.MatchString(v.Name) {
+			return fmt.Errorf("%w: variant %d: name must be 1-32 letters, digits, '-' or '_'", ErrInvalidVariants, i)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: duplicate variant name %q", ErrInvalidVariants, v.Name)
+		}
+		seen[v.Name] = true
+		if validateURL(v.URL) != nil {
+			return fmt.Errorf("%w: variant %q: invalid url", ErrInvalidVariants, v.Name)
+		}
+		if v.Weight < 1 || v.Weight > maxVariantWeight {
+			return fmt.Errorf("%w: variant %q: weight must be between 1 and %d", ErrInvalidVariants, v.Name, maxVariantWeight)
+		}
+	}
+	return nil
+}
+
+// assignVariant chooses the variant a visit of url is sent to. With sticky
+// assignment a visitor presenting the name of one of the link's variants
+// keeps it; everyone else, including visitors whose variant has since been
+// removed, gets a fresh weighted pick.
+func assignVariant(url *model.URL, previous string) *model.Variant {
+	if url.StickyVariants && previous != "" {
+		for i := range url.Variants {
+			if url.Variants[i].Name == previous {
+				return &url.Variants[i]
+			}
+		}
+	}
+	total := 0
+	for _, v := range url.Variants {
+		total += v.Weight
+	}
+	return pickVariant(url.Variants, rand.IntN(total))
+}
+
+// pickVariant returns the variant whose share of the cumulative weights
+// contains roll, which must be in [0, sum of weights).
+func pickVariant(variants []model.Variant, roll int) *model.Variant {
+	for i := range variants {
+		if roll < variants[i].Weight {
+			return &variants[i]
+		}
+		roll -= variants[i].Weight
+	}
+	return &variants[len(variants)-1]
+}
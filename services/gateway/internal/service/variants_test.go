@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+)
+
+func TestValidateVariants(t *testing.T) {
+	a := model.Variant{Name: "a", URL: "https://example.com/a", Weight: 1}
+	b := model.Variant{Name: "b", URL: "https://example.com/b", Weight: 3}
+	require.NoError(t, validateVariants(nil))
+	require.NoError(t, validateVariants([]model.Variant{a, b}))
+
+	for name, variants := range map[string][]model.Variant{
+		"single variant": {a},
+		"duplicate name": {a, a},
+		"empty name":     {a, {URL: "https://example.com/c", Weight: 1}},
+		"name with dot":  {a, {Name: "v.2", URL: "https://example.com/c", Weight: 1}},
+		"invalid url":    {a, {Name: "c", URL: "not a url", Weight: 1}},
+		"zero weight":    {a, {Name: "c", URL: "https://example.com/c"}},
+		"huge weight":    {a, {Name: "c", URL: "https://example.com/c", Weight: maxVariantWeight + 1}},
+	} {
+		assert.ErrorIs(t, validateVariants(variants), ErrInvalidVariants, name)
+	}
+}
+
+func TestPickVariant(t *testing.T) {
+	variants := []model.Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}
+	for roll, want := range []string{"a", "b", "b", "b"} {
+		assert.Equal(t, want, pickVariant(variants, roll).Name, "roll %d", roll)
+	}
+}
+
+func TestURLService_RedirectVariants(t *testing.T) {
+	repo := &domainRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewURLService(repository.NewCachedURLRepository(repo, nil, 0, logger), logger, "http://short.example", 6, 3)
+	ctx := context.Background()
+
+	variants := []model.Variant{
+		{Name: "control", URL: "https://example.com/landing", Weight: 1},
+		{Name: "new", URL: "https://example.com/landing-v2", Weight: 1},
+	}
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{
+		URL:            "https://example.com/landing",
+		CustomAlias:    "split",
+		Variants:       variants,
+		StickyVariants: true,
+		Rules:          []model.RedirectRule{{OS: []string{"ios"}, URL: "https://apps.apple.com/app/id1"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("visits are split between variants", func(t *testing.T) {
+		seen := map[string]string{}
+		for range 200 {
+			target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "split"})
+			require.NoError(t, err)
+			seen[target.Variant] = target.URL
+			assert.True(t, target.NoStore)
+			assert.True(t, target.Sticky)
+		}
+		assert.Equal(t, map[string]string{
+			"control": "https://example.com/landing",
+			"new":     "https://example.com/landing-v2",
+		}, seen)
+	})
+
+	t.Run("sticky visitors keep their variant", func(t *testing.T) {
+		for range 20 {
+			target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "split", Variant: "new"})
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/landing-v2", target.URL)
+		}
+		target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "split", Variant: "removed"})
+		require.NoError(t, err)
+		assert.Contains(t, []string{"control", "new"}, target.Variant, "unknown variant is re-picked")
+	})
+
+	t.Run("rules take precedence", func(t *testing.T) {
+		target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "split", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://apps.apple.com/app/id1", target.URL)
+		assert.Empty(t, target.Variant)
+		assert.False(t, target.Sticky)
+	})
+
+	t.Run("non-sticky links ignore the cookie", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/landing", CustomAlias: "split2", Variants: []model.Variant{
+			{Name: "control", URL: "https://example.com/landing", Weight: 1},
+			{Name: "new", URL: "https://example.com/landing-v2", Weight: maxVariantWeight},
+		}})
+		require.NoError(t, err)
+		picked := map[string]int{}
+		for range 50 {
+			target, err := s.Redirect(ctx, &model.RedirectRequest{Code: "split2", Variant: "control"})
+			require.NoError(t, err)
+			assert.False(t, target.Sticky)
+			picked[target.Variant]++
+		}
+		assert.Greater(t, picked["new"], 40)
+	})
+
+	t.Run("invalid variants rejected", func(t *testing.T) {
+		_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://example.com/", Variants: variants[:1]})
+		assert.ErrorIs(t, err, ErrInvalidVariants)
+	})
+
+	t.Run("metadata reports variants", func(t *testing.T) {
+		got, err := s.GetURL(ctx, "split")
+		require.NoError(t, err)
+		assert.Equal(t, variants, got.Variants)
+		assert.True(t, got.StickyVariants)
+	})
+}
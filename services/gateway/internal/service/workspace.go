@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// workspacePolicy is a compiled alias policy and the workspace snapshot it
+// was built from.
+type workspacePolicy struct {
+	ws     *model.Workspace
+	policy *AliasPolicy
+}
+
+// baseURLFor returns the prefix of short URLs created in ctx's workspace.
+func (s *URLService) baseURLFor(ctx context.Context) string {
+	if ws, ok := tenant.FromContext(ctx); ok && ws.BaseURL != "" {
+		return ws.BaseURL
+	}
+	return s.baseURL
+}
+
+// shortURL returns the public URL of code: on its branded domain when it has
+// one, otherwise under the workspace's base URL. Branded domains are served
+// over HTTPS.
+func (s *URLService) shortURL(ctx context.Context, domain, code string) string {
+	if domain != "" {
+		return "https://" + domain + "/" + code
+	}
+	return s.baseURLFor(ctx) + "/" + code
+}
+
+// aliasPolicyFor returns the custom alias rules of ctx's workspace: the
+// server policy extended with the workspace's overrides.
+//
+// Compiled policies are kept per workspace and rebuilt when the resolver
+// hands out a new snapshot of it, so the regexp is not recompiled per
+// request. A workspace whose overrides do not compile falls back to the
+// server policy.
+func (s *URLService) aliasPolicyFor(ctx context.Context) *AliasPolicy {
+	ws, ok := tenant.FromContext(ctx)
+	if !ok || !ws.HasAliasOverrides() {
+		return s.aliasPolicy
+	}
+	if v, ok := s.workspacePolicies.Load(ws.ID); ok && v.(workspacePolicy).ws == ws {
+		return v.(workspacePolicy).policy
+	}
+
+	policy, err := s.aliasPolicy.Extend(ws.AliasMinLen, ws.AliasMaxLen, ws.AliasPattern, ws.ReservedAliases)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "invalid workspace alias policy, using server policy",
+			slog.String("workspace", ws.Slug),
+			slog.String("error", err.Error()))
+		policy = s.aliasPolicy
+	}
+	s.workspacePolicies.Store(ws.ID, workspacePolicy{ws: ws, policy: policy})
+	return policy
+}
+
+// remainingQuota returns how many more links ctx's workspace may hold, or -1
+// when it has no limit. It lets a full workspace fail fast and a batch keep
+// the items that fit; the count and the insert are not atomic, so the
+// database enforces the quota again on insert and concurrent creates that
+// pass this check may still fail with ErrQuotaExceeded.
+func (s *URLService) remainingQuota(ctx context.Context) (int64, error) {
+	ws, ok := tenant.FromContext(ctx)
+	if !ok || ws.MaxLinks <= 0 {
+		return -1, nil
+	}
+	count, err := s.repo.Count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(ws.MaxLinks-count, 0), nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+	"github.com/zhejian/url-shortener/gateway/internal/repository"
+	"github.com/zhejian/url-shortener/gateway/internal/tenant"
+)
+
+// quotaRepo stores links in memory for quota tests. Methods the tests do not
+// reach are left to the embedded nil interface.
+type quotaRepo struct {
+	repository.URLRepositoryInterface
+	links []*model.URL
+}
+
+func (r *quotaRepo) Count(context.Context) (int64, error) {
+	return int64(len(r.links)), nil
+}
+
+func (r *quotaRepo) GetByCode(_ context.Context, code string) (*model.URL, error) {
+	for _, l := range r.links {
+		if l.ShortCode == code {
+			return l, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *quotaRepo) Create(_ context.Context, url *model.URL) error {
+	r.links = append(r.links, url)
+	return nil
+}
+
+func (r *quotaRepo) CreateBatch(_ context.Context, urls []*model.URL) ([]error, error) {
+	r.links = append(r.links, urls...)
+	return make([]error, len(urls)), nil
+}
+
+func newWorkspaceTestService(repo repository.URLRepositoryInterface) *URLService {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cached := repository.NewCachedURLRepository(repo, nil, time.Minute, logger)
+	return NewURLService(cached, logger, "http://short.example", 6, 3)
+}
+
+func TestURLService_WorkspaceQuota(t *testing.T) {
+	repo := &quotaRepo{}
+	s := newWorkspaceTestService(repo)
+	ws := &model.Workspace{ID: uuid.New(), Slug: "acme", MaxLinks: 3, BaseURL: "https://go.acme.example"}
+	ctx := tenant.WithWorkspace(context.Background(), ws)
+
+	resp, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://acme.example/a", CustomAlias: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.acme.example/first", resp.ShortURL)
+	assert.Equal(t, ws.ID, repo.links[0].WorkspaceID)
+
+	results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{
+		{URL: "https://acme.example/b", CustomAlias: "second"},
+		{URL: "not a url"},
+		{URL: "https://acme.example/c", CustomAlias: "third"},
+		{URL: "https://acme.example/d", CustomAlias: "fourth"},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, ErrInvalidURL, "invalid items do not use quota")
+	assert.NoError(t, results[2].Err)
+	assert.ErrorIs(t, results[3].Err, ErrQuotaExceeded)
+
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://acme.example/e", CustomAlias: "fifth"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = s.CreateShortURL(context.Background(), &model.CreateURLRequest{URL: "https://example.com", CustomAlias: "free"})
+	assert.NoError(t, err, "the default workspace has no quota")
+}
+
+// fullRepo passes the quota pre-check but is full by the time it inserts,
+// as when a concurrent create took the last slot.
+type fullRepo struct {
+	quotaRepo
+}
+
+func (r *fullRepo) Create(context.Context, *model.URL) error {
+	return repository.ErrQuotaExceeded
+}
+
+func (r *fullRepo) CreateBatch(context.Context, []*model.URL) ([]error, error) {
+	return nil, repository.ErrQuotaExceeded
+}
+
+func TestURLService_WorkspaceQuotaRace(t *testing.T) {
+	s := newWorkspaceTestService(&fullRepo{})
+	ctx := tenant.WithWorkspace(context.Background(), &model.Workspace{ID: uuid.New(), Slug: "acme", MaxLinks: 3})
+
+	_, err := s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://acme.example/a", CustomAlias: "first"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = s.CreateShortURL(ctx, &model.CreateURLRequest{URL: "https://acme.example/b"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	results, err := s.CreateShortURLBatch(ctx, []model.CreateURLRequest{{URL: "https://acme.example/c"}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrQuotaExceeded)
+}
+
+func TestURLService_BaseURLFor(t *testing.T) {
+	s := newWorkspaceTestService(&quotaRepo{})
+	assert.Equal(t, "http://short.example", s.baseURLFor(context.Background()))
+	assert.Equal(t, "http://short.example", s.baseURLFor(tenant.WithWorkspace(context.Background(), &model.Workspace{Slug: "plain"})))
+	assert.Equal(t, "https://b.example", s.baseURLFor(tenant.WithWorkspace(context.Background(), &model.Workspace{BaseURL: "https://b.example"})))
+}
+
+func TestURLService_AliasPolicyFor(t *testing.T) {
+	s := newWorkspaceTestService(&quotaRepo{})
+	ws := &model.Workspace{ID: uuid.New(), ReservedAliases: []string{"sale"}}
+	ctx := tenant.WithWorkspace(context.Background(), ws)
+
+	assert.Same(t, s.aliasPolicy, s.aliasPolicyFor(context.Background()))
+	assert.Same(t, s.aliasPolicy, s.aliasPolicyFor(tenant.WithWorkspace(context.Background(), &model.Workspace{ID: uuid.New()})),
+		"workspaces without overrides share the server policy")
+
+	policy := s.aliasPolicyFor(ctx)
+	assert.ErrorIs(t, policy.Validate("sale"), ErrInvalidAlias)
+	assert.NoError(t, s.aliasPolicy.Validate("sale"))
+	assert.Same(t, policy, s.aliasPolicyFor(ctx), "compiled once per workspace snapshot")
+
+	updated := *ws
+	updated.ReservedAliases = nil
+	updated.AliasMinLen = 8
+	policy = s.aliasPolicyFor(tenant.WithWorkspace(context.Background(), &updated))
+	assert.NoError(t, policy.Validate("sale-2026"), "rebuilt for a new snapshot")
+
+	broken := &model.Workspace{ID: uuid.New(), AliasPattern: "["}
+	assert.Same(t, s.aliasPolicy, s.aliasPolicyFor(tenant.WithWorkspace(context.Background(), broken)))
+}
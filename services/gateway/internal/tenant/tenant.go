@@ -0,0 +1,50 @@
+// Package tenant carries the workspace a request was resolved to. Repositories
+// scope every short-code lookup to it, so two workspaces may use the same
+// alias without colliding.
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+type contextKey struct{}
+
+// WithWorkspace returns a copy of ctx scoped to ws.
+func WithWorkspace(ctx context.Context, ws *model.Workspace) context.Context {
+	return context.WithValue(ctx, contextKey{}, ws)
+}
+
+// FromContext returns the workspace attached by WithWorkspace, if any.
+// Requests without one belong to the default workspace and use the server
+// configuration.
+func FromContext(ctx context.Context) (*model.Workspace, bool) {
+	ws, ok := ctx.Value(contextKey{}).(*model.Workspace)
+	return ws, ok && ws != nil
+}
+
+// WorkspaceID returns the ID of the workspace in ctx, or
+// model.DefaultWorkspaceID when there is none.
+func WorkspaceID(ctx context.Context) uuid.UUID {
+	if ws, ok := FromContext(ctx); ok {
+		return ws.ID
+	}
+	return model.DefaultWorkspaceID
+}
+
+type domainKey struct{}
+
+// WithDomain returns a copy of ctx recording that the request arrived on the
+// registered domain host.
+func WithDomain(ctx context.Context, host string) context.Context {
+	return context.WithValue(ctx, domainKey{}, host)
+}
+
+// Domain returns the registered domain the request arrived on, or "" when
+// its host is not a branded domain.
+func Domain(ctx context.Context) string {
+	host, _ := ctx.Value(domainKey{}).(string)
+	return host
+}
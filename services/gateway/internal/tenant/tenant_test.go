@@ -0,0 +1,30 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhejian/url-shortener/gateway/internal/model"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, model.DefaultWorkspaceID, WorkspaceID(ctx))
+
+	ws := &model.Workspace{ID: uuid.New(), Slug: "acme"}
+	ctx = WithWorkspace(ctx, ws)
+	got, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, ws, got)
+	assert.Equal(t, ws.ID, WorkspaceID(ctx))
+}
+
+func TestDomain(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, Domain(ctx))
+	assert.Equal(t, "go.brand-a.com", Domain(WithDomain(ctx, "go.brand-a.com")))
+}
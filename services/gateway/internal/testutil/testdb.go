@@ -70,7 +70,11 @@ func (t *TestDB) Cleanup(ctx context.Context) {
 	if t == nil || t.Pool == nil {
 		return
 	}
-	if _, err := t.Pool.Exec(ctx, "TRUNCATE TABLE urls RESTART IDENTITY"); err != nil {
+	if _, err := t.Pool.Exec(ctx, "TRUNCATE TABLE urls, analytics, analytics_rollups, analytics_referer_rollups, api_keys, domains, short_code_pool RESTART IDENTITY"); err != nil {
+		return
+	}
+	// The default workspace is seeded by migration and must survive.
+	if _, err := t.Pool.Exec(ctx, "DELETE FROM workspaces WHERE id <> '00000000-0000-0000-0000-000000000000'"); err != nil {
 		return
 	}
 }